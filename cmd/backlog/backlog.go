@@ -11,6 +11,7 @@ import (
 
 	"gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsr/snapshot"
 	"gopkg.in/dfsr.v0/dfsrconfig"
 	"gopkg.in/dfsr.v0/dfsrflag"
 	"gopkg.in/dfsr.v0/helper"
@@ -30,6 +31,9 @@ var (
 	skipFlag           dfsrflag.RegexpSlice
 	minFlag            uint
 	verboseFlag        bool
+	snapshotFlag       string
+	exportFlag         string
+	filesFlag          uint
 )
 
 const (
@@ -51,6 +55,9 @@ func init() {
 	flag.Var(&skipFlag, "skip", "regex of hostname to skip")
 	flag.UintVar(&minFlag, "min", 0, "minimum backlog to display")
 	flag.BoolVar(&verboseFlag, "v", false, "verbose")
+	flag.StringVar(&snapshotFlag, "snapshot", "", "read configuration from a snapshot file instead of querying the domain")
+	flag.StringVar(&exportFlag, "export", "", "write the configuration to a snapshot file")
+	flag.UintVar(&filesFlag, "files", 0, "maximum number of backlogged files to list for each folder")
 
 	rand.Seed(time.Now().UnixNano())
 }
@@ -121,6 +128,8 @@ func run(domain string, iteration uint, min uint, client *helper.Client, connect
 	var wg sync.WaitGroup
 	wg.Add(len(connections))
 
+	fileErrs := make([]error, len(connections))
+
 	//fmt.Printf("[query %v] %s\n", iteration, domain)
 	fmt.Printf("%-50s %-50s %-50s %-15s %s\n", "Group", "Source", "Destination", "Backlog", "Time")
 	fmt.Printf("%-50s %-50s %-50s %-15s %s\n", "-----", "------", "-----------", "-------", "----")
@@ -128,7 +137,7 @@ func run(domain string, iteration uint, min uint, client *helper.Client, connect
 	start := time.Now()
 
 	for i := 0; i < len(connections); i++ {
-		go computeBacklog(ctx, client, &connections[i], &wg)
+		go computeBacklog(ctx, client, &connections[i], &fileErrs[i], &wg)
 	}
 
 	wg.Wait()
@@ -149,34 +158,35 @@ func run(domain string, iteration uint, min uint, client *helper.Client, connect
 		} else {
 			fmt.Printf("%-15s ", fmt.Sprint(c.Sum()))
 		}
-		fmt.Printf("%v\n", c.Call.Duration())
+		if c.Closed {
+			fmt.Printf("%v (schedule closed)\n", c.Call.Duration())
+		} else {
+			fmt.Printf("%v\n", c.Call.Duration())
+		}
 		if verboseFlag {
 			fmt.Printf("Call: %v\n", c.Call)
 		}
+		if fileErrs[i] != nil {
+			fmt.Printf("  Unable to list backlogged files: %v\n", fileErrs[i])
+		}
+		printFiles(c)
 	}
 
 	fmt.Printf("Total Time: %v\n", finish.Sub(start))
 }
 
 func setup(domain string, groupRegex, fromRegex, toRegex, memberRegex, skipRegex dfsrflag.RegexpSlice) (dom string, connections []dfsr.Backlog, err error) {
-	client, err := adsi.NewClient()
+	d, err := loadDomain(domain)
 	if err != nil {
-		return "", nil, err
+		return domain, nil, err
 	}
-	defer client.Close()
+	dom = d.DN
 
-	if domain == "" {
-		domain, err = dnc(client)
-		if err != nil {
-			return "", nil, err
+	if exportFlag != "" {
+		if err = snapshot.New(d, time.Now()).Save(exportFlag); err != nil {
+			return dom, nil, err
 		}
 	}
-	dom = domain
-
-	d, err := dfsrconfig.Domain(client, domain)
-	if err != nil {
-		return domain, nil, err
-	}
 
 	for g := 0; g < len(d.Groups); g++ {
 		group := &d.Groups[g]
@@ -217,9 +227,10 @@ func setup(domain string, groupRegex, fromRegex, toRegex, memberRegex, skipRegex
 				}
 
 				connections = append(connections, dfsr.Backlog{
-					Group: group,
-					From:  from,
-					To:    to,
+					Group:      group,
+					Connection: conn,
+					From:       from,
+					To:         to,
 				})
 			}
 		}
@@ -227,7 +238,44 @@ func setup(domain string, groupRegex, fromRegex, toRegex, memberRegex, skipRegex
 	return
 }
 
-func computeBacklog(ctx context.Context, client *helper.Client, backlog *dfsr.Backlog, wg *sync.WaitGroup) {
+// loadDomain retrieves the configuration of the given domain, or reads it from
+// a snapshot if one was requested.
+func loadDomain(domain string) (*dfsr.Domain, error) {
+	if snapshotFlag != "" {
+		snap, err := snapshot.Load(snapshotFlag)
+		if err != nil {
+			return nil, err
+		}
+		return snap.Domain, nil
+	}
+
+	client, err := adsi.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if domain == "" {
+		domain, err = dnc(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d, err := dfsrconfig.Domain(client, domain)
+	if dfsrconfig.IsPartial(err) {
+		log.Print(err) // Continue with the groups that were retrieved
+	} else if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func computeBacklog(ctx context.Context, client *helper.Client, backlog *dfsr.Backlog, fileErr *error, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	backlog.Annotate(time.Now())
+
 	var values []int
 	values, backlog.Call, backlog.Err = client.Backlog(ctx, backlog.From, backlog.To, backlog.Group.ID)
 	if n := len(values); n == len(backlog.Group.Folders) {
@@ -237,5 +285,33 @@ func computeBacklog(ctx context.Context, client *helper.Client, backlog *dfsr.Ba
 			backlog.Folders[v].Backlog = values[v]
 		}
 	}
-	wg.Done()
+
+	if filesFlag == 0 || backlog.Err != nil || backlog.Sum() == 0 {
+		return
+	}
+
+	files, fcall, err := client.BacklogFiles(ctx, backlog.From, backlog.To, backlog.Group.ID, int(filesFlag))
+	backlog.Call.Add(&fcall)
+	if err != nil {
+		*fileErr = err
+		return
+	}
+	backlog.AttachFiles(files)
+}
+
+// printFiles prints the backlogged files of each folder in the backlog.
+func printFiles(backlog *dfsr.Backlog) {
+	for _, folder := range backlog.Folders {
+		if len(folder.Files) == 0 {
+			continue
+		}
+		fmt.Printf("  %s (%d of %d):\n", folder.Folder.Name, len(folder.Files), folder.Backlog)
+		for _, file := range folder.Files {
+			if file.Path != "" {
+				fmt.Printf("    %s\n", file.Path)
+			} else {
+				fmt.Printf("    %s\n", file.Name)
+			}
+		}
+	}
 }
@@ -4,45 +4,78 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"gopkg.in/adsi.v0"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsr/topology"
 	"gopkg.in/dfsr.v0/dfsrconfig"
+	"gopkg.in/dfsr.v0/dfsrconfig/ldapconfig"
 )
 
 func main() {
+	var ldapConfig ldapconfig.Config
+	flag.StringVar(&ldapConfig.URL, "ldap", "", "query the given LDAP or LDAPS server URL instead of using ADSI")
+	flag.BoolVar(&ldapConfig.StartTLS, "starttls", false, "upgrade LDAP connections with StartTLS")
+	flag.StringVar((*string)(&ldapConfig.Bind), "bind", "", "LDAP bind method (anonymous, simple, ntlm or gssapi)")
+	flag.StringVar(&ldapConfig.Username, "user", "", "LDAP bind username")
+	flag.StringVar(&ldapConfig.Password, "pass", "", "LDAP bind password")
+	flag.StringVar(&ldapConfig.Domain, "realm", "", "NTLM domain or Kerberos realm of the LDAP bind user")
+	flag.StringVar(&ldapConfig.Keytab, "keytab", "", "Kerberos keytab for gssapi binds")
+	primary := flag.String("primary", "", "report members of each group that cannot receive changes from this member")
 	flag.Parse()
 
-	client, err := adsi.NewClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	var domain = flag.Arg(0)
 
-	if domain == "" {
-		dnc, dncErr := rootDNC(client)
-		if dncErr != nil {
-			log.Fatal(dncErr)
-		}
-		domain = dnc
+	var (
+		d   dfsr.Domain
+		err error
+	)
+	if ldapConfig.URL != "" {
+		d, err = ldapDomain(ldapConfig, domain)
+	} else {
+		d, err = adsiDomain(domain)
 	}
-
-	d, err := dfsrconfig.Domain(client, domain)
-	if err != nil {
+	if dfsrconfig.IsPartial(err) {
+		log.Print(err)
+	} else if err != nil {
 		log.Fatal(err)
 	}
 
+	now := time.Now().UTC()
+
 	fmt.Printf("      Domain: %-51s ID: %v DN: %-30s Duration: %v\n", d.Description, d.ID, d.DN, d.ConfigDuration)
 	for i := 0; i < len(d.Groups); i++ {
 		group := &d.Groups[i]
 		fmt.Printf("[%3d]   Group: %-50s ID: %v Duration: %v\n", i, group.Name, group.ID, group.ConfigDuration)
+		fmt.Printf("          Type: %s Schedule: %s\n", group.Type, scheduleSummary(group.Schedule, now))
 		for f := 0; f < len(group.Folders); f++ {
 			folder := &group.Folders[f]
+			settings := folder.FolderSettings.Effective(group.Defaults)
 			fmt.Printf("          Folder: %-47s ID: %v\n", folder.Name, folder.ID)
+			if len(settings.FileFilter) > 0 || len(settings.DirectoryFilter) > 0 {
+				fmt.Printf("            Filters: Files: %s Directories: %s\n", settings.FileFilter, settings.DirectoryFilter)
+			}
 		}
 		for m := 0; m < len(group.Members); m++ {
 			member := &group.Members[m]
 			fmt.Printf("          Member: %-47s ID: %v Computer: %s Version: %s\n", member.Name, member.ID, member.Computer.Host, member.Settings.Version)
+			for f := 0; f < len(group.Folders); f++ {
+				folder := &group.Folders[f]
+				sub, ok := member.Settings.Subscription(group.ID, folder.ID)
+				if !ok {
+					continue
+				}
+				enabledMark, readOnly := " ", ""
+				if sub.Enabled {
+					enabledMark = "x"
+				}
+				if sub.ReadOnly {
+					readOnly = " (read-only)"
+				}
+				fmt.Printf("            Subscription[%s]: %-37s Root: %s%s Staging: %s (%d MiB) Private: %s\n", enabledMark, folder.Name, sub.RootPath, readOnly, sub.StagingPath, sub.StagingSize, sub.PrivatePath())
+			}
 			for c := 0; c < len(member.Connections); c++ {
 				conn := &member.Connections[c]
 				enabledMark := " "
@@ -52,10 +85,38 @@ func main() {
 				fmt.Printf("            Connection[%s]: %-39s ID: %v Computer: %s\n", enabledMark, conn.Name, conn.ID, conn.Computer.Host)
 			}
 		}
+		printTopology(group, *primary)
 	}
 	fmt.Printf("Duration: %v\n", d.ConfigDuration)
 }
 
+func adsiDomain(domain string) (d dfsr.Domain, err error) {
+	client, err := adsi.NewClient()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	if domain == "" {
+		domain, err = rootDNC(client)
+		if err != nil {
+			return
+		}
+	}
+
+	return dfsrconfig.Domain(client, domain)
+}
+
+func ldapDomain(config ldapconfig.Config, domain string) (d dfsr.Domain, err error) {
+	conn, err := ldapconfig.Dial(config)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	return ldapconfig.Domain(conn, domain)
+}
+
 func rootDNC(client *adsi.Client) (string, error) {
 	rootDSE, err := client.Open("LDAP://RootDSE")
 	if err != nil {
@@ -65,3 +126,65 @@ func rootDNC(client *adsi.Client) (string, error) {
 
 	return rootDSE.AttrString("rootDomainNamingContext")
 }
+
+// scheduleSummary describes the state of the given schedule at time now.
+func scheduleSummary(s *dfsr.Schedule, now time.Time) string {
+	switch {
+	case s.IsAlwaysOpen():
+		return "always open"
+	case s.IsClosed():
+		return "always closed"
+	}
+	state := "closed"
+	if s.IsOpen(now) {
+		state = fmt.Sprintf("open (%s)", s.At(now))
+	}
+	if next, ok := s.NextChange(now); ok {
+		return fmt.Sprintf("%s until %s UTC", state, next.Format("Mon 15:04"))
+	}
+	return state
+}
+
+// printTopology prints any problems with the replication topology of group.
+func printTopology(group *dfsr.Group, primary string) {
+	graph := topology.New(group)
+	member, _ := graph.Member(primary)
+	report := graph.Analyze(member)
+	if report.OK() {
+		return
+	}
+
+	for _, m := range report.NoInbound {
+		fmt.Printf("          Warning: %s has no enabled inbound connections\n", m.Name)
+	}
+	for _, m := range report.NoOutbound {
+		fmt.Printf("          Warning: %s has no enabled outbound connections\n", m.Name)
+	}
+	if report.Partitioned() {
+		fmt.Printf("          Warning: group is split into %d components:", len(report.Components))
+		for _, c := range report.Components {
+			fmt.Printf(" [%s]", memberNames(c))
+		}
+		fmt.Printf("\n")
+	}
+	for _, m := range report.Unreachable {
+		fmt.Printf("          Warning: %s cannot receive changes from %s\n", m.Name, report.Primary.Name)
+	}
+	for _, link := range report.OneWay {
+		fmt.Printf("          Warning: %s replicates to %s in one direction only\n", link.From.Name, link.To.Name)
+	}
+	for _, link := range report.Partitioning {
+		fmt.Printf("          Warning: disabled connection %s from %s to %s partitions the group\n", link.Connection.Name, link.From.Name, link.To.Name)
+	}
+	for _, link := range report.Dangling {
+		fmt.Printf("          Warning: connection %s to %s refers to unknown member %s\n", link.Connection.Name, link.To.Name, link.Connection.MemberDN)
+	}
+}
+
+func memberNames(members []*dfsr.Member) string {
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ", ")
+}
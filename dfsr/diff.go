@@ -0,0 +1,285 @@
+package dfsr
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ChangeKind identifies the kind of a configuration change.
+type ChangeKind int
+
+// Configuration change kinds.
+const (
+	ChangeAdded ChangeKind = iota
+	ChangeRemoved
+	ChangeEnabled
+	ChangeDisabled
+	ChangeModified
+)
+
+// String returns a string representation of the change kind.
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeEnabled:
+		return "enabled"
+	case ChangeDisabled:
+		return "disabled"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(k))
+	}
+}
+
+// ObjectKind identifies the kind of configuration object affected by a
+// change.
+type ObjectKind int
+
+// Configuration object kinds.
+const (
+	ObjectGroup ObjectKind = iota
+	ObjectFolder
+	ObjectMember
+	ObjectConnection
+	ObjectSubscription
+)
+
+// String returns a string representation of the object kind.
+func (k ObjectKind) String() string {
+	switch k {
+	case ObjectGroup:
+		return "group"
+	case ObjectFolder:
+		return "folder"
+	case ObjectMember:
+		return "member"
+	case ObjectConnection:
+		return "connection"
+	case ObjectSubscription:
+		return "subscription"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(k))
+	}
+}
+
+// Change describes a single difference between two domain configurations.
+//
+// Group is always populated. Member is populated for changes to members,
+// connections and subscriptions. Name and ID identify the affected object
+// itself. For subscriptions they identify the subscribed folder.
+type Change struct {
+	Kind     ChangeKind
+	Object   ObjectKind
+	Group    string
+	GroupID  uuid.UUID
+	Member   string
+	MemberID uuid.UUID
+	Name     string
+	ID       uuid.UUID
+	Field    string // Name of the modified field, if Kind is ChangeModified
+	Old      string // Previous value of the modified field
+	New      string // Current value of the modified field
+}
+
+// String returns a string representation of the change.
+func (c Change) String() string {
+	path := c.Group
+	if c.Object != ObjectGroup {
+		if c.Member != "" {
+			path += "/" + c.Member
+		}
+		path += "/" + c.Name
+	}
+	if c.Kind == ChangeModified {
+		return fmt.Sprintf("%s %s %s %s: \"%s\" -> \"%s\"", c.Object, path, c.Kind, c.Field, c.Old, c.New)
+	}
+	return fmt.Sprintf("%s %s %s", c.Object, path, c.Kind)
+}
+
+// Diff returns the changes required to transform the configuration of domain
+// a into the configuration of domain b. Objects are matched by their GUIDs.
+//
+// A nil domain is treated as a domain without any replication groups.
+func Diff(a, b *Domain) (changes []Change) {
+	var ag, bg []Group
+	if a != nil {
+		ag = a.Groups
+	}
+	if b != nil {
+		bg = b.Groups
+	}
+
+	old := make(map[uuid.UUID]*Group, len(ag))
+	for i := range ag {
+		old[ag[i].ID] = &ag[i]
+	}
+
+	for i := range bg {
+		group := &bg[i]
+		prior, ok := old[group.ID]
+		if !ok {
+			changes = append(changes, groupChange(ChangeAdded, group))
+			continue
+		}
+		delete(old, group.ID)
+		changes = append(changes, diffGroup(prior, group)...)
+	}
+
+	for i := range ag {
+		if _, removed := old[ag[i].ID]; removed {
+			changes = append(changes, groupChange(ChangeRemoved, &ag[i]))
+		}
+	}
+
+	return
+}
+
+func groupChange(kind ChangeKind, g *Group) Change {
+	return Change{
+		Kind:    kind,
+		Object:  ObjectGroup,
+		Group:   g.Name,
+		GroupID: g.ID,
+		Name:    g.Name,
+		ID:      g.ID,
+	}
+}
+
+func diffGroup(a, b *Group) (changes []Change) {
+	change := func(kind ChangeKind, object ObjectKind, name string, id uuid.UUID) Change {
+		return Change{
+			Kind:    kind,
+			Object:  object,
+			Group:   b.Name,
+			GroupID: b.ID,
+			Name:    name,
+			ID:      id,
+		}
+	}
+
+	// Folders
+	oldFolders := make(map[uuid.UUID]*Folder, len(a.Folders))
+	for i := range a.Folders {
+		oldFolders[a.Folders[i].ID] = &a.Folders[i]
+	}
+	for i := range b.Folders {
+		folder := &b.Folders[i]
+		if _, ok := oldFolders[folder.ID]; ok {
+			delete(oldFolders, folder.ID)
+			continue
+		}
+		changes = append(changes, change(ChangeAdded, ObjectFolder, folder.Name, folder.ID))
+	}
+	for i := range a.Folders {
+		if folder, removed := oldFolders[a.Folders[i].ID]; removed {
+			changes = append(changes, change(ChangeRemoved, ObjectFolder, folder.Name, folder.ID))
+		}
+	}
+
+	// Members
+	oldMembers := make(map[uuid.UUID]*Member, len(a.Members))
+	for i := range a.Members {
+		oldMembers[a.Members[i].ID] = &a.Members[i]
+	}
+	for i := range b.Members {
+		member := &b.Members[i]
+		prior, ok := oldMembers[member.ID]
+		if !ok {
+			changes = append(changes, change(ChangeAdded, ObjectMember, member.Name, member.ID))
+			continue
+		}
+		delete(oldMembers, member.ID)
+		for _, c := range diffMember(b, prior, member) {
+			c.Group, c.GroupID = b.Name, b.ID
+			changes = append(changes, c)
+		}
+	}
+	for i := range a.Members {
+		if member, removed := oldMembers[a.Members[i].ID]; removed {
+			changes = append(changes, change(ChangeRemoved, ObjectMember, member.Name, member.ID))
+		}
+	}
+
+	return
+}
+
+func diffMember(group *Group, a, b *Member) (changes []Change) {
+	change := func(kind ChangeKind, object ObjectKind, name string, id uuid.UUID) Change {
+		return Change{
+			Kind:     kind,
+			Object:   object,
+			Member:   b.Name,
+			MemberID: b.ID,
+			Name:     name,
+			ID:       id,
+		}
+	}
+
+	// Connections
+	oldConns := make(map[uuid.UUID]*Connection, len(a.Connections))
+	for i := range a.Connections {
+		oldConns[a.Connections[i].ID] = &a.Connections[i]
+	}
+	for i := range b.Connections {
+		conn := &b.Connections[i]
+		prior, ok := oldConns[conn.ID]
+		if !ok {
+			changes = append(changes, change(ChangeAdded, ObjectConnection, conn.Name, conn.ID))
+			continue
+		}
+		delete(oldConns, conn.ID)
+		switch {
+		case conn.Enabled && !prior.Enabled:
+			changes = append(changes, change(ChangeEnabled, ObjectConnection, conn.Name, conn.ID))
+		case !conn.Enabled && prior.Enabled:
+			changes = append(changes, change(ChangeDisabled, ObjectConnection, conn.Name, conn.ID))
+		}
+	}
+	for i := range a.Connections {
+		if conn, removed := oldConns[a.Connections[i].ID]; removed {
+			changes = append(changes, change(ChangeRemoved, ObjectConnection, conn.Name, conn.ID))
+		}
+	}
+
+	// Subscriptions for the folders of the group
+	for i := range group.Folders {
+		folder := &group.Folders[i]
+		prior, hadPrior := a.Settings.Subscription(group.ID, folder.ID)
+		sub, hasSub := b.Settings.Subscription(group.ID, folder.ID)
+		switch {
+		case hasSub && !hadPrior:
+			changes = append(changes, change(ChangeAdded, ObjectSubscription, folder.Name, folder.ID))
+		case !hasSub && hadPrior:
+			changes = append(changes, change(ChangeRemoved, ObjectSubscription, folder.Name, folder.ID))
+		case hasSub && hadPrior:
+			fields := []struct {
+				name     string
+				old, new string
+			}{
+				{"RootPath", prior.RootPath, sub.RootPath},
+				{"StagingPath", prior.StagingPath, sub.StagingPath},
+				{"ConflictPath", prior.ConflictPath, sub.ConflictPath},
+			}
+			for _, field := range fields {
+				if field.old != field.new {
+					c := change(ChangeModified, ObjectSubscription, folder.Name, folder.ID)
+					c.Field, c.Old, c.New = field.name, field.old, field.new
+					changes = append(changes, c)
+				}
+			}
+			switch {
+			case sub.Enabled && !prior.Enabled:
+				changes = append(changes, change(ChangeEnabled, ObjectSubscription, folder.Name, folder.ID))
+			case !sub.Enabled && prior.Enabled:
+				changes = append(changes, change(ChangeDisabled, ObjectSubscription, folder.Name, folder.ID))
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,223 @@
+package dfsr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ScheduleInterval is the length of each interval within a replication
+	// schedule.
+	ScheduleInterval = 15 * time.Minute
+
+	// ScheduleIntervals is the number of intervals in a weekly replication
+	// schedule.
+	ScheduleIntervals = 7 * 24 * 4
+
+	// ScheduleSize is the size in bytes of an encoded replication schedule.
+	// Each byte holds two intervals.
+	ScheduleSize = ScheduleIntervals / 2
+)
+
+// The range of offsets from UTC that local times may have.
+const (
+	minZoneOffset = -12 * time.Hour
+	maxZoneOffset = 14 * time.Hour
+)
+
+// ErrInvalidSchedule is returned when an encoded schedule is malformed.
+var ErrInvalidSchedule = errors.New("invalid replication schedule")
+
+// Bandwidth is the replication bandwidth permitted during a schedule interval.
+type Bandwidth uint8
+
+// Bandwidth levels used in replication schedules.
+const (
+	BandwidthNone Bandwidth = 0x0 // No replication
+	Bandwidth16K  Bandwidth = 0x1 // 16 Kbps
+	Bandwidth64K  Bandwidth = 0x2 // 64 Kbps
+	Bandwidth128K Bandwidth = 0x3 // 128 Kbps
+	Bandwidth256K Bandwidth = 0x4 // 256 Kbps
+	Bandwidth512K Bandwidth = 0x5 // 512 Kbps
+	Bandwidth1M   Bandwidth = 0x6 // 1 Mbps
+	Bandwidth2M   Bandwidth = 0x7 // 2 Mbps
+	Bandwidth4M   Bandwidth = 0x8 // 4 Mbps
+	Bandwidth8M   Bandwidth = 0x9 // 8 Mbps
+	Bandwidth16M  Bandwidth = 0xA // 16 Mbps
+	Bandwidth32M  Bandwidth = 0xB // 32 Mbps
+	Bandwidth64M  Bandwidth = 0xC // 64 Mbps
+	Bandwidth128M Bandwidth = 0xD // 128 Mbps
+	Bandwidth256M Bandwidth = 0xE // 256 Mbps
+	BandwidthFull Bandwidth = 0xF // Unrestricted
+)
+
+// BitsPerSecond returns the bandwidth limit in bits per second. It returns
+// zero for BandwidthNone and -1 for BandwidthFull, which is unrestricted.
+func (b Bandwidth) BitsPerSecond() int64 {
+	switch {
+	case b == BandwidthNone:
+		return 0
+	case b >= BandwidthFull:
+		return -1
+	case b == Bandwidth16K:
+		return 16 * 1024
+	default:
+		// Each level from 64 Kbps onward doubles the previous one
+		return 64 * 1024 << uint(b-Bandwidth64K)
+	}
+}
+
+// String returns a string representation of the bandwidth level.
+func (b Bandwidth) String() string {
+	switch bps := b.BitsPerSecond(); {
+	case bps == 0:
+		return "none"
+	case bps < 0:
+		return "full"
+	case bps < 1024*1024:
+		return fmt.Sprintf("%d Kbps", bps/1024)
+	default:
+		return fmt.Sprintf("%d Mbps", bps/(1024*1024))
+	}
+}
+
+// Schedule is a weekly replication schedule. It holds the permitted bandwidth
+// for each 15 minute interval of the week, starting at midnight on Sunday.
+//
+// Replication groups and connections may indicate that their schedules are
+// expressed in either UTC or the local time of the receiving member. Callers
+// are responsible for converting times to the appropriate location before
+// consulting the schedule.
+type Schedule [ScheduleIntervals]Bandwidth
+
+// ParseSchedule decodes a schedule in the format used by the msDFSR-Schedule
+// attribute. Each byte holds two intervals, with the earlier interval in the
+// high-order nibble.
+func ParseSchedule(data []byte) (*Schedule, error) {
+	if len(data) != ScheduleSize {
+		return nil, ErrInvalidSchedule
+	}
+	s := new(Schedule)
+	for i, b := range data {
+		s[i*2] = Bandwidth(b >> 4)
+		s[i*2+1] = Bandwidth(b & 0x0F)
+	}
+	return s, nil
+}
+
+// Bytes returns the schedule encoded in the format used by the msDFSR-Schedule
+// attribute.
+func (s *Schedule) Bytes() []byte {
+	data := make([]byte, ScheduleSize)
+	for i := range data {
+		data[i] = byte(s[i*2]&0x0F)<<4 | byte(s[i*2+1]&0x0F)
+	}
+	return data
+}
+
+// MarshalText encodes the schedule as a hexadecimal string of its
+// msDFSR-Schedule form.
+func (s *Schedule) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(s.Bytes())), nil
+}
+
+// UnmarshalText decodes a schedule from a hexadecimal string of its
+// msDFSR-Schedule form.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(string(text))
+	if err != nil {
+		return ErrInvalidSchedule
+	}
+	parsed, err := ParseSchedule(data)
+	if err != nil {
+		return err
+	}
+	*s = *parsed
+	return nil
+}
+
+// At returns the bandwidth permitted by the schedule at time t. The weekday
+// and time of day of t are used as-is, without converting its location.
+//
+// A nil schedule imposes no restrictions and always returns BandwidthFull.
+func (s *Schedule) At(t time.Time) Bandwidth {
+	if s == nil {
+		return BandwidthFull
+	}
+	return s[scheduleIndex(t)]
+}
+
+// IsOpen reports whether the schedule permits replication at time t.
+func (s *Schedule) IsOpen(t time.Time) bool {
+	return s.At(t) != BandwidthNone
+}
+
+// IsOpenInAnyZone reports whether the schedule permits replication at the
+// instant t when it is interpreted in UTC or in the local time of any time
+// zone. It is used when the location that a schedule is expressed in is not
+// known. Every time zone is offset from UTC by a multiple of the schedule
+// interval, so each offset that a local time may have is considered.
+func (s *Schedule) IsOpenInAnyZone(t time.Time) bool {
+	u := t.UTC()
+	for offset := minZoneOffset; offset <= maxZoneOffset; offset += ScheduleInterval {
+		if s.IsOpen(u.Add(offset)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAlwaysOpen reports whether the schedule permits replication in every
+// interval of the week.
+func (s *Schedule) IsAlwaysOpen() bool {
+	if s == nil {
+		return true
+	}
+	for _, b := range s {
+		if b == BandwidthNone {
+			return false
+		}
+	}
+	return true
+}
+
+// IsClosed reports whether the schedule prohibits replication in every
+// interval of the week.
+func (s *Schedule) IsClosed() bool {
+	if s == nil {
+		return false
+	}
+	for _, b := range s {
+		if b != BandwidthNone {
+			return false
+		}
+	}
+	return true
+}
+
+// NextChange returns the start of the next interval after t in which the
+// replication state of the schedule differs from its state at t. If the state
+// never changes ok will be false.
+func (s *Schedule) NextChange(t time.Time) (next time.Time, ok bool) {
+	if s == nil {
+		return
+	}
+	open := s.IsOpen(t)
+	start := t.Add(-(time.Duration(t.Minute()%15)*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())))
+	index := scheduleIndex(t)
+	for i := 1; i < ScheduleIntervals; i++ {
+		if (s[(index+i)%ScheduleIntervals] != BandwidthNone) != open {
+			return start.Add(time.Duration(i) * ScheduleInterval), true
+		}
+	}
+	return
+}
+
+// scheduleIndex returns the schedule interval that contains t.
+func scheduleIndex(t time.Time) int {
+	return int(t.Weekday())*24*4 + t.Hour()*4 + t.Minute()/15
+}
@@ -0,0 +1,52 @@
+package dfsr_test
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// closedFor returns a schedule that is open except for the given number of
+// hours starting at midnight on Wednesday.
+func closedFor(hours int) *dfsr.Schedule {
+	s := new(dfsr.Schedule)
+	for i := range s {
+		s[i] = dfsr.BandwidthFull
+	}
+	start := int(time.Wednesday) * 24 * 4
+	for i := start; i < start+hours*4; i++ {
+		s[i%len(s)] = dfsr.BandwidthNone
+	}
+	return s
+}
+
+func TestAnnotate(t *testing.T) {
+	group := &dfsr.Group{Schedule: closedFor(4)}
+	noon := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC) // A Wednesday
+
+	for _, tt := range []struct {
+		name     string
+		schedule *dfsr.Schedule
+		t        time.Time
+		closed   bool
+	}{
+		{"closed in UTC only", nil, time.Date(2017, 3, 1, 2, 0, 0, 0, time.UTC), false},
+		{"closed in every zone", closedFor(40), noon, true},
+		{"open in every zone", closedFor(40), noon.Add(-48 * time.Hour), false},
+		{"always closed", closedFor(7 * 24), noon, true},
+		{"always open", closedFor(0), noon, false},
+	} {
+		backlog := &dfsr.Backlog{Group: group, Connection: &dfsr.Connection{Schedule: tt.schedule}}
+		backlog.Annotate(tt.t)
+		if backlog.Closed != tt.closed {
+			t.Errorf("%s: Closed is %t, want %t", tt.name, backlog.Closed, tt.closed)
+		}
+	}
+
+	backlog := &dfsr.Backlog{Group: group}
+	backlog.Annotate(noon)
+	if backlog.Closed {
+		t.Error("backlog without a connection is closed")
+	}
+}
@@ -0,0 +1,16 @@
+package snapshot
+
+import "errors"
+
+// Version is the snapshot format version written by this package.
+const Version = 1
+
+var (
+	// ErrUnsupportedVersion is returned when a snapshot was written in a
+	// format version that this package does not understand.
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+
+	// ErrMissingDomain is returned when a snapshot does not contain domain
+	// configuration.
+	ErrMissingDomain = errors.New("snapshot does not contain a domain")
+)
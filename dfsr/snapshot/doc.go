@@ -0,0 +1,20 @@
+// Package snapshot reads and writes offline snapshots of DFSR domain
+// configuration.
+//
+// A snapshot captures a dfsr.Domain in a versioned JSON document. Snapshots
+// allow tools and services to run from a previously captured configuration
+// when Active Directory is slow or unreachable, and allow the topology of
+// another environment to be reproduced from a file.
+//
+// A snapshot document has the following form:
+//
+//   {
+//     "version": 1,
+//     "captured": "2017-03-01T12:00:00Z",
+//     "domain": { ... }
+//   }
+//
+// The domain member holds the fields of dfsr.Domain and its descendants as
+// encoded by the encoding/json package. Schedules are encoded as hexadecimal
+// strings of their msDFSR-Schedule form.
+package snapshot
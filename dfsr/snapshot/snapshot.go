@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// Snapshot holds domain configuration captured at a particular time.
+type Snapshot struct {
+	Version  int          `json:"version"`
+	Captured time.Time    `json:"captured"`
+	Domain   *dfsr.Domain `json:"domain"`
+}
+
+// New returns a snapshot of the given domain configuration captured at the
+// given time.
+func New(domain *dfsr.Domain, captured time.Time) *Snapshot {
+	return &Snapshot{
+		Version:  Version,
+		Captured: captured,
+		Domain:   domain,
+	}
+}
+
+// Read decodes a snapshot from r.
+func Read(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version < 1 || s.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	if s.Domain == nil {
+		return nil, ErrMissingDomain
+	}
+	return &s, nil
+}
+
+// Write encodes the snapshot to w.
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Load reads a snapshot from the file at path.
+func Load(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Save writes the snapshot to the file at path. The snapshot is written to a
+// temporary file in the same directory first, so that an existing snapshot
+// is not left incomplete if the write fails.
+func (s *Snapshot) Save(path string) (err error) {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	if err = s.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,60 @@
+package snapshot
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+var _ = (monitor.Source)((*Source)(nil)) // Compile-time interface compliance check
+
+// Source serves domain configuration from a snapshot file. It can be used in
+// place of a dfsrconfig.DomainMonitor to run a backlog monitor from a
+// captured configuration.
+//
+// The zero value of Source is not suitable for use. Sources should be created
+// with a call to NewSource().
+type Source struct {
+	path string
+
+	mutex    sync.RWMutex
+	domain   *dfsr.Domain
+	captured time.Time
+}
+
+// NewSource returns a source that serves the snapshot stored in the file at
+// path. The file is read immediately and an error is returned if it cannot
+// be loaded.
+func NewSource(path string) (*Source, error) {
+	s := &Source{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the snapshot file again. If it cannot be loaded an error is
+// returned and the source continues to serve the previous snapshot.
+func (s *Source) Reload() error {
+	snap, err := Load(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.domain = snap.Domain
+	s.captured = snap.Captured
+	s.mutex.Unlock()
+
+	return nil
+}
+
+// Value returns the domain configuration held by the snapshot and the time at
+// which it was captured.
+func (s *Source) Value() (*dfsr.Domain, time.Time, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.domain, s.captured, nil
+}
@@ -0,0 +1,10 @@
+// Package topology analyzes the replication topology of DFSR replication
+// groups.
+//
+// A topology graph is built from the members and connections of a
+// dfsr.Group. Each member is a vertex and each connection is a directed edge
+// from its sending member to its receiving member. The analysis reports
+// conditions that cause replication to silently stop, such as members without
+// enabled connections, members that cannot receive changes from a primary
+// member, one-way links and disabled connections that partition the group.
+package topology
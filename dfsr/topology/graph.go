@@ -0,0 +1,277 @@
+package topology
+
+import (
+	"strings"
+
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// Graph is a directed graph of the members and connections of a replication
+// group.
+//
+// The zero value of Graph is not suitable for use. Graphs should be created
+// with a call to New().
+type Graph struct {
+	group *dfsr.Group
+	edges [][]edge // Outbound edges of each member, indexed by member
+	links []Link   // All links, including dangling and disabled ones
+}
+
+type edge struct {
+	to      int
+	enabled bool
+}
+
+// New builds a topology graph from the members and connections of group.
+//
+// The sending member of each connection is matched by its distinguished name,
+// or by the distinguished name of its computer when the connection refers to
+// the member indirectly, as is the case for the Domain System Volume.
+func New(group *dfsr.Group) *Graph {
+	g := &Graph{
+		group: group,
+		edges: make([][]edge, len(group.Members)),
+	}
+
+	byDN := make(map[string]int)
+	byComputer := make(map[string]int)
+	for m := range group.Members {
+		member := &group.Members[m]
+		if member.DN != "" {
+			byDN[strings.ToLower(member.DN)] = m
+		}
+		if member.Computer.DN != "" {
+			byComputer[strings.ToLower(member.Computer.DN)] = m
+		}
+	}
+
+	for to := range group.Members {
+		member := &group.Members[to]
+		for c := range member.Connections {
+			conn := &member.Connections[c]
+			link := Link{To: member, Connection: conn}
+
+			from, ok := byDN[strings.ToLower(conn.MemberDN)]
+			if !ok && conn.Computer.DN != "" {
+				from, ok = byComputer[strings.ToLower(conn.Computer.DN)]
+			}
+			if ok {
+				link.From = &group.Members[from]
+				g.edges[from] = append(g.edges[from], edge{to: to, enabled: conn.Enabled})
+			}
+
+			g.links = append(g.links, link)
+		}
+	}
+
+	return g
+}
+
+// Group returns the replication group the graph was built from.
+func (g *Graph) Group() *dfsr.Group {
+	return g.group
+}
+
+// Links returns all of the links in the graph.
+func (g *Graph) Links() []Link {
+	return g.links
+}
+
+// Member returns the member of the group with the given name, host name or
+// distinguished name. Names are matched without regard to case.
+func (g *Graph) Member(name string) (member *dfsr.Member, ok bool) {
+	for m := range g.group.Members {
+		candidate := &g.group.Members[m]
+		if strings.EqualFold(candidate.Name, name) ||
+			strings.EqualFold(candidate.DN, name) ||
+			strings.EqualFold(candidate.Computer.Host, name) {
+			return candidate, true
+		}
+	}
+	return nil, false
+}
+
+// Analyze examines the topology of the graph. If primary is not nil the
+// report includes the members that cannot receive changes from it.
+func (g *Graph) Analyze(primary *dfsr.Member) (report Report) {
+	report.Group = g.group
+	report.Primary = primary
+
+	members := len(g.group.Members)
+
+	// Members without enabled connections in either direction
+	if members > 1 {
+		inbound := make([]bool, members)
+		outbound := make([]bool, members)
+		for from := range g.edges {
+			for _, e := range g.edges[from] {
+				if e.enabled && e.to != from {
+					outbound[from] = true
+					inbound[e.to] = true
+				}
+			}
+		}
+		for m := 0; m < members; m++ {
+			if !inbound[m] {
+				report.NoInbound = append(report.NoInbound, &g.group.Members[m])
+			}
+			if !outbound[m] {
+				report.NoOutbound = append(report.NoOutbound, &g.group.Members[m])
+			}
+		}
+	}
+
+	// Strongly connected components over enabled connections
+	component, count := g.components()
+	report.Components = make([]Component, count)
+	for m, c := range component {
+		report.Components[c] = append(report.Components[c], &g.group.Members[m])
+	}
+
+	// Reachability from the primary member
+	if primary != nil {
+		if start := g.index(primary); start >= 0 {
+			reached := g.reachable(start)
+			for m := 0; m < members; m++ {
+				if !reached[m] {
+					report.Unreachable = append(report.Unreachable, &g.group.Members[m])
+				}
+			}
+		}
+	}
+
+	// Link classification
+	for _, link := range g.links {
+		if link.From == nil {
+			report.Dangling = append(report.Dangling, link)
+			continue
+		}
+		from, to := g.index(link.From), g.index(link.To)
+		if from == to {
+			continue
+		}
+		if !link.Connection.Enabled {
+			if component[from] != component[to] {
+				report.Partitioning = append(report.Partitioning, link)
+			}
+			continue
+		}
+		if !g.hasEnabledEdge(to, from) {
+			report.OneWay = append(report.OneWay, link)
+		}
+	}
+
+	return
+}
+
+// index returns the index of member within the group, or -1 if it is not a
+// member of the group.
+func (g *Graph) index(member *dfsr.Member) int {
+	for m := range g.group.Members {
+		if &g.group.Members[m] == member {
+			return m
+		}
+	}
+	return -1
+}
+
+func (g *Graph) hasEnabledEdge(from, to int) bool {
+	for _, e := range g.edges[from] {
+		if e.enabled && e.to == to {
+			return true
+		}
+	}
+	return false
+}
+
+// reachable returns the set of members that can be reached from start
+// through enabled edges.
+func (g *Graph) reachable(start int) []bool {
+	reached := make([]bool, len(g.edges))
+	reached[start] = true
+	queue := []int{start}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, e := range g.edges[from] {
+			if e.enabled && !reached[e.to] {
+				reached[e.to] = true
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return reached
+}
+
+// components assigns each member to a strongly connected component over
+// enabled edges using Tarjan's algorithm. Components are numbered in order of
+// the first member they contain.
+func (g *Graph) components() (component []int, count int) {
+	n := len(g.edges)
+	var (
+		index   = make([]int, n)
+		lowlink = make([]int, n)
+		onStack = make([]bool, n)
+		found   = make([]int, n) // Component of each member in order of discovery
+		stack   []int
+		next    = 1 // Zero means unvisited
+	)
+
+	var visit func(v int)
+	visit = func(v int) {
+		index[v], lowlink[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, e := range g.edges[v] {
+			if !e.enabled {
+				continue
+			}
+			if index[e.to] == 0 {
+				visit(e.to)
+				if lowlink[e.to] < lowlink[v] {
+					lowlink[v] = lowlink[e.to]
+				}
+			} else if onStack[e.to] && index[e.to] < lowlink[v] {
+				lowlink[v] = index[e.to]
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				found[w] = count
+				if w == v {
+					break
+				}
+			}
+			count++
+		}
+	}
+
+	for v := 0; v < n; v++ {
+		if index[v] == 0 {
+			visit(v)
+		}
+	}
+
+	// Renumber the components in member order
+	order := make([]int, count)
+	for i := range order {
+		order[i] = -1
+	}
+	component = make([]int, n)
+	assigned := 0
+	for v := 0; v < n; v++ {
+		if order[found[v]] < 0 {
+			order[found[v]] = assigned
+			assigned++
+		}
+		component[v] = order[found[v]]
+	}
+
+	return component, count
+}
@@ -0,0 +1,46 @@
+package topology
+
+import "gopkg.in/dfsr.v0/dfsr"
+
+// Link is a directed replication link between two members of a replication
+// group.
+type Link struct {
+	From       *dfsr.Member // Sending member, nil if it is not a member of the group
+	To         *dfsr.Member // Receiving member
+	Connection *dfsr.Connection
+}
+
+// Component is a strongly connected component of a replication group. Every
+// member of a component can replicate changes to every other member of the
+// component through enabled connections.
+type Component []*dfsr.Member
+
+// Report holds the results of a topology analysis.
+type Report struct {
+	Group        *dfsr.Group
+	Primary      *dfsr.Member   // Member used for reachability analysis, may be nil
+	NoInbound    []*dfsr.Member // Members that have no enabled inbound connections
+	NoOutbound   []*dfsr.Member // Members that have no enabled outbound connections
+	Components   []Component    // Strongly connected components over enabled connections
+	Unreachable  []*dfsr.Member // Members that cannot receive changes from the primary
+	OneWay       []Link         // Enabled links without an enabled link in the opposite direction
+	Partitioning []Link         // Disabled links between members of different components
+	Dangling     []Link         // Links whose sending member is not a member of the group
+}
+
+// Partitioned reports whether the group is split into more than one strongly
+// connected component.
+func (r *Report) Partitioned() bool {
+	return len(r.Components) > 1
+}
+
+// OK reports whether the analysis found no problems with the topology.
+func (r *Report) OK() bool {
+	return len(r.NoInbound) == 0 &&
+		len(r.NoOutbound) == 0 &&
+		!r.Partitioned() &&
+		len(r.Unreachable) == 0 &&
+		len(r.OneWay) == 0 &&
+		len(r.Partitioning) == 0 &&
+		len(r.Dangling) == 0
+}
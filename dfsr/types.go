@@ -1,6 +1,7 @@
 package dfsr
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -61,19 +62,101 @@ type Site struct {
 
 // Group represents a replication group.
 type Group struct {
-	Name           string
-	ID             uuid.UUID
-	Folders        []Folder
-	Members        []Member
-	ConfigDuration time.Duration // Time elapsed while retrieving configuration
+	Name            string
+	ID              uuid.UUID
+	Type            GroupType
+	Description     string
+	Schedule        *Schedule // Default connection schedule, nil if not defined
+	Defaults        FolderSettings
+	TombstoneExpiry time.Duration
+	Flags           int // Enum flags
+	Options         int // Enum flags
+	Folders         []Folder
+	Members         []Member
+	ConfigDuration  time.Duration // Time elapsed while retrieving configuration
+}
+
+// GroupType identifies the kind of a replication group.
+type GroupType int
+
+// Replication group types.
+const (
+	GroupTypeOther  GroupType = 0 // General purpose replication
+	GroupTypeSysvol GroupType = 1 // Domain System Volume
+)
+
+// String returns a string representation of the replication group type.
+func (t GroupType) String() string {
+	switch t {
+	case GroupTypeOther:
+		return "other"
+	case GroupTypeSysvol:
+		return "sysvol"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(t))
+	}
 }
 
 // Folder represents a replication folder.
 //
 // TODO: Consider renaming this to ContentSet.
 type Folder struct {
-	Name string
-	ID   uuid.UUID
+	Name        string
+	ID          uuid.UUID
+	Description string
+	DfsPath     string // Published namespace path, if any
+	FolderSettings
+	Flags   int // Enum flags, which include the folder's protection flags
+	Options int // Enum flags
+}
+
+// FolderSettings holds the settings that may be defined for a replication
+// folder, or as defaults for all of the folders in a replication group.
+type FolderSettings struct {
+	FileFilter      Filter // File names excluded from replication
+	DirectoryFilter Filter // Directory names excluded from replication
+	StagingSize     int    // In Mibibytes, zero if not defined
+	ConflictSize    int    // In Mibibytes, zero if not defined
+}
+
+// Effective returns the settings that apply to a folder with settings s in a
+// replication group with default settings defaults. Settings that are not
+// defined by s are taken from defaults.
+func (s FolderSettings) Effective(defaults FolderSettings) FolderSettings {
+	if s.FileFilter == nil {
+		s.FileFilter = defaults.FileFilter
+	}
+	if s.DirectoryFilter == nil {
+		s.DirectoryFilter = defaults.DirectoryFilter
+	}
+	if s.StagingSize == 0 {
+		s.StagingSize = defaults.StagingSize
+	}
+	if s.ConflictSize == 0 {
+		s.ConflictSize = defaults.ConflictSize
+	}
+	return s
+}
+
+// Filter is a list of wildcard patterns that exclude files or directories
+// from replication.
+type Filter []string
+
+// ParseFilter parses a comma-separated filter as stored in the
+// msDFSR-FileFilter and msDFSR-DirectoryFilter attributes. It returns nil if
+// value is empty.
+func ParseFilter(value string) (filter Filter) {
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			filter = append(filter, pattern)
+		}
+	}
+	return
+}
+
+// String returns the filter in its comma-separated form.
+func (f Filter) String() string {
+	return strings.Join(f, ", ")
 }
 
 // Member represents a replication member.
@@ -94,6 +177,7 @@ type MemberInfo struct {
 // Computer represents information about a computer.
 type Computer struct {
 	DN   string // Distinguished name
+	ID   uuid.UUID
 	Host string
 }
 
@@ -132,29 +216,138 @@ type Subscription struct {
 	MinDurationCache time.Duration
 }
 
+// Subscription returns the subscription of the local settings for the given
+// replication group and content set. If no such subscription exists ok will
+// be false.
+func (s *LocalSettings) Subscription(group, contentSet uuid.UUID) (sub Subscription, ok bool) {
+	for g := range s.Groups {
+		for c := range s.Groups[g].ContentSets {
+			candidate := &s.Groups[g].ContentSets[c]
+			if candidate.ReplicationGroup == group && candidate.ContentSet == contentSet {
+				return *candidate, true
+			}
+		}
+	}
+	return
+}
+
+// PrivatePath returns the path of the DfsrPrivate directory of the
+// subscription, which holds its conflict and deleted files and their
+// manifest. It is the parent of the conflict path when one is configured, and
+// the DfsrPrivate directory of the root path otherwise.
+func (s *Subscription) PrivatePath() string {
+	if s.ConflictPath != "" {
+		if i := strings.LastIndexAny(strings.TrimRight(s.ConflictPath, `\`), `\`); i >= 0 {
+			return s.ConflictPath[:i]
+		}
+	}
+	return strings.TrimRight(s.RootPath, `\`) + `\DfsrPrivate`
+}
+
+// ManifestPath returns the path of the conflict and deleted manifest of the
+// subscription.
+func (s *Subscription) ManifestPath() string {
+	return s.PrivatePath() + `\ConflictAndDeletedManifest.xml`
+}
+
 // Connection represents a one-way connection between replication members.
 type Connection struct {
-	Name     string
-	ID       uuid.UUID
-	MemberDN string
-	Enabled  bool
-	Computer Computer // Distinguished name of source member in topology, matches DN field of that Member
+	Name           string
+	ID             uuid.UUID
+	MemberDN       string
+	Enabled        bool
+	Computer       Computer  // Distinguished name of source member in topology, matches DN field of that Member
+	Schedule       *Schedule // Connection schedule, nil if the group's default schedule applies
+	RDC            bool      // Remote differential compression
+	RDCMinFileSize int       // In Kibibytes, zero if not defined
+	Keywords       string
+	Priority       int
+	Flags          int // Enum flags
+	Options        ConnectionOptions
+}
+
+// ConnectionOptions holds the option flags of a connection.
+type ConnectionOptions int
+
+// Connection option flags.
+const (
+	ConnectionDisableCrossFileRDC ConnectionOptions = 0x1 // Cross-file remote differential compression is disabled
+)
+
+// CrossFileRDC reports whether cross-file remote differential compression is
+// permitted on the connection. It is only used when RDC is also enabled.
+func (c *Connection) CrossFileRDC() bool {
+	return c.RDC && c.Options&ConnectionDisableCrossFileRDC == 0
+}
+
+// EffectiveSchedule returns the schedule that governs the connection within
+// the given replication group. It is the connection's own schedule if it has
+// one, and the default schedule of the group otherwise. A nil schedule
+// imposes no restrictions.
+func (c *Connection) EffectiveSchedule(group *Group) *Schedule {
+	if c.Schedule != nil || group == nil {
+		return c.Schedule
+	}
+	return group.Schedule
+}
+
+// IsOpen reports whether the connection is scheduled to replicate at time t
+// within the given replication group.
+func (c *Connection) IsOpen(group *Group, t time.Time) bool {
+	return c.EffectiveSchedule(group).IsOpen(t)
 }
 
 // FolderBacklog represents the backlog for an individual folder.
+//
+// Files holds the backlogged files when they have been requested. It may hold
+// fewer entries than Backlog, as members list a limited number of files.
 type FolderBacklog struct {
 	Folder  *Folder
 	Backlog int
+	Files   []BacklogFile
+}
+
+// BacklogFile describes a file that is waiting to be replicated.
+type BacklogFile struct {
+	Name string
+	Path string
+	UID  string // Unique identifier of the file version
 }
 
 // Backlog represents the backlog from one DFSR member to another.
 type Backlog struct {
-	Group   *Group
-	From    string
-	To      string
-	Folders []FolderBacklog
-	Call    callstat.Call
-	Err     error
+	Group      *Group
+	Connection *Connection // May be nil if the connection is not known
+	From       string
+	To         string
+	Folders    []FolderBacklog
+	Closed     bool // The connection was scheduled closed when the backlog was queried
+	Call       callstat.Call
+	Err        error
+}
+
+// AttachFiles assigns backlogged files to the folders of the backlog. The
+// files are provided as a map of folder IDs to files.
+func (b *Backlog) AttachFiles(files map[uuid.UUID][]BacklogFile) {
+	for f := range b.Folders {
+		if folder := b.Folders[f].Folder; folder != nil {
+			b.Folders[f].Files = files[folder.ID]
+		}
+	}
+}
+
+// Annotate records the schedule state of the backlog's connection at time t.
+//
+// Schedules may be expressed in UTC or in the local time of the receiving
+// member, and neither the option that selects between them nor the time zone
+// of the member is known. Closed is therefore only set when the connection's
+// schedule prohibits replication at t in every time zone.
+func (b *Backlog) Annotate(t time.Time) {
+	if b.Connection == nil {
+		b.Closed = false
+		return
+	}
+	b.Closed = !b.Connection.EffectiveSchedule(b.Group).IsOpenInAnyZone(t)
 }
 
 // Sum returns the total backlog of all replicated folders. Negatives values,
@@ -1,16 +1,21 @@
 package dfsrconfig
 
 import (
-	"strings"
+	"context"
 
-	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
-	"gopkg.in/dfsr.v0/dname"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 )
 
 // Computer retrieves the DNS host name for the given distinguished name.
 func (c *Client) Computer(dn string) (computer dfsr.Computer, err error) {
-	comp, err := c.client.Open(dname.URL(dn))
+	return c.ComputerContext(context.Background(), dn)
+}
+
+// ComputerContext retrieves the DNS host name for the given distinguished
+// name. The query is abandoned if ctx is cancelled.
+func (c *Client) ComputerContext(ctx context.Context, dn string) (computer dfsr.Computer, err error) {
+	comp, err := c.open(ctx, dn)
 	if err != nil {
 		return
 	}
@@ -19,12 +24,15 @@ func (c *Client) Computer(dn string) (computer dfsr.Computer, err error) {
 	return c.computer(comp)
 }
 
-func (c *Client) computer(comp *adsi.Object) (computer dfsr.Computer, err error) {
-	computer.DN, err = comp.Path()
-	if err != nil {
+func (c *Client) computer(comp directory.Object) (computer dfsr.Computer, err error) {
+	computer.DN = comp.DN()
+
+	computer.ID, err = comp.GUID()
+	if err == directory.ErrNoAttribute {
+		err = nil // Only used to track renamed computers
+	} else if err != nil {
 		return
 	}
-	computer.DN = strings.TrimPrefix(computer.DN, "LDAP://")
 
 	computer.Host, err = comp.AttrString("dNSHostName")
 	if err != nil {
@@ -1,29 +1,21 @@
 package dfsrconfig
 
 import (
-	"strings"
+	"context"
 
-	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 )
 
-func (c *Client) connections(member *adsi.Object) (connections []dfsr.Connection, err error) {
-	mc, err := member.ToContainer()
+func (c *Client) connections(ctx context.Context, member directory.Object) (connections []dfsr.Connection, err error) {
+	children, err := c.children(ctx, member)
 	if err != nil {
 		return nil, err
 	}
-	defer mc.Close()
+	defer directory.CloseAll(children)
 
-	iter, err := mc.Children()
-	if err != nil {
-		return nil, err
-	}
-	defer iter.Close()
-
-	for child, err := iter.Next(); err == nil; child, err = iter.Next() {
-		defer child.Close()
-
-		conn, err := c.connection(child)
+	for _, child := range children {
+		conn, err := c.connection(ctx, child)
 		if err != nil {
 			return nil, err
 		}
@@ -34,7 +26,7 @@ func (c *Client) connections(member *adsi.Object) (connections []dfsr.Connection
 	return
 }
 
-func (c *Client) connection(obj *adsi.Object) (conn dfsr.Connection, err error) {
+func (c *Client) connection(ctx context.Context, obj directory.Object) (conn dfsr.Connection, err error) {
 	class, err := obj.Class()
 	if err != nil {
 		return
@@ -44,7 +36,6 @@ func (c *Client) connection(obj *adsi.Object) (conn dfsr.Connection, err error)
 	if err != nil {
 		return
 	}
-	conn.Name = strings.TrimPrefix(conn.Name, "CN=")
 
 	conn.ID, err = obj.GUID()
 	if err != nil {
@@ -62,12 +53,17 @@ func (c *Client) connection(obj *adsi.Object) (conn dfsr.Connection, err error)
 		if err != nil {
 			return
 		}
+
+		err = c.connectionAttributes(obj, &conn)
+		if err != nil {
+			return
+		}
 	} else if class == "nTDSConnection" {
 		// Domain System Volume membership
 		conn.Enabled = true // These members are always enabled
 	}
 
-	mi, err := c.MemberInfo(conn.MemberDN)
+	mi, err := c.MemberInfoContext(ctx, conn.MemberDN)
 	if err != nil {
 		return
 	}
@@ -76,3 +72,34 @@ func (c *Client) connection(obj *adsi.Object) (conn dfsr.Connection, err error)
 
 	return
 }
+
+// connectionAttributes reads the optional attributes of the DFSR connection
+// obj into conn.
+func (c *Client) connectionAttributes(obj directory.Object, conn *dfsr.Connection) (err error) {
+	if conn.Schedule, err = optionalSchedule(obj, "msDFSR-Schedule"); err != nil {
+		return
+	}
+	if conn.RDC, err = optionalBool(obj, "msDFSR-RdcEnabled"); err != nil {
+		return
+	}
+	if conn.RDCMinFileSize, err = optionalInt(obj, "msDFSR-RdcMinFileSizeInKb"); err != nil {
+		return
+	}
+	if conn.Keywords, err = optionalString(obj, "msDFSR-Keywords"); err != nil {
+		return
+	}
+	if conn.Priority, err = optionalInt(obj, "msDFSR-Priority"); err != nil {
+		return
+	}
+	if conn.Flags, err = optionalInt(obj, "msDFSR-Flags"); err != nil {
+		return
+	}
+
+	options, err := optionalInt(obj, "msDFSR-Options")
+	if err != nil {
+		return
+	}
+	conn.Options = dfsr.ConnectionOptions(options)
+
+	return
+}
@@ -1,22 +1,20 @@
 package dfsrconfig
 
 import (
-	"strings"
+	"context"
 
-	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 )
 
-func (c *Client) folders(content *adsi.Container) (folders []dfsr.Folder, err error) {
-	iter, err := content.Children()
+func (c *Client) folders(ctx context.Context, content directory.Object) (folders []dfsr.Folder, err error) {
+	children, err := c.children(ctx, content)
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
-
-	for f, err := iter.Next(); err == nil; f, err = iter.Next() {
-		defer f.Close()
+	defer directory.CloseAll(children)
 
+	for _, f := range children {
 		folder, err := c.folder(f)
 		if err != nil {
 			return nil, err
@@ -28,17 +26,58 @@ func (c *Client) folders(content *adsi.Container) (folders []dfsr.Folder, err er
 	return
 }
 
-func (c *Client) folder(f *adsi.Object) (folder dfsr.Folder, err error) {
+func (c *Client) folder(f directory.Object) (folder dfsr.Folder, err error) {
 	folder.Name, err = f.Name()
 	if err != nil {
 		return
 	}
-	folder.Name = strings.TrimPrefix(folder.Name, "CN=")
 
 	folder.ID, err = f.GUID()
 	if err != nil {
 		return
 	}
 
+	// The remaining attributes are optional
+	if folder.Description, err = optionalString(f, "description"); err != nil {
+		return
+	}
+	if folder.DfsPath, err = optionalString(f, "msDFSR-DfsPath"); err != nil {
+		return
+	}
+	if folder.FolderSettings, err = folderSettings(f); err != nil {
+		return
+	}
+	if folder.Flags, err = optionalInt(f, "msDFSR-Flags"); err != nil {
+		return
+	}
+	if folder.Options, err = optionalInt(f, "msDFSR-Options"); err != nil {
+		return
+	}
+
+	return
+}
+
+// folderSettings reads the folder settings held by obj, which is either a
+// content set or a replication group.
+func folderSettings(obj directory.Object) (settings dfsr.FolderSettings, err error) {
+	fileFilter, err := optionalString(obj, "msDFSR-FileFilter")
+	if err != nil {
+		return
+	}
+	settings.FileFilter = dfsr.ParseFilter(fileFilter)
+
+	directoryFilter, err := optionalString(obj, "msDFSR-DirectoryFilter")
+	if err != nil {
+		return
+	}
+	settings.DirectoryFilter = dfsr.ParseFilter(directoryFilter)
+
+	if settings.StagingSize, err = optionalInt(obj, "msDFSR-StagingSizeInMb"); err != nil {
+		return
+	}
+	if settings.ConflictSize, err = optionalInt(obj, "msDFSR-ConflictSizeInMb"); err != nil {
+		return
+	}
+
 	return
 }
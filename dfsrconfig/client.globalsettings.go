@@ -1,6 +1,7 @@
 package dfsrconfig
 
 import (
+	"context"
 	"time"
 
 	"gopkg.in/dfsr.v0/dfsr"
@@ -8,15 +9,24 @@ import (
 
 // Domain will fetch DFSR configuration data from the domain.
 func (c *Client) Domain() (domain dfsr.Domain, err error) {
+	return c.DomainContext(context.Background())
+}
+
+// DomainContext will fetch DFSR configuration data from the domain. The
+// query is abandoned if ctx is cancelled.
+//
+// If the configuration of some replication groups cannot be retrieved the
+// domain is returned with the remaining groups along with a *PartialError.
+func (c *Client) DomainContext(ctx context.Context) (domain dfsr.Domain, err error) {
 	start := time.Now()
 
-	nc, err := c.NamingContext()
+	nc, err := c.NamingContextContext(ctx)
 	if err != nil {
 		return
 	}
 
-	groups, err := c.Groups()
-	if err != nil {
+	groups, err := c.GroupsContext(ctx)
+	if err != nil && !IsPartial(err) {
 		return
 	}
 
@@ -24,5 +34,5 @@ func (c *Client) Domain() (domain dfsr.Domain, err error) {
 		NamingContext:  nc,
 		Groups:         groups,
 		ConfigDuration: time.Now().Sub(start),
-	}, nil
+	}, err
 }
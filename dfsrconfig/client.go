@@ -1,18 +1,20 @@
 package dfsrconfig
 
 import (
-	"strings"
+	"context"
 
 	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 	"gopkg.in/dfsr.v0/dfsrconfig/membercache"
 	"gopkg.in/dfsr.v0/dname"
 )
 
 // Client is capable of perfroming LDAP queries to retrieve DFSR configuration.
 type Client struct {
-	client   *adsi.Client
+	dir      directory.Directory
 	domainDN string
+	config   Config
 	mc       *membercache.Cache // Maps distinguished names to MemberInfo
 }
 
@@ -23,17 +25,38 @@ type Client struct {
 // responsibility to explicitly close the ADSI client at an appropriate time
 // when finished with the global settings.
 func NewClient(client *adsi.Client, domain string) *Client {
+	return NewClientWithDirectory(directory.NewADSI(client), domain)
+}
+
+// NewClientWithDirectory returns a new DFSR configuration client for the given
+// domain that retrieves configuration from the provided directory. The client
+// uses DefaultConfig.
+func NewClientWithDirectory(dir directory.Directory, domain string) *Client {
+	return NewClientWithConfig(dir, domain, DefaultConfig)
+}
+
+// NewClientWithConfig returns a new DFSR configuration client for the given
+// domain that retrieves configuration from the provided directory with the
+// given configuration.
+func NewClientWithConfig(dir directory.Directory, domain string, config Config) *Client {
 	return &Client{
-		client:   client,
+		dir:      dir,
 		domainDN: dname.Domain(domain),
-		mc:       membercache.New(),
+		config:   config,
+		mc:       membercache.NewWithLifetime(config.MemberCacheLifetime),
 	}
 }
 
 // NamingContext returns information about the default naming context for the
 // domain.
 func (c *Client) NamingContext() (nc dfsr.NamingContext, err error) {
-	domain, err := c.client.Open(dname.URL(c.domainDN))
+	return c.NamingContextContext(context.Background())
+}
+
+// NamingContextContext returns information about the default naming context
+// for the domain. The query is abandoned if ctx is cancelled.
+func (c *Client) NamingContextContext(ctx context.Context) (nc dfsr.NamingContext, err error) {
+	domain, err := c.open(ctx, c.domainDN)
 	if err != nil {
 		return
 	}
@@ -44,30 +67,20 @@ func (c *Client) NamingContext() (nc dfsr.NamingContext, err error) {
 		return
 	}
 
-	nc.Path, err = domain.Path()
-	if err != nil {
-		return
-	}
-
-	nc.DN = strings.TrimPrefix(nc.Path, "LDAP://")
+	nc.DN = domain.DN()
+	nc.Path = dname.URL(nc.DN)
 
 	nc.Description, err = domain.AttrString("description")
-	if err != nil {
-		return
+	if err == directory.ErrNoAttribute {
+		err = nil // The description is optional
 	}
 	return
 }
 
-func (c *Client) openParent(o *adsi.Object) (parent *adsi.Object, err error) {
-	path, err := o.Parent()
-	if err != nil {
-		return
-	}
-
-	return c.client.Open(path)
+func (c *Client) openParent(ctx context.Context, o directory.Object) (parent directory.Object, err error) {
+	return c.open(ctx, directory.ParentDN(o.DN()))
 }
 
-func (c *Client) openContainer(partialDN string) (*adsi.Container, error) {
-	path := dname.URL(dname.Combine(partialDN, c.domainDN))
-	return c.client.OpenContainer(path)
+func (c *Client) openContainer(ctx context.Context, partialDN string) (directory.Object, error) {
+	return c.open(ctx, dname.Combine(partialDN, c.domainDN))
 }
@@ -1,66 +1,104 @@
 package dfsrconfig
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
-	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 	"gopkg.in/dfsr.v0/dname"
 )
 
 // Groups retreives the DFSR group configuration for all groups contained in the
 // domain.
 func (c *Client) Groups() (groups []dfsr.Group, err error) {
-	container, err := c.openContainer(dname.Make("cn", "DFSR-GlobalSettings", "System"))
+	return c.GroupsContext(context.Background())
+}
+
+// GroupsContext retreives the DFSR group configuration for all groups
+// contained in the domain. Groups are retrieved concurrently, up to the
+// group concurrency limit of the client.
+//
+// If the configuration of some groups cannot be retrieved the remaining
+// groups are returned along with a *PartialError that describes each failure.
+// If ctx is cancelled the groups that have not yet been retrieved are
+// abandoned and reported in the same way. If none of the groups could be
+// retrieved an error other than a *PartialError is returned.
+func (c *Client) GroupsContext(ctx context.Context) (groups []dfsr.Group, err error) {
+	container, err := c.openContainer(ctx, dname.Make("cn", "DFSR-GlobalSettings", "System"))
 	if err != nil {
 		return nil, err
 	}
 	defer container.Close()
 
-	iter, err := container.Children()
+	children, err := c.children(ctx, container)
 	if err != nil {
 		return nil, err
 	}
-	defer iter.Close()
 
 	type groupResult struct {
 		Group dfsr.Group
 		Err   error
 	}
 
-	var results []chan groupResult
+	concurrency := c.config.GroupConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
 
-	for g, gerr := iter.Next(); gerr == nil; g, gerr = iter.Next() {
+	results := make([]chan groupResult, len(children))
+	for i, g := range children {
 		ch := make(chan groupResult, 1)
-		results = append(results, ch)
+		results[i] = ch
+
+		if i > 0 && c.config.GroupQueryDelay > 0 {
+			wait(ctx, c.config.GroupQueryDelay) // Try to avoid rate-limiting
+		}
 
-		go func(ch chan groupResult, g *adsi.Object) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			g.Close()
+			ch <- groupResult{Err: ctx.Err()}
+			continue
+		}
+
+		go func(ch chan groupResult, g directory.Object) {
+			defer func() { <-sem }()
 			defer g.Close()
-			defer close(ch)
-			group, werr := c.group(g)
+			group, werr := c.group(ctx, g)
 			ch <- groupResult{Group: group, Err: werr}
 		}(ch, g)
-
-		time.Sleep(groupQueryDelay) // Try to avoid rate-limiting
 	}
 
-	for i := 0; i < len(results); i++ {
+	var partial PartialError
+	for i := range results {
 		result := <-results[i]
-		if err != nil {
-			continue // Already hit an error, just drain the channels
-		}
-
 		if result.Err != nil {
-			err = fmt.Errorf("Error retrieving configuration for replication group %v: %v", i, result.Err.Error())
-			groups = nil
+			partial.Groups = append(partial.Groups, GroupError{DN: children[i].DN(), Err: result.Err})
 		} else {
 			groups = append(groups, result.Group)
 		}
 	}
 
+	switch {
+	case len(partial.Groups) == 0:
+	case len(groups) > 0:
+		err = &partial
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	default:
+		// A configuration without any of its groups is not usable
+		err = fmt.Errorf("none of the replication groups could be retrieved: %v", &partial)
+	}
+
 	return
 }
 
@@ -95,32 +133,35 @@ func (gs *GlobalSettings) groups() (groups []dfsr.Group, err error) {
 
 // GroupByName retreives the DFSR group configuration for the given name.
 func (c *Client) GroupByName(groupName string) (group dfsr.Group, err error) {
+	return c.GroupByNameContext(context.Background(), groupName)
+}
+
+// GroupByNameContext retreives the DFSR group configuration for the given
+// name. The query is abandoned if ctx is cancelled.
+func (c *Client) GroupByNameContext(ctx context.Context, groupName string) (group dfsr.Group, err error) {
 	groupName = strings.ToLower(groupName)
 
-	container, err := c.openContainer(dname.Make("cn", "DFSR-GlobalSettings", "System"))
+	container, err := c.openContainer(ctx, dname.Make("cn", "DFSR-GlobalSettings", "System"))
 	if err != nil {
 		return
 	}
 	defer container.Close()
 
-	iter, err := container.Children()
+	children, err := c.children(ctx, container)
 	if err != nil {
 		return
 	}
-	defer iter.Close()
-
-	for g, gerr := iter.Next(); gerr == nil; g, gerr = iter.Next() {
-		defer g.Close()
+	defer directory.CloseAll(children)
 
+	for _, g := range children {
 		candidate, cerr := g.Name()
-		if err != nil {
+		if cerr != nil {
 			err = cerr
 			return
 		}
-		candidate = strings.ToLower(candidate)
 
-		if candidate == groupName || strings.TrimPrefix(candidate, "cn=") == groupName {
-			return c.group(g)
+		if strings.ToLower(candidate) == groupName {
+			return c.group(ctx, g)
 		}
 	}
 
@@ -131,53 +172,56 @@ func (c *Client) GroupByName(groupName string) (group dfsr.Group, err error) {
 // Group retreives the DFSR group configuration for the given distinguished
 // name.
 func (c *Client) Group(groupDN string) (group dfsr.Group, err error) {
-	g, err := c.client.Open(dname.URL(groupDN))
+	return c.GroupContext(context.Background(), groupDN)
+}
+
+// GroupContext retreives the DFSR group configuration for the given
+// distinguished name. The query is abandoned if ctx is cancelled.
+func (c *Client) GroupContext(ctx context.Context, groupDN string) (group dfsr.Group, err error) {
+	g, err := c.open(ctx, groupDN)
 	if err != nil {
 		return
 	}
 	defer g.Close()
 
-	return c.group(g)
+	return c.group(ctx, g)
 }
 
-func (c *Client) group(g *adsi.Object) (group dfsr.Group, err error) {
+func (c *Client) group(ctx context.Context, g directory.Object) (group dfsr.Group, err error) {
 	start := time.Now()
 
 	group.Name, err = g.Name()
 	if err != nil {
 		return
 	}
-	group.Name = strings.TrimPrefix(group.Name, "CN=")
 
 	group.ID, err = g.GUID()
 	if err != nil {
 		return
 	}
 
-	gc, err := g.ToContainer()
-	if err != nil {
+	if err = c.groupAttributes(g, &group); err != nil {
 		return
 	}
-	defer gc.Close()
 
-	content, err := gc.Container("msDFSR-Content", "cn=Content")
+	content, err := c.open(ctx, dname.Combine("cn=Content", g.DN()))
 	if err != nil {
 		return
 	}
 	defer content.Close()
 
-	group.Folders, err = c.folders(content)
+	group.Folders, err = c.folders(ctx, content)
 	if err != nil {
 		return
 	}
 
-	topology, err := gc.Object("msDFSR-Topology", "cn=Topology")
+	topology, err := c.open(ctx, dname.Combine("cn=Topology", g.DN()))
 	if err != nil {
 		return
 	}
 	defer topology.Close()
 
-	group.Members, err = c.members(topology)
+	group.Members, err = c.members(ctx, topology)
 	if err != nil {
 		return
 	}
@@ -186,3 +230,38 @@ func (c *Client) group(g *adsi.Object) (group dfsr.Group, err error) {
 
 	return
 }
+
+// groupAttributes reads the optional attributes of the replication group g
+// into group.
+func (c *Client) groupAttributes(g directory.Object, group *dfsr.Group) (err error) {
+	groupType, err := optionalInt(g, "msDFSR-ReplicationGroupType")
+	if err != nil {
+		return
+	}
+	group.Type = dfsr.GroupType(groupType)
+
+	if group.Description, err = optionalString(g, "description"); err != nil {
+		return
+	}
+	if group.Schedule, err = optionalSchedule(g, "msDFSR-Schedule"); err != nil {
+		return
+	}
+	if group.Defaults, err = folderSettings(g); err != nil {
+		return
+	}
+
+	tombstone, err := optionalInt(g, "msDFSR-TombstoneExpiryInMin")
+	if err != nil {
+		return
+	}
+	group.TombstoneExpiry = time.Duration(tombstone) * time.Minute
+
+	if group.Flags, err = optionalInt(g, "msDFSR-Flags"); err != nil {
+		return
+	}
+	if group.Options, err = optionalInt(g, "msDFSR-Options"); err != nil {
+		return
+	}
+
+	return
+}
@@ -1,27 +1,157 @@
 package dfsrconfig
 
 import (
-	adsi "gopkg.in/adsi.v0"
+	"context"
+	"time"
+
 	"gopkg.in/dfsr.v0/dfsr"
-	"gopkg.in/dfsr.v0/dname"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 )
 
 // LocalSettings retreives the DFSR local settings for the given distinguished
-// name.
+// name, including the member's replication group subscriptions.
 func (c *Client) LocalSettings(settingsDN string) (settings dfsr.LocalSettings, err error) {
-	s, err := c.client.Open(dname.URL(settingsDN))
+	return c.LocalSettingsContext(context.Background(), settingsDN)
+}
+
+// LocalSettingsContext retreives the DFSR local settings for the given
+// distinguished name, including the member's replication group subscriptions.
+// The query is abandoned if ctx is cancelled.
+func (c *Client) LocalSettingsContext(ctx context.Context, settingsDN string) (settings dfsr.LocalSettings, err error) {
+	s, err := c.open(ctx, settingsDN)
 	if err != nil {
 		return
 	}
 	defer s.Close()
 
-	return c.localSettings(s)
+	return c.localSettings(ctx, s)
 }
 
-func (c *Client) localSettings(ls *adsi.Object) (settings dfsr.LocalSettings, err error) {
+func (c *Client) localSettings(ctx context.Context, ls directory.Object) (settings dfsr.LocalSettings, err error) {
 	settings.Version, err = ls.AttrString("msDFSR-Version")
 	if err != nil {
 		return
 	}
+
+	children, err := c.children(ctx, ls)
+	if err != nil {
+		return
+	}
+	defer directory.CloseAll(children)
+
+	for _, child := range children {
+		class, cerr := child.Class()
+		if cerr != nil {
+			err = cerr
+			return
+		}
+		if class != "msDFSR-Subscriber" {
+			continue
+		}
+
+		subscriber, serr := c.subscriber(ctx, child)
+		if serr != nil {
+			err = serr
+			return
+		}
+
+		settings.Groups = append(settings.Groups, subscriber)
+	}
+
+	return
+}
+
+func (c *Client) subscriber(ctx context.Context, obj directory.Object) (subscriber dfsr.Subscriber, err error) {
+	subscriber.MemberReference, err = obj.AttrString("msDFSR-MemberReference")
+	if err != nil {
+		return
+	}
+
+	subscriber.ReplicationGroup, err = obj.AttrGUID("msDFSR-ReplicationGroupGuid")
+	if err != nil {
+		return
+	}
+
+	children, err := c.children(ctx, obj)
+	if err != nil {
+		return
+	}
+	defer directory.CloseAll(children)
+
+	for _, child := range children {
+		class, cerr := child.Class()
+		if cerr != nil {
+			err = cerr
+			return
+		}
+		if class != "msDFSR-Subscription" {
+			continue
+		}
+
+		subscription, serr := c.subscription(child)
+		if serr != nil {
+			err = serr
+			return
+		}
+
+		subscriber.ContentSets = append(subscriber.ContentSets, subscription)
+	}
+
+	return
+}
+
+func (c *Client) subscription(obj directory.Object) (sub dfsr.Subscription, err error) {
+	if sub.ReplicationGroup, err = obj.AttrGUID("msDFSR-ReplicationGroupGuid"); err != nil {
+		return
+	}
+	if sub.ContentSet, err = obj.AttrGUID("msDFSR-ContentSetGuid"); err != nil {
+		return
+	}
+
+	// The remaining attributes are optional. The root path may be absent for
+	// memberships that have not been fully configured.
+	if sub.RootPath, err = optionalString(obj, "msDFSR-RootPath"); err != nil {
+		return
+	}
+	if sub.RootSize, err = optionalInt(obj, "msDFSR-RootSizeInMb"); err != nil {
+		return
+	}
+	if sub.StagingPath, err = optionalString(obj, "msDFSR-StagingPath"); err != nil {
+		return
+	}
+	if sub.StagingSize, err = optionalInt(obj, "msDFSR-StagingSizeInMb"); err != nil {
+		return
+	}
+	if sub.ConflictPath, err = optionalString(obj, "msDFSR-ConflictPath"); err != nil {
+		return
+	}
+	if sub.ConflictSize, err = optionalInt(obj, "msDFSR-ConflictSizeInMb"); err != nil {
+		return
+	}
+	if sub.Enabled, err = optionalBool(obj, "msDFSR-Enabled"); err != nil {
+		return
+	}
+	if sub.ReadOnly, err = optionalBool(obj, "msDFSR-ReadOnly"); err != nil {
+		return
+	}
+	if sub.Options, err = optionalInt(obj, "msDFSR-Options"); err != nil {
+		return
+	}
+	if sub.CachePolicy, err = optionalInt(obj, "msDFSR-CachePolicy"); err != nil {
+		return
+	}
+
+	maxAge, err := optionalInt(obj, "msDFSR-MaxAgeInCacheInMin")
+	if err != nil {
+		return
+	}
+	sub.MaxAgeInCache = time.Duration(maxAge) * time.Minute
+
+	minDuration, err := optionalInt(obj, "msDFSR-MinDurationCacheInMin")
+	if err != nil {
+		return
+	}
+	sub.MinDurationCache = time.Duration(minDuration) * time.Minute
+
 	return
 }
@@ -1,31 +1,23 @@
 package dfsrconfig
 
 import (
+	"context"
 	"errors"
-	"strings"
 
-	adsi "gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 	"gopkg.in/dfsr.v0/dname"
 )
 
-func (c *Client) members(topology *adsi.Object) (members []dfsr.Member, err error) {
-	tc, err := topology.ToContainer()
+func (c *Client) members(ctx context.Context, topology directory.Object) (members []dfsr.Member, err error) {
+	children, err := c.children(ctx, topology)
 	if err != nil {
 		return nil, err
 	}
-	defer tc.Close()
+	defer directory.CloseAll(children)
 
-	iter, err := tc.Children()
-	if err != nil {
-		return nil, err
-	}
-	defer iter.Close()
-
-	for m, err := iter.Next(); err == nil; m, err = iter.Next() {
-		defer m.Close()
-
-		member, err := c.member(m, "")
+	for _, m := range children {
+		member, err := c.member(ctx, m)
 		if err != nil {
 			return nil, err
 		}
@@ -39,17 +31,24 @@ func (c *Client) members(topology *adsi.Object) (members []dfsr.Member, err erro
 // Member retreives the DFSR member configuration for the given distinguished
 // name. The member's connection list is included in the returned data.
 func (c *Client) Member(memberDN string) (member dfsr.Member, err error) {
-	m, err := c.client.Open(dname.URL(memberDN))
+	return c.MemberContext(context.Background(), memberDN)
+}
+
+// MemberContext retreives the DFSR member configuration for the given
+// distinguished name. The member's connection list is included in the
+// returned data. The query is abandoned if ctx is cancelled.
+func (c *Client) MemberContext(ctx context.Context, memberDN string) (member dfsr.Member, err error) {
+	m, err := c.open(ctx, memberDN)
 	if err != nil {
 		return
 	}
 	defer m.Close()
 
-	return c.member(m, memberDN)
+	return c.member(ctx, m)
 }
 
-func (c *Client) member(m *adsi.Object, dn string) (member dfsr.Member, err error) {
-	member.MemberInfo, err = c.memberInfo(m, dn)
+func (c *Client) member(ctx context.Context, m directory.Object) (member dfsr.Member, err error) {
+	member.MemberInfo, err = c.memberInfo(ctx, m)
 	if err != nil {
 		return
 	}
@@ -58,13 +57,14 @@ func (c *Client) member(m *adsi.Object, dn string) (member dfsr.Member, err erro
 
 	if serverref, _ := m.AttrString("serverReference"); serverref != "" {
 		// Domain System Volume membership has an extra level of indirection
-		connContainer, err = c.client.Open(dname.URL(serverref))
+		connContainer, err = c.open(ctx, serverref)
 		if err != nil {
 			return
 		}
+		defer connContainer.Close()
 	}
 
-	member.Connections, err = c.connections(connContainer)
+	member.Connections, err = c.connections(ctx, connContainer)
 	return
 }
 
@@ -72,31 +72,29 @@ func (c *Client) member(m *adsi.Object, dn string) (member dfsr.Member, err erro
 // distinguished name. The member's connection list is not included in the
 // returned data.
 func (c *Client) MemberInfo(memberDN string) (member dfsr.MemberInfo, err error) {
+	return c.MemberInfoContext(context.Background(), memberDN)
+}
+
+// MemberInfoContext retreives the DFSR member configuration for the given
+// distinguished name. The member's connection list is not included in the
+// returned data. The query is abandoned if ctx is cancelled.
+func (c *Client) MemberInfoContext(ctx context.Context, memberDN string) (member dfsr.MemberInfo, err error) {
 	member, ok := c.mc.Retrieve(memberDN)
 	if ok {
 		return
 	}
 
-	m, err := c.client.Open(dname.URL(memberDN))
+	m, err := c.open(ctx, memberDN)
 	if err != nil {
 		return
 	}
 	defer m.Close()
 
-	return c.memberInfo(m, memberDN)
+	return c.memberInfo(ctx, m)
 }
 
-func (c *Client) memberInfo(obj *adsi.Object, dn string) (member dfsr.MemberInfo, err error) {
-	if dn == "" {
-		path, perr := obj.Path()
-		if err != nil {
-			err = perr
-			return
-		}
-		member.DN = strings.TrimPrefix(path, "LDAP://")
-	} else {
-		member.DN = dn
-	}
+func (c *Client) memberInfo(ctx context.Context, obj directory.Object) (member dfsr.MemberInfo, err error) {
+	member.DN = obj.DN()
 
 	class, err := obj.Class()
 	if err != nil {
@@ -106,7 +104,7 @@ func (c *Client) memberInfo(obj *adsi.Object, dn string) (member dfsr.MemberInfo
 	var compref string
 	switch class {
 	case "nTDSDSA":
-		obj, err = c.openParent(obj)
+		obj, err = c.openParent(ctx, obj)
 		if err != nil {
 			return
 		}
@@ -131,19 +129,21 @@ func (c *Client) memberInfo(obj *adsi.Object, dn string) (member dfsr.MemberInfo
 	if err != nil {
 		return
 	}
-	member.Name = strings.TrimPrefix(member.Name, "CN=")
 
 	member.ID, err = obj.GUID()
 	if err != nil {
 		return
 	}
 
-	member.Computer, err = c.Computer(compref)
+	member.Computer, err = c.ComputerContext(ctx, compref)
 	if err != nil {
 		return
 	}
 
-	member.Settings, err = c.LocalSettings(dname.Combine("cn=DFSR-LocalSettings", compref))
+	member.Settings, err = c.LocalSettingsContext(ctx, dname.Combine("cn=DFSR-LocalSettings", compref))
+	if err != nil {
+		return
+	}
 
 	c.mc.Set(member) // Add member info to the cache
 	return
@@ -0,0 +1,273 @@
+package dfsrconfig
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+	"gopkg.in/dfsr.v0/dname"
+)
+
+// Mark identifies a point in the update history of a directory server. It is
+// used to retrieve only the configuration that has changed since the mark was
+// taken.
+type Mark struct {
+	Server string // Directory server that issued the update sequence number
+	USN    int64  // Highest committed update sequence number
+}
+
+// Mark returns the current point in the update history of the directory. If
+// the directory does not support change tracking ErrChangeTrackingUnsupported
+// is returned.
+func (c *Client) Mark() (Mark, error) {
+	return c.MarkContext(context.Background())
+}
+
+// MarkContext returns the current point in the update history of the
+// directory. If the directory does not support change tracking
+// ErrChangeTrackingUnsupported is returned. The query is abandoned if ctx is
+// cancelled.
+//
+// A mark should be taken before retrieving the configuration that it will be
+// paired with, so that changes made during retrieval are not missed.
+func (c *Client) MarkContext(ctx context.Context) (Mark, error) {
+	tracker, ok := c.dir.(directory.ChangeTracker)
+	if !ok {
+		return Mark{}, ErrChangeTrackingUnsupported
+	}
+
+	var (
+		server string
+		usn    int64
+	)
+	if err := c.call(ctx, func() (err error) {
+		server, usn, err = tracker.HighestUSN()
+		return
+	}); err != nil {
+		return Mark{}, err
+	}
+	return Mark{Server: server, USN: usn}, nil
+}
+
+// Refresh returns updated configuration for the domain. It re-fetches only
+// the replication groups of prev that are affected by changes made after
+// since, and reuses the remaining groups of prev as-is.
+func (c *Client) Refresh(prev *dfsr.Domain, since Mark) (domain dfsr.Domain, err error) {
+	return c.RefreshContext(context.Background(), prev, since)
+}
+
+// RefreshContext returns updated configuration for the domain. It re-fetches
+// only the replication groups of prev that are affected by changes made after
+// since, and reuses the remaining groups of prev as-is. The query is abandoned
+// if ctx is cancelled.
+//
+// The prev configuration must have been retrieved from the same directory
+// server after since was taken. Changes are detected by the uSNChanged
+// attribute of objects in the domain naming context. Deleted objects are
+// detected when the directory reports them, as directories accessed over LDAP
+// do. Objects in other naming contexts, such as the Domain System Volume
+// connections held in the configuration naming context, are not detected.
+// Callers should perform a complete retrieval periodically to account for
+// them.
+//
+// If the directory does not support change tracking
+// ErrChangeTrackingUnsupported is returned. If some groups cannot be
+// retrieved the domain is returned along with a *PartialError, and the
+// previous configuration of those groups is retained.
+func (c *Client) RefreshContext(ctx context.Context, prev *dfsr.Domain, since Mark) (domain dfsr.Domain, err error) {
+	start := time.Now()
+
+	tracker, ok := c.dir.(directory.ChangeTracker)
+	if !ok {
+		return dfsr.Domain{}, ErrChangeTrackingUnsupported
+	}
+
+	var changes []directory.Change
+	err = c.call(ctx, func() (err error) {
+		changes, err = tracker.ChangedSince(c.domainDN, since.USN)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	affected, err := c.affectedGroups(ctx, prev, changes)
+	if err != nil {
+		return
+	}
+
+	domain.NamingContext = prev.NamingContext
+	if len(affected) == 0 {
+		domain.Groups = prev.Groups
+		domain.ConfigDuration = time.Now().Sub(start)
+		return
+	}
+
+	// Build an updated list of groups, which also picks up added and removed
+	// groups
+	container, err := c.openContainer(ctx, dname.Make("cn", "DFSR-GlobalSettings", "System"))
+	if err != nil {
+		return
+	}
+	defer container.Close()
+
+	children, err := c.children(ctx, container)
+	if err != nil {
+		return
+	}
+	defer directory.CloseAll(children)
+
+	previous := make(map[string]*dfsr.Group, len(prev.Groups))
+	for g := range prev.Groups {
+		previous[strings.ToLower(prev.Groups[g].Name)] = &prev.Groups[g]
+	}
+
+	var partial PartialError
+	for _, child := range children {
+		name := strings.ToLower(directory.RDNValue(child.DN()))
+		old, existed := previous[name]
+		if existed && !affected[name] {
+			domain.Groups = append(domain.Groups, *old)
+			continue
+		}
+
+		if existed {
+			for m := range old.Members {
+				c.mc.Invalidate(old.Members[m].DN)
+			}
+		}
+
+		group, gerr := c.group(ctx, child)
+		if gerr != nil {
+			partial.Groups = append(partial.Groups, GroupError{DN: child.DN(), Err: gerr})
+			if existed {
+				domain.Groups = append(domain.Groups, *old)
+			}
+			continue
+		}
+		domain.Groups = append(domain.Groups, group)
+	}
+
+	domain.ConfigDuration = time.Now().Sub(start)
+	if len(partial.Groups) > 0 {
+		err = &partial
+	}
+	return
+}
+
+// affectedGroups returns the lower-case names of the groups in prev that are
+// affected by the given changes, including groups that have been added.
+// Cached member information for members on changed computers is invalidated.
+func (c *Client) affectedGroups(ctx context.Context, prev *dfsr.Domain, changes []directory.Change) (affected map[string]bool, err error) {
+	affected = make(map[string]bool)
+
+	settingsDN := strings.ToLower(dname.Combine(dname.Make("cn", "DFSR-GlobalSettings", "System"), c.domainDN))
+
+	// Map the computers of each member to the groups they belong to
+	type computerRef struct {
+		dfsr.Computer
+		groups []string
+	}
+	byID := make(map[uuid.UUID]*computerRef)
+	byDN := make(map[string]*computerRef)
+	for g := range prev.Groups {
+		group := &prev.Groups[g]
+		name := strings.ToLower(group.Name)
+		for m := range group.Members {
+			computer := group.Members[m].Computer
+			dn := strings.ToLower(computer.DN)
+			ref, ok := byDN[dn]
+			if !ok {
+				ref = &computerRef{Computer: computer}
+				byDN[dn] = ref
+				if computer.ID != uuid.Nil {
+					byID[computer.ID] = ref
+				}
+			}
+			ref.groups = append(ref.groups, name)
+		}
+	}
+
+	invalidate := func(ref *computerRef) {
+		c.mc.InvalidateComputer(ref.DN)
+		for _, name := range ref.groups {
+			affected[name] = true
+		}
+	}
+
+	for _, change := range changes {
+		dn := strings.ToLower(change.DN)
+
+		// Objects within the replication group configuration
+		if strings.HasSuffix(dn, ","+settingsDN) {
+			rdns := strings.TrimSuffix(dn, ","+settingsDN)
+			// The last relative name before the settings container is the group
+			for rdns != "" && directory.ParentDN(rdns) != "" {
+				rdns = directory.ParentDN(rdns)
+			}
+			affected[strings.ToLower(directory.RDNValue(rdns))] = true
+			continue
+		}
+
+		// Member computers, which may have been renamed or moved
+		if change.Class == "computer" {
+			ref, ok := byID[change.GUID]
+			if !ok {
+				ref, ok = byDN[dn]
+			}
+			if !ok {
+				continue // Not a member computer
+			}
+			if change.Deleted || !strings.EqualFold(ref.DN, change.DN) {
+				invalidate(ref)
+				continue
+			}
+			computer, cerr := c.ComputerContext(ctx, change.DN)
+			if cerr != nil {
+				return nil, cerr
+			}
+			if !strings.EqualFold(computer.Host, ref.Host) {
+				invalidate(ref)
+			}
+			continue
+		}
+
+		// Local settings and subscriptions beneath member computers
+		for parent := directory.ParentDN(dn); parent != ""; parent = directory.ParentDN(parent) {
+			if ref, ok := byDN[parent]; ok {
+				invalidate(ref)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// call invokes fn and waits for it to return. If ctx is done or the object
+// timeout elapses first the call is abandoned and the context's error is
+// returned. An abandoned fn continues to run, so it must not write to
+// variables that are read after call returns with an error.
+func (c *Client) call(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.objectContext(ctx)
+	defer cancel()
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- fn()
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
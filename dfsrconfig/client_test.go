@@ -0,0 +1,200 @@
+package dfsrconfig_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+// Objects of the fixture in testdata/domain.ldif
+const (
+	fixture     = "testdata/domain.ldif"
+	domainName  = "example.com"
+	sysvolDN    = "CN=Domain System Volume,CN=DFSR-GlobalSettings,CN=System,DC=example,DC=com"
+	dc2NTDSDN   = "CN=NTDS Settings,CN=DC2,CN=Servers,CN=Default-First-Site-Name,CN=Sites,CN=Configuration,DC=example,DC=com"
+	dc2ServerDN = "CN=DC2,CN=Servers,CN=Default-First-Site-Name,CN=Sites,CN=Configuration,DC=example,DC=com"
+	fs1MemberDN = "CN=FS1,CN=Topology,CN=Example,CN=DFSR-GlobalSettings,CN=System,DC=example,DC=com"
+	fs2MemberDN = "CN=FS2,CN=Topology,CN=Example,CN=DFSR-GlobalSettings,CN=System,DC=example,DC=com"
+	fs1DN       = "CN=FS1,CN=Computers,DC=example,DC=com"
+)
+
+var (
+	sysvolID      = uuid.MustParse("5a1b2c3d-0001-4000-8000-000000000001")
+	sysvolShareID = uuid.MustParse("5a1b2c3d-0002-4000-8000-000000000001")
+	dc2ServerID   = uuid.MustParse("5a1b2c3d-0004-4000-8000-000000000002")
+	exampleID     = uuid.MustParse("23456789-0001-4002-8003-000405060708")
+	exampleDataID = uuid.MustParse("7e000000-0002-4000-8000-000000000001")
+	fs1ID         = uuid.MustParse("7e000000-0007-4000-8000-000000000001")
+	fromFS2ID     = uuid.MustParse("7e000000-0004-4000-8000-000000000001")
+)
+
+func newDirectory(t *testing.T) *directory.Memory {
+	t.Helper()
+	f, err := os.Open(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	dir, err := directory.LoadLDIF(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newClient(t *testing.T) *dfsrconfig.Client {
+	t.Helper()
+	return dfsrconfig.NewClientWithDirectory(newDirectory(t), domainName)
+}
+
+func member(t *testing.T, group *dfsr.Group, name string) *dfsr.Member {
+	t.Helper()
+	for m := range group.Members {
+		if group.Members[m].Name == name {
+			return &group.Members[m]
+		}
+	}
+	t.Fatalf("member %s not found in %s", name, group.Name)
+	return nil
+}
+
+func TestDomain(t *testing.T) {
+	c := newClient(t)
+
+	domain, err := c.Domain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain.Description != "Example domain" {
+		t.Errorf("domain description is %q", domain.Description)
+	}
+
+	var names []string
+	for _, group := range domain.Groups {
+		names = append(names, group.Name)
+	}
+	if want := []string{"Domain System Volume", "Example"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("domain has groups %v, want %v", names, want)
+	}
+}
+
+func TestSysvolGroup(t *testing.T) {
+	c := newClient(t)
+
+	group, err := c.Group(sysvolDN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != sysvolID || group.Type != dfsr.GroupTypeSysvol {
+		t.Errorf("unexpected group %s of type %v", group.ID, group.Type)
+	}
+	if len(group.Folders) != 1 || group.Folders[0].ID != sysvolShareID {
+		t.Fatalf("unexpected folders %+v", group.Folders)
+	}
+	if filter := group.Folders[0].FileFilter; !reflect.DeepEqual(filter, dfsr.Filter{"~*", "*.bak", "*.tmp"}) {
+		t.Errorf("unexpected file filter %v", filter)
+	}
+
+	dc1 := member(t, &group, "DC1")
+	if dc1.Computer.Host != "dc1.example.com" {
+		t.Errorf("DC1 has host %s", dc1.Computer.Host)
+	}
+
+	// Connections are read from the NTDS settings of the domain controller,
+	// and each source is resolved through its server object
+	if len(dc1.Connections) != 1 {
+		t.Fatalf("DC1 has %d connections, want 1", len(dc1.Connections))
+	}
+	conn := dc1.Connections[0]
+	if conn.Name != "From DC2" || !conn.Enabled || conn.MemberDN != dc2NTDSDN {
+		t.Errorf("unexpected connection %+v", conn)
+	}
+	if conn.Computer.Host != "dc2.example.com" {
+		t.Errorf("connection source has host %q, want dc2.example.com", conn.Computer.Host)
+	}
+
+	sub, ok := dc1.Settings.Subscription(sysvolID, sysvolShareID)
+	if !ok {
+		t.Fatal("DC1 has no SYSVOL subscription")
+	}
+	if sub.RootPath != `C:\Windows\SYSVOL\domain` || sub.StagingSize != 4096 || !sub.Enabled {
+		t.Errorf("unexpected subscription %+v", sub)
+	}
+}
+
+func TestMemberInfoIndirection(t *testing.T) {
+	c := newClient(t)
+
+	for _, dn := range []string{dc2NTDSDN, dc2ServerDN} {
+		info, err := c.MemberInfo(dn)
+		if err != nil {
+			t.Fatalf("%s: %v", dn, err)
+		}
+		if info.Name != "DC2" || info.ID != dc2ServerID || info.Computer.Host != "dc2.example.com" {
+			t.Errorf("%s: unexpected member info %+v", dn, info)
+		}
+		if info.DN != dn {
+			t.Errorf("member info has DN %s, want %s", info.DN, dn)
+		}
+	}
+
+	if _, err := c.MemberInfo(fs1DN); err == nil {
+		t.Error("member info was returned for a computer object")
+	}
+}
+
+func TestMemberGroup(t *testing.T) {
+	c := newClient(t)
+
+	group, err := c.GroupByName("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != exampleID || group.Type != dfsr.GroupTypeOther || group.Description != "File server replication" {
+		t.Errorf("unexpected group %s of type %v: %s", group.ID, group.Type, group.Description)
+	}
+	if group.Defaults.StagingSize != 8192 {
+		t.Errorf("group staging size is %d", group.Defaults.StagingSize)
+	}
+	if len(group.Folders) != 1 || group.Folders[0].DfsPath != `\\example.com\files\data` {
+		t.Fatalf("unexpected folders %+v", group.Folders)
+	}
+
+	fs1 := member(t, &group, "FS1")
+	if fs1.DN != fs1MemberDN || fs1.Computer.DN != fs1DN || fs1.Computer.ID != fs1ID {
+		t.Errorf("unexpected member %+v", fs1.MemberInfo)
+	}
+	if len(fs1.Connections) != 1 {
+		t.Fatalf("FS1 has %d connections, want 1", len(fs1.Connections))
+	}
+	conn := fs1.Connections[0]
+	if conn.ID != fromFS2ID || !conn.Enabled || !conn.RDC || conn.RDCMinFileSize != 64 {
+		t.Errorf("unexpected connection %+v", conn)
+	}
+	if conn.MemberDN != fs2MemberDN || conn.Computer.Host != "fs2.example.com" {
+		t.Errorf("connection source is %s on %s", conn.MemberDN, conn.Computer.Host)
+	}
+
+	sub, ok := fs1.Settings.Subscription(exampleID, exampleDataID)
+	if !ok || sub.RootPath != `D:\Data` || !sub.Enabled {
+		t.Errorf("unexpected FS1 subscription %+v", sub)
+	}
+
+	fs2 := member(t, &group, "FS2")
+	if len(fs2.Connections) != 1 || fs2.Connections[0].Enabled {
+		t.Errorf("unexpected FS2 connections %+v", fs2.Connections)
+	}
+
+	// The membership of FS2 has not been configured, so its subscription has
+	// no root path
+	sub, ok = fs2.Settings.Subscription(exampleID, exampleDataID)
+	if !ok || sub.RootPath != "" || sub.Enabled {
+		t.Errorf("unexpected FS2 subscription %+v", sub)
+	}
+}
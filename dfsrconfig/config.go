@@ -0,0 +1,30 @@
+package dfsrconfig
+
+import "time"
+
+// Config holds the query configuration of a Client.
+type Config struct {
+	// ObjectTimeout is the maximum duration of each individual directory
+	// query. Zero means no per-object limit is applied.
+	ObjectTimeout time.Duration
+
+	// GroupConcurrency is the maximum number of replication groups that are
+	// retrieved at the same time. Zero or less means one at a time.
+	GroupConcurrency int
+
+	// GroupQueryDelay is the delay between the start of successive replication
+	// group retrievals, which helps to avoid rate-limiting by LDAP servers.
+	GroupQueryDelay time.Duration
+
+	// MemberCacheLifetime is the lifetime of cached member information. Zero
+	// means cached member information does not expire.
+	MemberCacheLifetime time.Duration
+}
+
+// DefaultConfig is the default configuration of a Client.
+var DefaultConfig = Config{
+	ObjectTimeout:       defaultObjectTimeout,
+	GroupConcurrency:    defaultGroupConcurrency,
+	GroupQueryDelay:     groupQueryDelay,
+	MemberCacheLifetime: defaultMemberCacheLifetime,
+}
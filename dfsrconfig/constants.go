@@ -7,6 +7,10 @@ import (
 
 const updateChanSize = 16
 const groupQueryDelay = 25 * time.Millisecond // Group query delay to avoid rate-limiting by LDAP servers
+const defaultGroupConcurrency = 8
+const defaultObjectTimeout = 30 * time.Second
+const defaultMemberCacheLifetime = time.Hour
+const fullRefreshInterval = 6 * time.Hour // Maximum time between complete configuration retrievals
 
 var (
 	// ErrClosed is returned from calls to a service or interface in the event
@@ -16,4 +20,8 @@ var (
 	// ErrDomainLookupFailed is returned when the appropriate domain naming
 	// context cannot be determined.
 	ErrDomainLookupFailed = errors.New("unable to determine DFSR configuration domain")
+
+	// ErrChangeTrackingUnsupported is returned when incremental retrieval is
+	// requested from a directory that cannot report changed objects.
+	ErrChangeTrackingUnsupported = errors.New("directory does not support change tracking")
 )
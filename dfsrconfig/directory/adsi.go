@@ -0,0 +1,206 @@
+package directory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-ole/go-ole"
+	"github.com/google/uuid"
+	adsi "gopkg.in/adsi.v0"
+	"gopkg.in/dfsr.v0/dname"
+)
+
+var _ = (Directory)((*ADSI)(nil))    // Compile-time interface compliance check
+var _ = (Object)((*adsiObject)(nil)) // Compile-time interface compliance check
+
+// ADSI is a directory that accesses Active Directory through the Active
+// Directory Service Interfaces on Windows.
+type ADSI struct {
+	client *adsi.Client
+}
+
+// NewADSI returns a directory that performs queries with the given ADSI
+// client.
+//
+// The provided client is retained by the directory. It is the caller's
+// responsibility to close the client at an appropriate time when finished
+// with the directory.
+func NewADSI(client *adsi.Client) *ADSI {
+	return &ADSI{client: client}
+}
+
+// Open returns the object with the given distinguished name.
+func (d *ADSI) Open(dn string) (Object, error) {
+	obj, err := d.client.Open(dname.URL(dn))
+	if err != nil {
+		return nil, err
+	}
+	return newADSIObject(obj)
+}
+
+type adsiObject struct {
+	obj *adsi.Object
+	dn  string
+}
+
+// newADSIObject wraps obj. It takes ownership of obj and closes it if an error
+// is returned.
+func newADSIObject(obj *adsi.Object) (*adsiObject, error) {
+	path, err := obj.Path()
+	if err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return &adsiObject{obj: obj, dn: strings.TrimPrefix(path, "LDAP://")}, nil
+}
+
+func (o *adsiObject) DN() string {
+	return o.dn
+}
+
+func (o *adsiObject) Name() (string, error) {
+	name, err := o.obj.Name()
+	if err != nil {
+		return "", err
+	}
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[i+1:]
+	}
+	return name, nil
+}
+
+func (o *adsiObject) GUID() (uuid.UUID, error) {
+	return o.obj.GUID()
+}
+
+func (o *adsiObject) Class() (string, error) {
+	return o.obj.Class()
+}
+
+func (o *adsiObject) AttrString(name string) (string, error) {
+	value, err := o.obj.AttrString(name)
+	return value, attrErr(err)
+}
+
+func (o *adsiObject) AttrBool(name string) (bool, error) {
+	value, err := o.obj.AttrBool(name)
+	return value, attrErr(err)
+}
+
+func (o *adsiObject) AttrInt(name string) (int, error) {
+	values, err := o.obj.Attr(name)
+	if err != nil {
+		return 0, attrErr(err)
+	}
+	if len(values) == 0 {
+		return 0, ErrNoAttribute
+	}
+	switch v := values[0].(type) {
+	case int:
+		return v, nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("attribute %s has unexpected type %T", name, v)
+	}
+}
+
+func (o *adsiObject) AttrGUID(name string) (uuid.UUID, error) {
+	values, err := o.obj.Attr(name)
+	if err != nil {
+		return uuid.Nil, attrErr(err)
+	}
+	if len(values) == 0 {
+		return uuid.Nil, ErrNoAttribute
+	}
+	raw, ok := values[0].([]byte)
+	if !ok {
+		return uuid.Nil, ErrInvalidGUID
+	}
+	return GUIDFromBytes(raw)
+}
+
+func (o *adsiObject) AttrBytes(name string) ([]byte, error) {
+	values, err := o.obj.Attr(name)
+	if err != nil {
+		return nil, attrErr(err)
+	}
+	if len(values) == 0 {
+		return nil, ErrNoAttribute
+	}
+	raw, ok := values[0].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("attribute %s has unexpected type %T", name, values[0])
+	}
+	return raw, nil
+}
+
+func (o *adsiObject) Children() (children []Object, err error) {
+	container, err := o.obj.ToContainer()
+	if err != nil {
+		return nil, err
+	}
+	defer container.Close()
+
+	iter, err := container.Children()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for child, cerr := iter.Next(); cerr == nil; child, cerr = iter.Next() {
+		obj, oerr := newADSIObject(child)
+		if oerr != nil {
+			CloseAll(children)
+			return nil, oerr
+		}
+		children = append(children, obj)
+	}
+
+	return
+}
+
+func (o *adsiObject) Close() {
+	o.obj.Close()
+}
+
+// hrPropertyNotFound is the E_ADS_PROPERTY_NOT_FOUND result code that ADSI
+// returns when an attribute is not present on an object.
+const hrPropertyNotFound = 0x8000500D
+
+// attrErr translates errors returned by ADSI attribute queries. The error
+// reported for a missing attribute is replaced by ErrNoAttribute, so that
+// callers can recognize optional attributes regardless of the directory
+// backend in use.
+func attrErr(err error) error {
+	if isPropertyNotFound(err) {
+		return ErrNoAttribute
+	}
+	return err
+}
+
+// isPropertyNotFound returns true if err is a COM error indicating that an
+// attribute is not present. The code may be returned directly, or as the
+// exception code of a failed IDispatch invocation.
+func isPropertyNotFound(err error) bool {
+	oleErr, ok := err.(*ole.OleError)
+	if !ok {
+		return false
+	}
+	if oleErr.Code() == hrPropertyNotFound {
+		return true
+	}
+	if info, ok := oleErr.SubError().(interface {
+		SCODE() uint32
+	}); ok && info.SCODE() == hrPropertyNotFound {
+		return true
+	}
+	return false
+}
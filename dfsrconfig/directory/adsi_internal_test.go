@@ -0,0 +1,21 @@
+package directory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-ole/go-ole"
+)
+
+func TestAttrErr(t *testing.T) {
+	if attrErr(ole.NewError(hrPropertyNotFound)) != ErrNoAttribute {
+		t.Fatal("direct")
+	}
+	if attrErr(ole.NewErrorWithSubError(0x80020009, "x", ole.EXCEPINFO{})) == ErrNoAttribute {
+		t.Fatal("scode zero")
+	}
+	other := errors.New("x")
+	if attrErr(other) != other || attrErr(nil) != nil {
+		t.Fatal("passthrough")
+	}
+}
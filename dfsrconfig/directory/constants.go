@@ -0,0 +1,24 @@
+package directory
+
+import "errors"
+
+const memoryServer = "memory" // Server name reported by in-memory directories
+
+var (
+	// ErrNotFound is returned when a requested object does not exist.
+	ErrNotFound = errors.New("directory object not found")
+
+	// ErrNoAttribute is returned when a requested attribute is not present on
+	// an object.
+	ErrNoAttribute = errors.New("directory attribute not present")
+
+	// ErrInvalidGUID is returned when an object has a malformed objectGUID.
+	ErrInvalidGUID = errors.New("invalid object GUID")
+
+	// ErrInvalidBool is returned when a boolean attribute has a value other
+	// than TRUE or FALSE.
+	ErrInvalidBool = errors.New("invalid boolean attribute value")
+
+	// ErrMissingDN is returned when a fixture entry lacks a distinguished name.
+	ErrMissingDN = errors.New("fixture entry is missing a distinguished name")
+)
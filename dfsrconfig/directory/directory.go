@@ -0,0 +1,86 @@
+package directory
+
+import "github.com/google/uuid"
+
+// Directory provides access to the objects of a directory service.
+type Directory interface {
+	// Open returns the object with the given distinguished name.
+	Open(dn string) (Object, error)
+}
+
+// Object is an object within a directory. Objects may hold resources that
+// must be released with a call to Close when finished with the object.
+type Object interface {
+	// DN returns the distinguished name of the object.
+	DN() string
+
+	// Name returns the value of the object's relative distinguished name,
+	// without its attribute type.
+	Name() (string, error)
+
+	// GUID returns the objectGUID of the object.
+	GUID() (uuid.UUID, error)
+
+	// Class returns the most specific object class of the object.
+	Class() (string, error)
+
+	// AttrString returns the first value of the requested attribute. If the
+	// attribute is not present an error is returned.
+	AttrString(name string) (string, error)
+
+	// AttrBool returns the value of the requested boolean attribute. If the
+	// attribute is not present an error is returned.
+	AttrBool(name string) (bool, error)
+
+	// AttrInt returns the value of the requested integer attribute. If the
+	// attribute is not present an error is returned.
+	AttrInt(name string) (int, error)
+
+	// AttrGUID returns the value of the requested GUID attribute, which is
+	// stored as an octet string. If the attribute is not present an error is
+	// returned.
+	AttrGUID(name string) (uuid.UUID, error)
+
+	// AttrBytes returns the first value of the requested octet string
+	// attribute. If the attribute is not present an error is returned.
+	AttrBytes(name string) ([]byte, error)
+
+	// Children returns the immediate children of the object.
+	Children() ([]Object, error)
+
+	// Close releases any resources consumed by the object.
+	Close()
+}
+
+// Change identifies an object that has changed within a directory.
+type Change struct {
+	DN      string
+	GUID    uuid.UUID
+	Class   string // Most specific object class
+	Deleted bool   // The object has been deleted and DN is its last known name
+}
+
+// ChangeTracker is implemented by directories that can report the objects
+// that have changed since a point in the update history of a directory server.
+//
+// Update sequence numbers are local to the server that issued them. Callers
+// must compare the server names returned by HighestUSN to ensure that
+// successive calls were answered by the same server.
+type ChangeTracker interface {
+	// HighestUSN returns the name of the directory server answering queries
+	// and its highest committed update sequence number.
+	HighestUSN() (server string, usn int64, err error)
+
+	// ChangedSince returns the objects within the subtree rooted at base with
+	// a uSNChanged value greater than usn. Implementations may limit the
+	// result to the objects that can affect the DFSR configuration. Deleted
+	// objects are included with Deleted set if the directory retains them.
+	ChangedSince(base string, usn int64) ([]Change, error)
+}
+
+// CloseAll closes each of the given objects.
+func CloseAll(objects []Object) {
+	for _, obj := range objects {
+		obj.Close()
+	}
+}
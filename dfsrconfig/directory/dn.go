@@ -0,0 +1,60 @@
+package directory
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ParentDN returns the distinguished name of the parent of dn, or an empty
+// string if dn has no parent.
+func ParentDN(dn string) string {
+	for i := 0; i < len(dn); i++ {
+		switch dn[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case ',':
+			return dn[i+1:]
+		}
+	}
+	return ""
+}
+
+// RDNValue returns the value of the relative distinguished name of dn,
+// without its attribute type. Escape sequences are not decoded.
+func RDNValue(dn string) string {
+	rdn := dn
+	if parent := ParentDN(dn); parent != "" {
+		rdn = dn[:len(dn)-len(parent)-1]
+	}
+	if i := strings.Index(rdn, "="); i >= 0 {
+		return rdn[i+1:]
+	}
+	return rdn
+}
+
+// ParseBool parses an LDAP boolean value, which must be TRUE or FALSE.
+func ParseBool(value string) (bool, error) {
+	switch strings.ToUpper(value) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	default:
+		return false, ErrInvalidBool
+	}
+}
+
+// GUIDFromBytes returns the GUID represented by the given objectGUID bytes.
+// Active Directory stores GUIDs with their first three fields in little-endian
+// byte order.
+func GUIDFromBytes(raw []byte) (id uuid.UUID, err error) {
+	if len(raw) != 16 {
+		return id, ErrInvalidGUID
+	}
+	copy(id[:], raw)
+	id[0], id[1], id[2], id[3] = id[3], id[2], id[1], id[0]
+	id[4], id[5] = id[5], id[4]
+	id[6], id[7] = id[7], id[6]
+	return
+}
@@ -0,0 +1,8 @@
+// Package directory defines a minimal interface for reading the objects of a
+// directory service, along with implementations backed by ADSI and by
+// in-memory fixtures.
+//
+// The DFSR configuration walking logic of the dfsrconfig package operates on
+// this interface, which allows it to be used with any directory backend and
+// to be exercised against fixtures loaded from LDIF or JSON.
+package directory
@@ -0,0 +1,78 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LoadJSON returns an in-memory directory holding the entries of the JSON
+// content read from r.
+//
+// The content must be an array of objects. Each object holds the
+// distinguished name of an entry in its "dn" member and the attributes of the
+// entry in its other members. Attribute values may be strings, numbers,
+// booleans or arrays of them. Boolean values are stored as TRUE or FALSE.
+//
+//   [
+//     {"dn": "CN=FS1,CN=Computers,DC=example,DC=com", "objectClass": ["top", "computer"], "dNSHostName": "fs1.example.com"}
+//   ]
+func LoadJSON(r io.Reader) (*Memory, error) {
+	var records []map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for i, record := range records {
+		entry := Entry{Attributes: make(map[string][]string, len(record))}
+		for name, raw := range record {
+			values, err := jsonValues(raw)
+			if err != nil {
+				return nil, fmt.Errorf("json entry %d attribute \"%s\": %v", i, name, err)
+			}
+			if strings.EqualFold(name, "dn") {
+				if len(values) != 1 {
+					return nil, fmt.Errorf("json entry %d: %v", i, ErrMissingDN)
+				}
+				entry.DN = values[0]
+				continue
+			}
+			entry.Attributes[name] = values
+		}
+		entries = append(entries, entry)
+	}
+
+	return NewMemory(entries...)
+}
+
+func jsonValues(raw json.RawMessage) (values []string, err error) {
+	var list []interface{}
+	if err = json.Unmarshal(raw, &list); err != nil {
+		var single interface{}
+		if err = json.Unmarshal(raw, &single); err != nil {
+			return nil, err
+		}
+		list = []interface{}{single}
+	}
+
+	for _, v := range list {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case bool:
+			if v {
+				values = append(values, "TRUE")
+			} else {
+				values = append(values, "FALSE")
+			}
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			return nil, fmt.Errorf("unsupported value %v", v)
+		}
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package directory
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadLDIF returns an in-memory directory holding the entries of the LDIF
+// content read from r.
+//
+// Only content records are supported. Values may be plain or base64-encoded,
+// and folded lines are joined. Version lines, comments and add change types
+// are ignored.
+func LoadLDIF(r io.Reader) (*Memory, error) {
+	var (
+		entries []Entry
+		current *Entry
+		lines   []string // Unfolded lines of the current record
+		lineNum int
+	)
+
+	flush := func() error {
+		defer func() { lines = lines[:0] }()
+		for _, line := range lines {
+			if err := addLDIFLine(&current, line); err != nil {
+				return fmt.Errorf("ldif line %d: %v", lineNum, err)
+			}
+		}
+		if current != nil {
+			entries = append(entries, *current)
+			current = nil
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, " "):
+			if n := len(lines); n > 0 {
+				lines[n-1] += line[1:]
+			}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return NewMemory(entries...)
+}
+
+func addLDIFLine(current **Entry, line string) error {
+	i := strings.Index(line, ":")
+	if i < 1 {
+		return fmt.Errorf("invalid line \"%s\"", line)
+	}
+	name, value := line[:i], line[i+1:]
+
+	switch {
+	case strings.HasPrefix(value, ":"):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	case strings.HasPrefix(value, "<"):
+		return fmt.Errorf("URL values are not supported for attribute \"%s\"", name)
+	default:
+		value = strings.TrimLeft(value, " ")
+	}
+
+	switch {
+	case strings.EqualFold(name, "version") && *current == nil:
+		return nil
+	case strings.EqualFold(name, "dn"):
+		*current = &Entry{DN: value, Attributes: make(map[string][]string)}
+		return nil
+	case *current == nil:
+		return ErrMissingDN
+	case strings.EqualFold(name, "changetype"):
+		if !strings.EqualFold(value, "add") {
+			return fmt.Errorf("unsupported change type \"%s\"", value)
+		}
+		return nil
+	}
+
+	(*current).Attributes[name] = append((*current).Attributes[name], value)
+	return nil
+}
@@ -0,0 +1,204 @@
+package directory
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var _ = (Directory)((*Memory)(nil))     // Compile-time interface compliance check
+var _ = (Object)((*memoryObject)(nil))  // Compile-time interface compliance check
+var _ = (ChangeTracker)((*Memory)(nil)) // Compile-time interface compliance check
+
+// Entry is a directory object held in memory.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string // Maps attribute names to values
+}
+
+// Memory is a read-only directory held in memory. It is typically loaded from
+// a fixture with LoadLDIF or LoadJSON.
+//
+// Distinguished names and attribute names are matched without regard to case.
+// GUID attributes such as objectGUID may hold either the 16 bytes of a GUID in
+// Active Directory byte order or its canonical string form. Other octet string
+// attributes, such as msDFSR-Schedule, hold their raw bytes.
+type Memory struct {
+	entries  map[string]*Entry   // Maps lower-case DNs to entries
+	children map[string][]string // Maps lower-case DNs to the sorted lower-case DNs of their children
+}
+
+// NewMemory returns an in-memory directory holding the given entries.
+func NewMemory(entries ...Entry) (*Memory, error) {
+	m := &Memory{
+		entries:  make(map[string]*Entry, len(entries)),
+		children: make(map[string][]string),
+	}
+
+	for _, entry := range entries {
+		if entry.DN == "" {
+			return nil, ErrMissingDN
+		}
+		e := &Entry{
+			DN:         entry.DN,
+			Attributes: make(map[string][]string, len(entry.Attributes)),
+		}
+		for name, values := range entry.Attributes {
+			name = strings.ToLower(name)
+			e.Attributes[name] = append(e.Attributes[name], values...)
+		}
+
+		key := strings.ToLower(entry.DN)
+		if _, exists := m.entries[key]; !exists {
+			parent := strings.ToLower(ParentDN(entry.DN))
+			m.children[parent] = append(m.children[parent], key)
+		}
+		m.entries[key] = e
+	}
+
+	for _, children := range m.children {
+		sort.Strings(children)
+	}
+
+	return m, nil
+}
+
+// Open returns the object with the given distinguished name.
+func (m *Memory) Open(dn string) (Object, error) {
+	entry, ok := m.entries[strings.ToLower(dn)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &memoryObject{m: m, entry: entry}, nil
+}
+
+// HighestUSN returns the highest uSNChanged value held by the entries of the
+// directory. The server name of an in-memory directory is always "memory".
+func (m *Memory) HighestUSN() (server string, usn int64, err error) {
+	for _, entry := range m.entries {
+		if value, ok := entryUSN(entry); ok && value > usn {
+			usn = value
+		}
+	}
+	return memoryServer, usn, nil
+}
+
+// ChangedSince returns the entries within the subtree rooted at base with a
+// uSNChanged value greater than usn. Entries without a uSNChanged value are
+// never reported.
+func (m *Memory) ChangedSince(base string, usn int64) (changes []Change, err error) {
+	base = strings.ToLower(base)
+
+	keys := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		if key == base || strings.HasSuffix(key, ","+base) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry := m.entries[key]
+		if value, ok := entryUSN(entry); !ok || value <= usn {
+			continue
+		}
+		obj := &memoryObject{m: m, entry: entry}
+		id, _ := obj.GUID()
+		class, _ := obj.Class()
+		changes = append(changes, Change{DN: entry.DN, GUID: id, Class: class})
+	}
+
+	return
+}
+
+func entryUSN(entry *Entry) (usn int64, ok bool) {
+	values := entry.Attributes["usnchanged"]
+	if len(values) == 0 {
+		return 0, false
+	}
+	usn, err := strconv.ParseInt(values[0], 10, 64)
+	return usn, err == nil
+}
+
+type memoryObject struct {
+	m     *Memory
+	entry *Entry
+}
+
+func (o *memoryObject) DN() string {
+	return o.entry.DN
+}
+
+func (o *memoryObject) Name() (string, error) {
+	return RDNValue(o.entry.DN), nil
+}
+
+func (o *memoryObject) GUID() (uuid.UUID, error) {
+	return o.AttrGUID("objectGUID")
+}
+
+func (o *memoryObject) Class() (string, error) {
+	values := o.entry.Attributes["objectclass"]
+	if len(values) == 0 {
+		return "", ErrNoAttribute
+	}
+	return values[len(values)-1], nil
+}
+
+func (o *memoryObject) AttrString(name string) (string, error) {
+	values := o.entry.Attributes[strings.ToLower(name)]
+	if len(values) == 0 {
+		return "", ErrNoAttribute
+	}
+	return values[0], nil
+}
+
+func (o *memoryObject) AttrBool(name string) (bool, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return false, err
+	}
+	return ParseBool(value)
+}
+
+func (o *memoryObject) AttrInt(name string) (int, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (o *memoryObject) AttrGUID(name string) (uuid.UUID, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(value) == 16 {
+		return GUIDFromBytes([]byte(value))
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, ErrInvalidGUID
+	}
+	return id, nil
+}
+
+func (o *memoryObject) AttrBytes(name string) ([]byte, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+func (o *memoryObject) Children() (children []Object, err error) {
+	for _, key := range o.m.children[strings.ToLower(o.entry.DN)] {
+		children = append(children, &memoryObject{m: o.m, entry: o.m.entries[key]})
+	}
+	return
+}
+
+func (o *memoryObject) Close() {}
@@ -0,0 +1,99 @@
+package directory_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+const ldifFixture = `version: 1
+
+# A folded value and a base64-encoded GUID
+dn: CN=FS1,CN=Computers,DC=example,DC=com
+objectClass: top
+objectClass: computer
+objectGUID:: iWdFIwEAAkCAAwAEBQYHCA==
+dNSHostName: fs1.
+ example.com
+uSNChanged: 20
+
+dn: CN=DFSR-LocalSettings,CN=FS1,CN=Computers,DC=example,DC=com
+changetype: add
+objectClass: msDFSR-LocalSettings
+msDFSR-Version: 1.0.0.0
+uSNChanged: 30
+`
+
+const jsonFixture = `[
+	{"dn": "CN=FS1,CN=Computers,DC=example,DC=com", "objectClass": ["top", "computer"], "objectGUID": "23456789-0001-4002-8003-000405060708", "dNSHostName": "fs1.example.com", "uSNChanged": 20},
+	{"dn": "CN=DFSR-LocalSettings,CN=FS1,CN=Computers,DC=example,DC=com", "objectClass": "msDFSR-LocalSettings", "msDFSR-Version": "1.0.0.0", "msDFSR-Enabled": true, "uSNChanged": 30}
+]`
+
+var computerID = uuid.MustParse("23456789-0001-4002-8003-000405060708")
+
+func TestLoad(t *testing.T) {
+	ldif, err := directory.LoadLDIF(strings.NewReader(ldifFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, err := directory.LoadJSON(strings.NewReader(jsonFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, dir := range map[string]*directory.Memory{"ldif": ldif, "json": json} {
+		obj, err := dir.Open("cn=fs1,cn=computers,dc=example,dc=com")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if class, _ := obj.Class(); class != "computer" {
+			t.Errorf("%s: class is %s", name, class)
+		}
+		if id, err := obj.GUID(); err != nil || id != computerID {
+			t.Errorf("%s: GUID returned %v, %v", name, id, err)
+		}
+		if host, _ := obj.AttrString("dnshostname"); host != "fs1.example.com" {
+			t.Errorf("%s: host is %q", name, host)
+		}
+		if _, err := obj.AttrString("description"); err != directory.ErrNoAttribute {
+			t.Errorf("%s: missing attribute returned %v", name, err)
+		}
+
+		children, err := obj.Children()
+		if err != nil || len(children) != 1 {
+			t.Fatalf("%s: Children returned %d objects, %v", name, len(children), err)
+		}
+		if version, _ := children[0].AttrString("msDFSR-Version"); version != "1.0.0.0" {
+			t.Errorf("%s: version is %q", name, version)
+		}
+
+		if _, err := dir.Open("CN=FS2,CN=Computers,DC=example,DC=com"); err != directory.ErrNotFound {
+			t.Errorf("%s: missing object returned %v", name, err)
+		}
+
+		_, usn, _ := dir.HighestUSN()
+		changes, _ := dir.ChangedSince("DC=example,DC=com", 20)
+		if usn != 30 || len(changes) != 1 || changes[0].Class != "msDFSR-LocalSettings" {
+			t.Errorf("%s: highest USN %d, changes %+v", name, usn, changes)
+		}
+	}
+
+	obj, _ := json.Open("CN=DFSR-LocalSettings,CN=FS1,CN=Computers,DC=example,DC=com")
+	if enabled, err := obj.AttrBool("msDFSR-Enabled"); err != nil || !enabled {
+		t.Errorf("JSON boolean returned %v, %v", enabled, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := directory.LoadLDIF(strings.NewReader("objectClass: top\n")); err == nil {
+		t.Error("LDIF record without a DN was accepted")
+	}
+	if _, err := directory.LoadLDIF(strings.NewReader("dn: CN=X\nchangetype: delete\n")); err == nil {
+		t.Error("LDIF delete record was accepted")
+	}
+	if _, err := directory.LoadJSON(strings.NewReader(`[{"objectClass": "top"}]`)); err != directory.ErrMissingDN {
+		t.Errorf("JSON entry without a DN returned %v", err)
+	}
+}
@@ -2,20 +2,24 @@ package dfsrconfig
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 	"gopkg.in/dfsr.v0/poller"
 	"gopkg.in/dfsr.v0/valuesink"
 )
 
 // domainBroadcaster broadcasts domain configuration updates to a set of
-// listeners.
+// listeners. Change listeners only receive updates that carry changes.
 type domainBroadcaster struct {
 	mutex     sync.RWMutex
 	listeners []chan<- DomainUpdate
+	changes   []chan<- DomainUpdate
 	closed    bool
 }
 
@@ -31,20 +35,32 @@ func (bc *domainBroadcaster) Listen() <-chan DomainUpdate {
 	return ch
 }
 
-func (bc *domainBroadcaster) Broadcast(domain *dfsr.Domain, timestamp time.Time, err error) {
+func (bc *domainBroadcaster) ListenChanges() <-chan DomainUpdate {
+	ch := make(chan DomainUpdate, updateChanSize)
+	bc.mutex.Lock()
+	if !bc.closed {
+		bc.changes = append(bc.changes, ch)
+	} else {
+		close(ch)
+	}
+	bc.mutex.Unlock()
+	return ch
+}
+
+func (bc *domainBroadcaster) Broadcast(update DomainUpdate) {
 	bc.mutex.Lock()
 	defer bc.mutex.Unlock()
 
-	if len(bc.listeners) == 0 {
+	for _, listener := range bc.listeners {
+		listener <- update
+	}
+
+	if len(update.Changes) == 0 {
 		return
 	}
 
-	for _, listener := range bc.listeners {
-		listener <- DomainUpdate{
-			Domain:    domain,
-			Timestamp: timestamp,
-			Err:       err,
-		}
+	for _, listener := range bc.changes {
+		listener <- update
 	}
 }
 
@@ -61,34 +77,144 @@ func (bc *domainBroadcaster) Close() {
 		close(ch)
 	}
 	bc.listeners = nil
+
+	for _, ch := range bc.changes {
+		close(ch)
+	}
+	bc.changes = nil
 }
 
 // domainSource acts as a polling source for poller.Poller. It retrieves
 // domain configuration data, updates a sink and sends data via a broadcaster.
+//
+// When the directory supports change tracking the source only re-fetches the
+// replication groups affected by changes since the last poll. A complete
+// retrieval is performed periodically, and whenever the directory server
+// answering queries changes. Cached member information is retained across
+// complete retrievals; it expires according to the member cache lifetime of
+// the client, or when changes to the member are detected.
 type domainSource struct {
-	client *adsi.Client
-	domain string
-	sink   *valuesink.Sink
-	bc     *domainBroadcaster
+	client  *Client
+	conn    *adsi.Client // Closed with the source if not nil
+	sink    *valuesink.Sink
+	bc      *domainBroadcaster
+	last    *dfsr.Domain // Last configuration successfully retrieved
+	mark    Mark         // Point in the update history that last reflects
+	marked  bool         // True if mark is valid
+	crawled time.Time    // Time of the last complete retrieval
 }
 
 func (ds *domainSource) Poll(ctx context.Context) {
-	// FIXME: Propagate the context
 	timestamp := time.Now()
-	cfg, err := Domain(ds.client, ds.domain)
-	ds.sink.Update(&cfg, timestamp, err)
-	ds.bc.Broadcast(&cfg, timestamp, err)
+
+	// Take the mark before retrieval so that changes made during retrieval
+	// are picked up by the next poll
+	mark, markErr := ds.client.MarkContext(ctx)
+
+	var (
+		cfg dfsr.Domain
+		err error
+	)
+	if ds.incremental(mark, markErr, timestamp) {
+		cfg, err = ds.client.RefreshContext(ctx, ds.last, ds.mark)
+	} else {
+		cfg, err = ds.client.DomainContext(ctx)
+		if err == nil {
+			ds.crawled = timestamp
+		} else if IsPartial(err) {
+			// Fill in the groups that could not be retrieved from the last
+			// known configuration, so that transient failures don't look like
+			// removals
+			fillGroups(&cfg, ds.last, err.(*PartialError))
+		}
+	}
+
+	if IsPartial(err) && len(cfg.Groups) == 0 {
+		// Nothing was retrieved and the last configuration could not fill
+		// the gaps, so treat the retrieval as a failure rather than as the
+		// removal of every group
+		err = errors.New(err.Error())
+	}
+
+	if IsPartial(err) {
+		ds.sink.Update(&cfg, timestamp, nil)
+	} else {
+		ds.sink.Update(&cfg, timestamp, err)
+	}
+
+	// Only advance the mark when every group was retrieved, otherwise changes
+	// to the groups that failed would be lost
+	if err == nil && markErr == nil {
+		ds.mark, ds.marked = mark, true
+	}
+
+	update := DomainUpdate{
+		Domain:    &cfg,
+		Timestamp: timestamp,
+		Err:       err,
+	}
+	if err == nil || IsPartial(err) {
+		if ds.last != nil {
+			update.Changes = dfsr.Diff(ds.last, &cfg)
+		}
+		ds.last = &cfg
+	}
+	ds.bc.Broadcast(update)
+}
+
+// incremental reports whether the next retrieval can be limited to the
+// changes made since the last one.
+func (ds *domainSource) incremental(mark Mark, markErr error, now time.Time) bool {
+	switch {
+	case markErr != nil, !ds.marked, ds.last == nil:
+		return false
+	case !strings.EqualFold(mark.Server, ds.mark.Server):
+		return false // Update sequence numbers are local to each server
+	case now.Sub(ds.crawled) >= fullRefreshInterval:
+		return false // Account for changes that are not tracked, such as those in other naming contexts
+	default:
+		return true
+	}
+}
+
+// fillGroups adds the groups of last that failed to be retrieved for domain,
+// as described by partial.
+func fillGroups(domain, last *dfsr.Domain, partial *PartialError) {
+	if last == nil {
+		return
+	}
+	for _, failure := range partial.Groups {
+		name := directory.RDNValue(failure.DN)
+		for g := range last.Groups {
+			if strings.EqualFold(last.Groups[g].Name, name) {
+				domain.Groups = append(domain.Groups, last.Groups[g])
+				break
+			}
+		}
+	}
 }
 
 func (ds *domainSource) Close() {
-	ds.client.Close()
+	if ds.conn != nil {
+		ds.conn.Close()
+	}
 }
 
 // DomainUpdate represents an update to domain configuration data.
+//
+// Changes lists the differences from the last configuration that was
+// successfully retrieved. It is empty for the first configuration retrieved
+// by a monitor and for updates that carry an error other than a
+// *PartialError.
+//
+// If Err is a *PartialError the configuration of some groups could not be
+// retrieved. Domain is still usable in that case and holds the last known
+// configuration of those groups, if any.
 type DomainUpdate struct {
 	Domain    *dfsr.Domain
 	Timestamp time.Time
 	Err       error
+	Changes   []dfsr.Change
 }
 
 // DomainMonitor polls Active Directory for updated domain-wide DFSR
@@ -98,6 +224,7 @@ type DomainMonitor struct {
 	bc   domainBroadcaster // Broadcasts configuration updates
 
 	mutex    sync.Mutex
+	dir      directory.Directory
 	domain   string
 	interval time.Duration
 	timeout  time.Duration
@@ -118,6 +245,27 @@ func NewDomainMonitor(domain string, interval, timeout time.Duration) *DomainMon
 	return m
 }
 
+// NewDomainMonitorWithDirectory returns a new DFSR configuration monitor that
+// polls the provided directory for updated DFSR configuration for a domain.
+// The domain must not be an empty string.
+//
+// If the directory implements directory.ChangeTracker the monitor will only
+// re-fetch the configuration of replication groups that have changed since it
+// last polled the directory.
+//
+// The directory is retained by the monitor but is not closed by it. It is the
+// caller's responsibility to release any resources held by the directory when
+// finished with the monitor.
+func NewDomainMonitorWithDirectory(dir directory.Directory, domain string, interval, timeout time.Duration) *DomainMonitor {
+	m := &DomainMonitor{
+		dir:      dir,
+		domain:   domain,
+		interval: interval,
+		timeout:  timeout,
+	}
+	return m
+}
+
 // Close will release resources consumed by the monitor. It should be called
 // when finished with the monitor. Calling close will prevent future calls to
 // start or update from succeeding. Close will not return until all
@@ -141,9 +289,9 @@ func (m *DomainMonitor) Close() {
 }
 
 // Start starts the configuration monitor. If the monitor is already running
-// start does nothing and returns nil. If it is unable to initialize an ADSI
-// client start will return an error. If the monitor is already closed
-// ErrClosed will be returned.
+// start does nothing and returns nil. If the monitor was not provided with a
+// directory and it is unable to initialize an ADSI client start will return an
+// error. If the monitor is already closed ErrClosed will be returned.
 func (m *DomainMonitor) Start() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -154,26 +302,37 @@ func (m *DomainMonitor) Start() error {
 		return nil // Already running
 	}
 
-	client, err := adsi.NewClient()
-	if err != nil {
-		return err
+	source := &domainSource{
+		sink: &m.sink,
+		bc:   &m.bc,
 	}
-	if m.domain == "" {
-		m.domain, err = dnc(client)
+
+	if m.dir != nil {
+		if m.domain == "" {
+			return ErrDomainLookupFailed
+		}
+		source.client = NewClientWithDirectory(m.dir, m.domain)
+	} else {
+		conn, err := adsi.NewClient()
 		if err != nil {
 			return err
 		}
 		if m.domain == "" {
-			return ErrDomainLookupFailed
+			m.domain, err = dnc(conn)
+			if err != nil {
+				conn.Close()
+				return err
+			}
+			if m.domain == "" {
+				conn.Close()
+				return ErrDomainLookupFailed
+			}
 		}
+		source.conn = conn
+		source.client = NewClient(conn, m.domain)
 	}
 
-	m.instance = poller.New(&domainSource{
-		client: client,
-		domain: m.domain,
-		sink:   &m.sink,
-		bc:     &m.bc,
-	}, m.interval, m.timeout)
+	m.instance = poller.New(source, m.interval, m.timeout)
 
 	return nil
 }
@@ -204,6 +363,15 @@ func (m *DomainMonitor) Listen() <-chan DomainUpdate {
 	return m.bc.Listen()
 }
 
+// ListenChanges returns a channel on which configuration updates will be
+// broadcast when the configuration differs from the configuration previously
+// retrieved. Updates that carry no changes are not sent. The channel will be
+// closed when the monitor is closed. If the monitor has already been closed
+// then the returned channel will be closed already.
+func (m *DomainMonitor) ListenChanges() <-chan DomainUpdate {
+	return m.bc.ListenChanges()
+}
+
 // WaitReady blocks until the monitor has retrieved configuration data. If the
 // monitor has already retrieved data the call will not block.
 func (m *DomainMonitor) WaitReady() (err error) {
@@ -0,0 +1,77 @@
+package dfsrconfig_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsrconfig"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+// failingDir is a directory that fails to open the objects whose
+// distinguished names start with one of the given prefixes.
+type failingDir struct {
+	directory.Directory
+	prefixes []string
+}
+
+func (d *failingDir) Open(dn string) (directory.Object, error) {
+	for _, prefix := range d.prefixes {
+		if strings.HasPrefix(strings.ToLower(dn), strings.ToLower(prefix)) {
+			return nil, directory.ErrNotFound
+		}
+	}
+	return d.Directory.Open(dn)
+}
+
+func TestGroupsPartial(t *testing.T) {
+	dir := &failingDir{Directory: newDirectory(t), prefixes: []string{"CN=Content,CN=Example,"}}
+	c := dfsrconfig.NewClientWithDirectory(dir, domainName)
+
+	groups, err := c.Groups()
+	if !dfsrconfig.IsPartial(err) {
+		t.Fatalf("Groups returned %v, want a partial error", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "Domain System Volume" {
+		t.Errorf("Groups returned %d groups", len(groups))
+	}
+}
+
+func TestGroupsAllFailed(t *testing.T) {
+	dir := &failingDir{Directory: newDirectory(t), prefixes: []string{"CN=Content,"}}
+	c := dfsrconfig.NewClientWithDirectory(dir, domainName)
+
+	groups, err := c.Groups()
+	if err == nil || dfsrconfig.IsPartial(err) {
+		t.Fatalf("Groups returned %v, want an error other than a partial error", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Groups returned %d groups", len(groups))
+	}
+}
+
+func TestDomainMonitorAllFailed(t *testing.T) {
+	dir := &failingDir{Directory: newDirectory(t), prefixes: []string{"CN=Content,"}}
+	m := dfsrconfig.NewDomainMonitorWithDirectory(dir, domainName, time.Hour, 10*time.Second)
+	defer m.Close()
+
+	updates := m.Listen()
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m.Update()
+
+	// An empty configuration must not be mistaken for a domain without groups
+	if err := m.WaitReady(); err == nil {
+		t.Fatal("monitor became ready without any groups")
+	}
+	select {
+	case update := <-updates:
+		if update.Err == nil || dfsrconfig.IsPartial(update.Err) {
+			t.Errorf("update carries error %v", update.Err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no update was broadcast")
+	}
+}
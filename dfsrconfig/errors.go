@@ -0,0 +1,44 @@
+package dfsrconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupError records a failure to retrieve the configuration of a replication
+// group.
+type GroupError struct {
+	DN  string // Distinguished name of the replication group
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("error retrieving configuration for replication group %s: %v", e.DN, e.Err)
+}
+
+// PartialError is returned when the configuration of some replication groups
+// could not be retrieved. The configuration of the remaining groups is
+// returned along with it.
+type PartialError struct {
+	Groups []GroupError
+}
+
+// Error returns a string representation of the error.
+func (e *PartialError) Error() string {
+	if len(e.Groups) == 1 {
+		return e.Groups[0].Error()
+	}
+	msgs := make([]string, len(e.Groups))
+	for i := range e.Groups {
+		msgs[i] = e.Groups[i].Error()
+	}
+	return fmt.Sprintf("%d replication groups could not be retrieved: %s", len(e.Groups), strings.Join(msgs, "; "))
+}
+
+// IsPartial reports whether err is a *PartialError, which indicates that the
+// accompanying configuration is usable but incomplete.
+func IsPartial(err error) bool {
+	_, ok := err.(*PartialError)
+	return ok
+}
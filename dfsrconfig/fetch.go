@@ -1,6 +1,8 @@
 package dfsrconfig
 
 import (
+	"context"
+
 	"gopkg.in/adsi.v0"
 	"gopkg.in/dfsr.v0/dfsr"
 )
@@ -8,13 +10,26 @@ import (
 // Domain will fetch DFSR configuration data from the specified domain using the
 // provided ADSI client.
 func Domain(client *adsi.Client, domain string) (data dfsr.Domain, err error) {
+	return DomainContext(context.Background(), client, domain)
+}
+
+// DomainContext will fetch DFSR configuration data from the specified domain
+// using the provided ADSI client. The query is abandoned if ctx is cancelled.
+func DomainContext(ctx context.Context, client *adsi.Client, domain string) (data dfsr.Domain, err error) {
 	c := NewClient(client, domain)
-	return c.Domain()
+	return c.DomainContext(ctx)
 }
 
 // Group will fetch DFSR configuration data for the replication group in the
 // specified domain that matches the given name using the provided ADSI client.
 func Group(client *adsi.Client, domain, groupName string) (data dfsr.Group, err error) {
+	return GroupContext(context.Background(), client, domain, groupName)
+}
+
+// GroupContext will fetch DFSR configuration data for the replication group in
+// the specified domain that matches the given name using the provided ADSI
+// client. The query is abandoned if ctx is cancelled.
+func GroupContext(ctx context.Context, client *adsi.Client, domain, groupName string) (data dfsr.Group, err error) {
 	c := NewClient(client, domain)
-	return c.GroupByName(groupName)
+	return c.GroupByNameContext(ctx, groupName)
 }
@@ -0,0 +1,172 @@
+package ldapconfig
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+)
+
+// BindMethod identifies the authentication method used to bind an LDAP
+// connection.
+type BindMethod string
+
+// LDAP bind methods.
+const (
+	Anonymous BindMethod = "anonymous" // No authentication
+	Simple    BindMethod = "simple"    // Simple bind with a distinguished name or user principal name and password
+	NTLM      BindMethod = "ntlm"      // NTLM bind with a domain, username and password
+	GSSAPI    BindMethod = "gssapi"    // Kerberos bind via SASL GSSAPI
+)
+
+// Config describes how to connect and bind to a directory server.
+type Config struct {
+	// URL is the address of the directory server, such as
+	// ldap://dc1.example.com or ldaps://dc1.example.com:636.
+	URL string
+
+	// StartTLS upgrades ldap:// connections with the StartTLS extended
+	// operation before binding.
+	StartTLS bool
+
+	// TLS is the TLS configuration for ldaps:// and StartTLS connections. If
+	// nil a default configuration that verifies the server name is used.
+	TLS *tls.Config
+
+	// Timeout limits the duration of dialing and of each request. If zero a
+	// default of two minutes is used.
+	Timeout time.Duration
+
+	// Bind is the authentication method. If empty, Simple is used when a
+	// username is present and Anonymous otherwise.
+	Bind BindMethod
+
+	Username string
+	Password string
+
+	// Domain is the NTLM domain or Kerberos realm of the user. Kerberos realms
+	// are converted to upper case.
+	Domain string
+
+	// Keytab is the path of a Kerberos keytab to use for GSSAPI binds in
+	// place of a password.
+	Keytab string
+
+	// Krb5Config is the path of the Kerberos configuration file used for
+	// GSSAPI binds. If empty /etc/krb5.conf is used.
+	Krb5Config string
+
+	// SPN is the service principal of the directory server for GSSAPI binds.
+	// If empty ldap/<host> is used.
+	SPN string
+
+	// GSSAPIClient, if not nil, is used for GSSAPI binds in place of a client
+	// created from the Kerberos settings above.
+	GSSAPIClient ldap.GSSAPIClient
+}
+
+// Dial connects to the directory server described by config and binds the
+// connection. It is the caller's responsibility to close the returned
+// connection when finished with it.
+func Dial(config Config) (conn *ldap.Conn, err error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	tlsConfig := config.TLS
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+
+	conn, err = ldap.DialURL(config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+
+	defer func() {
+		if err != nil {
+			conn.Close()
+			conn = nil
+		}
+	}()
+
+	if config.StartTLS && strings.EqualFold(u.Scheme, "ldap") {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			return
+		}
+	}
+
+	err = bind(conn, u, config)
+	return
+}
+
+func bind(conn *ldap.Conn, u *url.URL, config Config) error {
+	method := config.Bind
+	if method == "" {
+		if config.Username != "" {
+			method = Simple
+		} else {
+			method = Anonymous
+		}
+	}
+
+	switch method {
+	case Anonymous:
+		return conn.UnauthenticatedBind("")
+	case Simple:
+		return conn.Bind(config.Username, config.Password)
+	case NTLM:
+		return conn.NTLMBind(config.Domain, config.Username, config.Password)
+	case GSSAPI:
+		client := config.GSSAPIClient
+		if client == nil {
+			kc, err := kerberosClient(config)
+			if err != nil {
+				return err
+			}
+			defer kc.Close()
+			client = kc
+		}
+		spn := config.SPN
+		if spn == "" {
+			spn = "ldap/" + u.Hostname()
+		}
+		return conn.GSSAPIBind(client, spn, "")
+	default:
+		return ErrUnsupportedBind
+	}
+}
+
+func kerberosClient(config Config) (*gssapi.Client, error) {
+	krb5conf := config.Krb5Config
+	if krb5conf == "" {
+		krb5conf = defaultKrb5Config
+	}
+	realm := strings.ToUpper(config.Domain)
+
+	switch {
+	case config.Keytab != "":
+		return gssapi.NewClientWithKeytab(config.Username, realm, config.Keytab, krb5conf)
+	case config.Password != "":
+		return gssapi.NewClientWithPassword(config.Username, realm, config.Password, krb5conf)
+	}
+
+	if ccache := strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:"); ccache != "" {
+		return gssapi.NewClientFromCCache(ccache, krb5conf)
+	}
+	return nil, ErrNoCredentials
+}
@@ -0,0 +1,30 @@
+package ldapconfig
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultPageSize   = 500              // Number of entries requested in each page of search results
+	defaultTimeout    = 2 * time.Minute  // Default timeout for LDAP requests
+	defaultKrb5Config = "/etc/krb5.conf" // Default path of the Kerberos configuration file
+)
+
+var (
+	// ErrUnsupportedBind is returned when a configuration specifies an unknown
+	// bind method.
+	ErrUnsupportedBind = errors.New("unsupported LDAP bind method")
+
+	// ErrNoCredentials is returned when a GSSAPI bind is requested without a
+	// password, keytab or credential cache.
+	ErrNoCredentials = errors.New("no kerberos credentials were provided")
+
+	// ErrDomainLookupFailed is returned when the default naming context of
+	// the directory cannot be determined.
+	ErrDomainLookupFailed = errors.New("unable to determine DFSR configuration domain")
+
+	// ErrClosed is returned from calls to a redialer in the event that the
+	// Close() function has already been called.
+	ErrClosed = errors.New("the connection is closing or already closed")
+)
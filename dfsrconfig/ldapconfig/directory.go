@@ -0,0 +1,321 @@
+package ldapconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+var _ = (directory.Directory)((*Directory)(nil))     // Compile-time interface compliance check
+var _ = (directory.Object)((*object)(nil))           // Compile-time interface compliance check
+var _ = (directory.ChangeTracker)((*Directory)(nil)) // Compile-time interface compliance check
+
+// Conn is an LDAP connection capable of performing searches. It is
+// implemented by *ldap.Conn.
+type Conn interface {
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+}
+
+// pagingConn is implemented by connections that support the simple paged
+// results control.
+type pagingConn interface {
+	SearchWithPaging(request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
+}
+
+// attributes is the set of attributes retrieved for each object. It includes
+// every attribute read by the dfsrconfig package.
+var attributes = []string{
+	"cn",
+	"objectGUID",
+	"objectClass",
+	"description",
+	"dNSHostName",
+	"serverReference",
+	"fromServer",
+	"msDFSR-ComputerReference",
+	"msDFSR-Enabled",
+	"msDFSR-Version",
+	"msDFSR-MemberReference",
+	"msDFSR-ReplicationGroupGuid",
+	"msDFSR-ContentSetGuid",
+	"msDFSR-RootPath",
+	"msDFSR-RootSizeInMb",
+	"msDFSR-StagingPath",
+	"msDFSR-StagingSizeInMb",
+	"msDFSR-ConflictPath",
+	"msDFSR-ConflictSizeInMb",
+	"msDFSR-ReadOnly",
+	"msDFSR-Options",
+	"msDFSR-CachePolicy",
+	"msDFSR-MaxAgeInCacheInMin",
+	"msDFSR-MinDurationCacheInMin",
+	"msDFSR-ReplicationGroupType",
+	"msDFSR-Schedule",
+	"msDFSR-FileFilter",
+	"msDFSR-DirectoryFilter",
+	"msDFSR-DfsPath",
+	"msDFSR-TombstoneExpiryInMin",
+	"msDFSR-Flags",
+	"msDFSR-RdcEnabled",
+	"msDFSR-RdcMinFileSizeInKb",
+	"msDFSR-Keywords",
+	"msDFSR-Priority",
+}
+
+// Directory is a directory that accesses Active Directory over LDAP.
+type Directory struct {
+	conn Conn
+}
+
+// NewDirectory returns a directory that performs queries with the given LDAP
+// connection.
+//
+// The provided connection is retained by the directory. It is the caller's
+// responsibility to close the connection at an appropriate time when finished
+// with the directory.
+func NewDirectory(conn Conn) *Directory {
+	return &Directory{conn: conn}
+}
+
+// Open returns the object with the given distinguished name.
+func (d *Directory) Open(dn string) (directory.Object, error) {
+	result, err := d.conn.Search(ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", attributes, nil))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, directory.ErrNotFound
+		}
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, directory.ErrNotFound
+	}
+	return &object{d: d, entry: result.Entries[0]}, nil
+}
+
+// children returns the immediate children of the object with the given
+// distinguished name.
+func (d *Directory) children(dn string) ([]*ldap.Entry, error) {
+	return d.search(ldap.NewSearchRequest(dn, ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", attributes, nil))
+}
+
+// search performs a search that may return many entries, using paged results
+// when the connection supports them.
+func (d *Directory) search(request *ldap.SearchRequest) ([]*ldap.Entry, error) {
+	var (
+		result *ldap.SearchResult
+		err    error
+	)
+	if pc, ok := d.conn.(pagingConn); ok {
+		result, err = pc.SearchWithPaging(request, defaultPageSize)
+	} else {
+		result, err = d.conn.Search(request)
+	}
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, directory.ErrNotFound
+		}
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// HighestUSN returns the DNS name of the directory server answering queries
+// and its highest committed update sequence number, as advertised by its root
+// DSE.
+func (d *Directory) HighestUSN() (server string, usn int64, err error) {
+	result, err := d.conn.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"dnsHostName", "highestCommittedUSN"}, nil))
+	if err != nil {
+		return
+	}
+	if len(result.Entries) == 0 {
+		return "", 0, directory.ErrNotFound
+	}
+	entry := result.Entries[0]
+	server = entry.GetAttributeValue("dnsHostName")
+	value := entry.GetAttributeValue("highestCommittedUSN")
+	if value == "" {
+		return "", 0, directory.ErrNoAttribute
+	}
+	usn, err = strconv.ParseInt(value, 10, 64)
+	return
+}
+
+// trackedClasses are the object classes whose changes can affect the DFSR
+// configuration of a domain. They cover the replication group configuration
+// beneath CN=DFSR-GlobalSettings,CN=System and the member computers along with
+// their local settings and subscriptions.
+var trackedClasses = []string{
+	"computer",
+	"msDFSR-GlobalSettings",
+	"msDFSR-ReplicationGroup",
+	"msDFSR-Topology",
+	"msDFSR-Member",
+	"msDFSR-Connection",
+	"msDFSR-Content",
+	"msDFSR-ContentSet",
+	"msDFSR-LocalSettings",
+	"msDFSR-Subscriber",
+	"msDFSR-Subscription",
+}
+
+// ChangedSince returns the objects within the subtree rooted at base with a
+// uSNChanged value greater than usn. Only objects of the classes that make up
+// the DFSR configuration and member computers are returned.
+//
+// Deleted objects are retrieved with the show deleted control and are
+// returned with Deleted set. Their distinguished names are reconstructed from
+// the lastKnownParent attribute, so that they identify the location of the
+// objects before they were deleted.
+func (d *Directory) ChangedSince(base string, usn int64) (changes []directory.Change, err error) {
+	var classes strings.Builder
+	for _, class := range trackedClasses {
+		classes.WriteString("(objectClass=" + class + ")")
+	}
+	filter := fmt.Sprintf("(&(uSNChanged>=%d)(|%s))", usn+1, classes.String())
+
+	entries, err := d.search(ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, []string{"objectGUID", "objectClass", "isDeleted", "lastKnownParent"},
+		[]ldap.Control{ldap.NewControlMicrosoftShowDeleted()}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		obj := &object{d: d, entry: entry}
+		id, _ := obj.GUID()
+		class, _ := obj.Class()
+		change := directory.Change{DN: entry.DN, GUID: id, Class: class}
+		if deleted, _ := obj.AttrBool("isDeleted"); deleted {
+			change.Deleted = true
+			if parent := entry.GetEqualFoldAttributeValue("lastKnownParent"); parent != "" {
+				change.DN = lastKnownDN(entry.DN, parent)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	return
+}
+
+// lastKnownDN returns the distinguished name that the deleted object dn had
+// beneath its last known parent. Active Directory appends a newline followed
+// by DEL: and the object's GUID to the relative names of deleted objects.
+func lastKnownDN(dn, parent string) string {
+	rdn := dn
+	if p := directory.ParentDN(dn); p != "" {
+		rdn = dn[:len(dn)-len(p)-1]
+	}
+	for _, mangle := range []string{`\0ADEL:`, "\nDEL:"} {
+		if i := strings.Index(strings.ToUpper(rdn), mangle); i >= 0 {
+			rdn = rdn[:i]
+			break
+		}
+	}
+	return rdn + "," + parent
+}
+
+// DefaultNamingContext returns the distinguished name of the default naming
+// context of the directory server, as advertised by its root DSE.
+func DefaultNamingContext(conn Conn) (dn string, err error) {
+	result, err := conn.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 0, 0, false,
+		"(objectClass=*)", []string{"defaultNamingContext"}, nil))
+	if err != nil {
+		return
+	}
+	if len(result.Entries) > 0 {
+		dn = result.Entries[0].GetAttributeValue("defaultNamingContext")
+	}
+	if dn == "" {
+		err = ErrDomainLookupFailed
+	}
+	return
+}
+
+type object struct {
+	d     *Directory
+	entry *ldap.Entry
+}
+
+func (o *object) DN() string {
+	return o.entry.DN
+}
+
+func (o *object) Name() (string, error) {
+	if cn := o.entry.GetEqualFoldAttributeValue("cn"); cn != "" {
+		return cn, nil
+	}
+	return directory.RDNValue(o.entry.DN), nil
+}
+
+func (o *object) GUID() (uuid.UUID, error) {
+	return directory.GUIDFromBytes(o.entry.GetEqualFoldRawAttributeValue("objectGUID"))
+}
+
+func (o *object) Class() (string, error) {
+	// Active Directory lists object classes from least to most specific
+	values := o.entry.GetEqualFoldAttributeValues("objectClass")
+	if len(values) == 0 {
+		return "", directory.ErrNoAttribute
+	}
+	return values[len(values)-1], nil
+}
+
+func (o *object) AttrString(name string) (string, error) {
+	values := o.entry.GetEqualFoldAttributeValues(name)
+	if len(values) == 0 {
+		return "", directory.ErrNoAttribute
+	}
+	return values[0], nil
+}
+
+func (o *object) AttrBool(name string) (bool, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return false, err
+	}
+	return directory.ParseBool(value)
+}
+
+func (o *object) AttrInt(name string) (int, error) {
+	value, err := o.AttrString(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (o *object) AttrGUID(name string) (uuid.UUID, error) {
+	raw := o.entry.GetEqualFoldRawAttributeValue(name)
+	if len(raw) == 0 {
+		return uuid.Nil, directory.ErrNoAttribute
+	}
+	return directory.GUIDFromBytes(raw)
+}
+
+func (o *object) AttrBytes(name string) ([]byte, error) {
+	raw := o.entry.GetEqualFoldRawAttributeValue(name)
+	if len(raw) == 0 {
+		return nil, directory.ErrNoAttribute
+	}
+	return raw, nil
+}
+
+func (o *object) Children() (children []directory.Object, err error) {
+	entries, err := o.d.children(o.entry.DN)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		children = append(children, &object{d: o.d, entry: entry})
+	}
+	return
+}
+
+func (o *object) Close() {}
@@ -0,0 +1,343 @@
+package ldapconfig_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+	"gopkg.in/dfsr.v0/dfsrconfig/ldapconfig"
+)
+
+const (
+	testUser     = "CN=Monitor,CN=Users,DC=example,DC=com"
+	testPassword = "secret"
+	testDomain   = "DC=example,DC=com"
+	settingsDN   = "CN=DFSR-GlobalSettings,CN=System,DC=example,DC=com"
+	manyDN       = "CN=Many,DC=example,DC=com"
+	deletedDN    = `CN=Retired\0ADEL:89abcdef-0123-4567-89ab-cdef01234567,CN=Deleted Objects,DC=example,DC=com`
+	manyChildren = 1200 // Spans several pages of search results
+)
+
+// groupGUID is the objectGUID of the Example replication group in the byte
+// order used by Active Directory.
+var groupGUID = string([]byte{
+	0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd,
+	0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef,
+})
+
+var groupID = uuid.MustParse("01234567-89ab-cdef-0123-456789abcdef")
+
+func testEntries() map[string]map[string][]string {
+	entries := map[string]map[string][]string{
+		"": {
+			"dnsHostName":          {"dc1.example.com"},
+			"highestCommittedUSN":  {"120"},
+			"defaultNamingContext": {testDomain},
+		},
+		testDomain: {
+			"objectClass": {"top", "domain", "domainDNS"},
+			"objectGUID":  {string(make([]byte, 16))},
+			"description": {"Example domain"},
+			"uSNChanged":  {"5"},
+		},
+		"CN=System,DC=example,DC=com": {
+			"cn":          {"System"},
+			"objectClass": {"top", "container"},
+			"uSNChanged":  {"6"},
+		},
+		settingsDN: {
+			"cn":          {"DFSR-GlobalSettings"},
+			"objectClass": {"top", "msDFSR-GlobalSettings"},
+			"uSNChanged":  {"7"},
+		},
+		"CN=Example," + settingsDN: {
+			"cn":                          {"Example"},
+			"objectClass":                 {"top", "msDFSR-ReplicationGroup"},
+			"objectGUID":                  {groupGUID},
+			"msDFSR-ReplicationGroupType": {"0"},
+			"msDFSR-TombstoneExpiryInMin": {"86400"},
+			"msDFSR-Schedule":             {"\x01\x02\x03"},
+			"uSNChanged":                  {"100"},
+		},
+		"CN=Content,CN=Example," + settingsDN: {
+			"cn":          {"Content"},
+			"objectClass": {"top", "msDFSR-Content"},
+			"uSNChanged":  {"101"},
+		},
+		"CN=Data,CN=Content,CN=Example," + settingsDN: {
+			"cn":             {"Data"},
+			"objectClass":    {"top", "msDFSR-ContentSet"},
+			"msDFSR-Enabled": {"TRUE"},
+			"uSNChanged":     {"110"},
+		},
+		"CN=FS1,CN=Computers,DC=example,DC=com": {
+			"cn":          {"FS1"},
+			"objectClass": {"top", "person", "organizationalPerson", "user", "computer"},
+			"dNSHostName": {"fs1.example.com"},
+			"uSNChanged":  {"112"},
+		},
+		"CN=Ignored,DC=example,DC=com": {
+			"cn":          {"Ignored"},
+			"objectClass": {"top", "container"},
+			"uSNChanged":  {"113"},
+		},
+		deletedDN: {
+			"cn":              {"Retired\nDEL:89abcdef-0123-4567-89ab-cdef01234567"},
+			"objectClass":     {"top", "msDFSR-ReplicationGroup"},
+			"isDeleted":       {"TRUE"},
+			"lastKnownParent": {settingsDN},
+			"uSNChanged":      {"115"},
+		},
+		manyDN: {
+			"cn":          {"Many"},
+			"objectClass": {"top", "container"},
+			"uSNChanged":  {"8"},
+		},
+	}
+	for i := 0; i < manyChildren; i++ {
+		entries[fmt.Sprintf("CN=Child%04d,%s", i, manyDN)] = map[string][]string{
+			"cn":          {fmt.Sprintf("Child%04d", i)},
+			"objectClass": {"top", "container"},
+			"uSNChanged":  {"9"},
+		}
+	}
+	return entries
+}
+
+func dial(t *testing.T, s *testServer) *ldap.Conn {
+	t.Helper()
+	conn, err := ldapconfig.Dial(ldapconfig.Config{URL: s.URL(), Username: testUser, Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestDialBind(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+
+	dial(t, s)
+
+	conn, err := ldapconfig.Dial(ldapconfig.Config{URL: s.URL()})
+	if err != nil {
+		t.Fatalf("anonymous bind failed: %v", err)
+	}
+	conn.Close()
+
+	_, err = ldapconfig.Dial(ldapconfig.Config{URL: s.URL(), Username: testUser, Password: "wrong"})
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Fatalf("bind with a bad password returned %v", err)
+	}
+
+	_, err = ldapconfig.Dial(ldapconfig.Config{URL: s.URL(), Bind: "bogus"})
+	if err != ldapconfig.ErrUnsupportedBind {
+		t.Fatalf("bind with an unknown method returned %v", err)
+	}
+}
+
+func TestDirectoryOpen(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+	dir := ldapconfig.NewDirectory(dial(t, s))
+
+	obj, err := dir.Open("cn=example," + settingsDN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+
+	if name, err := obj.Name(); err != nil || name != "Example" {
+		t.Errorf("Name returned %q, %v", name, err)
+	}
+	if class, err := obj.Class(); err != nil || class != "msDFSR-ReplicationGroup" {
+		t.Errorf("Class returned %q, %v", class, err)
+	}
+	if id, err := obj.GUID(); err != nil || id != groupID {
+		t.Errorf("GUID returned %v, %v", id, err)
+	}
+	if value, err := obj.AttrString("msDFSR-ReplicationGroupType"); err != nil || value != "0" {
+		t.Errorf("AttrString returned %q, %v", value, err)
+	}
+	if value, err := obj.AttrInt("msDFSR-TombstoneExpiryInMin"); err != nil || value != 86400 {
+		t.Errorf("AttrInt returned %d, %v", value, err)
+	}
+	if value, err := obj.AttrBytes("msDFSR-Schedule"); err != nil || string(value) != "\x01\x02\x03" {
+		t.Errorf("AttrBytes returned %v, %v", value, err)
+	}
+	if _, err := obj.AttrString("description"); err != directory.ErrNoAttribute {
+		t.Errorf("AttrString of a missing attribute returned %v", err)
+	}
+	if _, err := obj.AttrGUID("msDFSR-ContentSetGuid"); err != directory.ErrNoAttribute {
+		t.Errorf("AttrGUID of a missing attribute returned %v", err)
+	}
+
+	content, err := dir.Open("CN=Data,CN=Content,CN=Example," + settingsDN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	if enabled, err := content.AttrBool("msDFSR-Enabled"); err != nil || !enabled {
+		t.Errorf("AttrBool returned %v, %v", enabled, err)
+	}
+
+	if _, err := dir.Open("CN=Missing," + settingsDN); err != directory.ErrNotFound {
+		t.Errorf("Open of a missing object returned %v", err)
+	}
+}
+
+func TestDirectoryChildren(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+	dir := ldapconfig.NewDirectory(dial(t, s))
+
+	obj, err := dir.Open(manyDN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+
+	_, before := s.Stats()
+	children, err := obj.Children()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer directory.CloseAll(children)
+	_, after := s.Stats()
+
+	if len(children) != manyChildren {
+		t.Fatalf("Children returned %d objects, want %d", len(children), manyChildren)
+	}
+	if pages := after - before; pages < 2 {
+		t.Errorf("children were retrieved in %d pages", pages)
+	}
+	names := make([]string, len(children))
+	for i, child := range children {
+		names[i], _ = child.Name()
+	}
+	if !sort.StringsAreSorted(names) || names[0] != "Child0000" || names[len(names)-1] != fmt.Sprintf("Child%04d", manyChildren-1) {
+		t.Errorf("unexpected children %s ... %s", names[0], names[len(names)-1])
+	}
+}
+
+func TestDirectoryChangeTracking(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+	dir := ldapconfig.NewDirectory(dial(t, s))
+
+	server, usn, err := dir.HighestUSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server != "dc1.example.com" || usn != 120 {
+		t.Errorf("HighestUSN returned %s, %d", server, usn)
+	}
+
+	changes, err := dir.ChangedSince(settingsDN, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("ChangedSince returned %d changes, want 2: %+v", len(changes), changes)
+	}
+	sort.Slice(changes, func(i, j int) bool { return len(changes[i].DN) < len(changes[j].DN) })
+	if changes[0].Class != "msDFSR-Content" || changes[1].Class != "msDFSR-ContentSet" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	changes, err = dir.ChangedSince(settingsDN, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		if change.DN == "CN=Example,"+settingsDN && change.GUID != groupID {
+			t.Errorf("change to %s has GUID %v", change.DN, change.GUID)
+		}
+	}
+	if len(changes) != 4 {
+		t.Errorf("ChangedSince returned %d changes, want 4", len(changes))
+	}
+
+	// Changes across the domain are limited to DFSR configuration and
+	// computers, and include deleted objects
+	changes, err = dir.ChangedSince(testDomain, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]directory.Change)
+	for _, change := range changes {
+		found[change.DN] = change
+	}
+	if len(changes) != 4 {
+		t.Errorf("ChangedSince returned %d changes, want 4: %+v", len(changes), changes)
+	}
+	if change, ok := found["CN=FS1,CN=Computers,DC=example,DC=com"]; !ok || change.Class != "computer" || change.Deleted {
+		t.Errorf("computer change is %+v", change)
+	}
+	if change, ok := found["CN=Retired,"+settingsDN]; !ok || !change.Deleted || change.Class != "msDFSR-ReplicationGroup" {
+		t.Errorf("deleted group was not reported at its last known location: %+v", changes)
+	}
+	if _, ok := found["CN=Ignored,DC=example,DC=com"]; ok {
+		t.Error("change to an unrelated object was reported")
+	}
+}
+
+func TestDefaultNamingContext(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+	conn := dial(t, s)
+
+	dn, err := ldapconfig.DefaultNamingContext(conn)
+	if err != nil || dn != testDomain {
+		t.Fatalf("DefaultNamingContext returned %q, %v", dn, err)
+	}
+
+	client, err := ldapconfig.NewClient(conn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc, err := client.NamingContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc.DN != testDomain || nc.Description != "Example domain" {
+		t.Errorf("unexpected naming context %+v", nc)
+	}
+}
+
+func TestRedialer(t *testing.T) {
+	s := newTestServer(t, testUser, testPassword, testEntries())
+
+	dials := 0
+	r := ldapconfig.NewRedialer(func() (*ldap.Conn, error) {
+		dials++
+		return ldapconfig.Dial(ldapconfig.Config{URL: s.URL(), Username: testUser, Password: testPassword})
+	})
+	dir := ldapconfig.NewDirectory(r)
+
+	if _, _, err := dir.HighestUSN(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dir.HighestUSN(); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 1 {
+		t.Fatalf("redialer dialed %d times, want 1", dials)
+	}
+
+	// The request that discovers the lost connection may fail, but the next
+	// one must succeed on a new connection
+	s.Disconnect()
+	if _, _, err := dir.HighestUSN(); err != nil {
+		if _, _, err = dir.HighestUSN(); err != nil {
+			t.Fatalf("redialer did not recover from a lost connection: %v", err)
+		}
+	}
+	if dials != 2 {
+		t.Errorf("redialer dialed %d times, want 2", dials)
+	}
+
+	r.Close()
+	if _, _, err := dir.HighestUSN(); err != ldapconfig.ErrClosed {
+		t.Errorf("request after close returned %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+// Package ldapconfig retrieves DFSR configuration from Active Directory over
+// plain LDAP or LDAPS.
+//
+// It provides an implementation of the directory interface used by the
+// dfsrconfig package that does not rely on ADSI, which makes it suitable for
+// use on hosts that are not running Windows. Connections can be bound with
+// simple, NTLM or GSSAPI (Kerberos) authentication.
+//
+// The Directory type operates on any implementation of the Conn interface,
+// which is satisfied by *ldap.Conn. This allows it to be used with connections
+// that have been established by other means, including connections to local
+// test servers. The Redialer type provides a Conn that re-establishes its
+// connection when it is lost, for use by long-running monitors.
+package ldapconfig
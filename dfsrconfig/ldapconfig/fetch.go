@@ -0,0 +1,45 @@
+package ldapconfig
+
+import (
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig"
+)
+
+// NewClient returns a new DFSR configuration client for the given domain that
+// performs queries with the provided LDAP connection. If domain is empty the
+// default naming context of the directory server is used.
+//
+// It is the caller's responsibility to close the connection at an
+// appropriate time when finished with the client.
+func NewClient(conn Conn, domain string) (*dfsrconfig.Client, error) {
+	if domain == "" {
+		var err error
+		if domain, err = DefaultNamingContext(conn); err != nil {
+			return nil, err
+		}
+	}
+	return dfsrconfig.NewClientWithDirectory(NewDirectory(conn), domain), nil
+}
+
+// Domain will fetch DFSR configuration data from the specified domain using the
+// provided LDAP connection. If domain is empty the default naming context of
+// the directory server is used.
+func Domain(conn Conn, domain string) (data dfsr.Domain, err error) {
+	c, err := NewClient(conn, domain)
+	if err != nil {
+		return
+	}
+	return c.Domain()
+}
+
+// Group will fetch DFSR configuration data for the replication group in the
+// specified domain that matches the given name using the provided LDAP
+// connection. If domain is empty the default naming context of the directory
+// server is used.
+func Group(conn Conn, domain, groupName string) (data dfsr.Group, err error) {
+	c, err := NewClient(conn, domain)
+	if err != nil {
+		return
+	}
+	return c.GroupByName(groupName)
+}
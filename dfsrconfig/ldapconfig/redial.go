@@ -0,0 +1,116 @@
+package ldapconfig
+
+import (
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var _ = (Conn)((*Redialer)(nil))       // Compile-time interface compliance check
+var _ = (pagingConn)((*Redialer)(nil)) // Compile-time interface compliance check
+
+// Redialer is a connection that dials a directory server when it is first
+// used, and dials again when a request is made after the connection to the
+// server has been lost. It is suitable for long-running monitors that must
+// survive directory server restarts.
+//
+// Requests that fail because the connection was lost are not retried. The
+// connection is re-established by the next request.
+//
+// The zero value of a Redialer is not suitable for use. Redialers should be
+// created with a call to NewRedialer.
+type Redialer struct {
+	dial func() (*ldap.Conn, error)
+
+	mutex  sync.Mutex
+	conn   *ldap.Conn
+	closed bool
+}
+
+// NewRedialer returns a connection that calls dial whenever it needs to
+// establish a connection to a directory server. The dial function is
+// responsible for binding the connection. It may return a connection to a
+// different server each time it is called.
+//
+// It is the caller's responsibility to close the redialer when finished with
+// it.
+func NewRedialer(dial func() (*ldap.Conn, error)) *Redialer {
+	return &Redialer{dial: dial}
+}
+
+// Search performs a search request on the current connection.
+func (r *Redialer) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	conn, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+	result, err := conn.Search(request)
+	r.check(conn, err)
+	return result, err
+}
+
+// SearchWithPaging performs a search request on the current connection with
+// the simple paged results control.
+func (r *Redialer) SearchWithPaging(request *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	conn, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+	result, err := conn.SearchWithPaging(request, pagingSize)
+	r.check(conn, err)
+	return result, err
+}
+
+// Close closes the current connection, if any. Requests made after the
+// redialer has been closed return ErrClosed.
+func (r *Redialer) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// get returns the current connection, dialing a new one if necessary.
+func (r *Redialer) get() (*ldap.Conn, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+	if r.conn != nil && !r.conn.IsClosing() {
+		return r.conn, nil
+	}
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+
+	conn, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	r.conn = conn
+	return conn, nil
+}
+
+// check discards conn if err indicates that the connection has been lost, so
+// that the next request dials again.
+func (r *Redialer) check(conn *ldap.Conn, err error) {
+	if err == nil || !ldap.IsErrorWithCode(err, ldap.ErrorNetwork) && !conn.IsClosing() {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conn == conn {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
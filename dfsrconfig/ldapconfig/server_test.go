@@ -0,0 +1,359 @@
+package ldapconfig_test
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+// testServer is a minimal in-process LDAP server for tests. It supports
+// simple binds, searches with the base, single-level and subtree scopes,
+// the filters used by the ldapconfig package, the simple paged results
+// control and the show deleted control. Searches are answered from a fixed set
+// of entries, and the root DSE is the entry with an empty distinguished name.
+// Every entry is treated as having an objectClass attribute. Entries with an
+// isDeleted value of TRUE are only returned when deleted objects are
+// requested.
+type testServer struct {
+	ln       net.Listener
+	user     string
+	password string
+	entries  map[string]map[string][]string // Maps lower-case DNs to attributes
+
+	mutex    sync.Mutex
+	conns    map[net.Conn]bool
+	searches int
+	pages    int
+}
+
+// newTestServer starts a server that holds the given entries and accepts
+// simple binds with the given credentials, as well as anonymous binds. The
+// server is closed when the test finishes.
+func newTestServer(t *testing.T, user, password string, entries map[string]map[string][]string) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		ln:       ln,
+		user:     user,
+		password: password,
+		entries:  make(map[string]map[string][]string),
+		conns:    make(map[net.Conn]bool),
+	}
+	for dn, attrs := range entries {
+		attrs["distinguishedName"] = []string{dn}
+		s.entries[strings.ToLower(dn)] = attrs
+	}
+
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+// URL returns the ldap:// URL of the server.
+func (s *testServer) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+// Stats returns the number of searches and of paged searches performed by the
+// server.
+func (s *testServer) Stats() (searches, pages int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.searches, s.pages
+}
+
+// Disconnect closes all client connections to the server.
+func (s *testServer) Disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	s.mutex.Lock()
+	s.conns[conn] = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		packet, err := ber.ReadPacket(r)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = append(responses, s.bind(id, op))
+		case ldap.ApplicationSearchRequest:
+			var controls *ber.Packet
+			if len(packet.Children) > 2 {
+				controls = packet.Children[2]
+			}
+			responses = s.search(id, op, controls)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			responses = append(responses, message(id, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, "unsupported operation"), nil))
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(response.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *testServer) bind(id int64, op *ber.Packet) *ber.Packet {
+	name := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+
+	code := uint16(ldap.LDAPResultSuccess)
+	switch {
+	case name == "" && password == "":
+	case !strings.EqualFold(name, s.user) || password != s.password:
+		code = ldap.LDAPResultInvalidCredentials
+	}
+	return message(id, result(ldap.ApplicationBindResponse, code, ""), nil)
+}
+
+func (s *testServer) search(id int64, op *ber.Packet, controls *ber.Packet) (responses []*ber.Packet) {
+	base := strings.ToLower(op.Children[0].Value.(string))
+	scope := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	var attrs []string
+	for _, attr := range op.Children[7].Children {
+		attrs = append(attrs, attr.Value.(string))
+	}
+
+	var (
+		paging      *ldap.ControlPaging
+		showDeleted bool
+	)
+	if controls != nil {
+		for _, child := range controls.Children {
+			control, err := ldap.DecodeControl(child)
+			if err != nil {
+				continue
+			}
+			switch c := control.(type) {
+			case *ldap.ControlPaging:
+				paging = c
+			case *ldap.ControlMicrosoftShowDeleted:
+				showDeleted = true
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	s.searches++
+	if paging != nil {
+		s.pages++
+	}
+	s.mutex.Unlock()
+
+	if _, ok := s.entries[base]; !ok {
+		return []*ber.Packet{message(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, ""), nil)}
+	}
+
+	var matches []string
+	for dn, entry := range s.entries {
+		if !showDeleted && isDeleted(entry) {
+			continue
+		}
+		if inScope(dn, base, scope) && match(filter, entry) {
+			matches = append(matches, dn)
+		}
+	}
+	sort.Strings(matches)
+
+	// Serve a single page of results when paging is requested, with a cookie
+	// holding the offset of the next page
+	var done *ber.Packet
+	if paging != nil {
+		offset, _ := strconv.Atoi(string(paging.Cookie))
+		if offset > len(matches) {
+			offset = len(matches)
+		}
+		matches = matches[offset:]
+		next := ""
+		if paging.PagingSize > 0 && int(paging.PagingSize) < len(matches) {
+			matches = matches[:paging.PagingSize]
+			next = strconv.Itoa(offset + int(paging.PagingSize))
+		}
+		response := ldap.NewControlPaging(paging.PagingSize)
+		response.SetCookie([]byte(next))
+		done = ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+		done.AppendChild(response.Encode())
+	}
+
+	for _, dn := range matches {
+		responses = append(responses, message(id, entry(s.entries[dn], attrs), nil))
+	}
+	return append(responses, message(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""), done))
+}
+
+// inScope reports whether dn lies within the search scope rooted at base.
+func inScope(dn, base string, scope int64) bool {
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == base
+	case ldap.ScopeSingleLevel:
+		return dn != "" && directory.ParentDN(dn) == base
+	default:
+		return dn == base || base == "" || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+// isDeleted reports whether an entry represents a deleted object.
+func isDeleted(attrs map[string][]string) bool {
+	v := values(attrs, "isDeleted")
+	return len(v) > 0 && strings.EqualFold(v[0], "TRUE")
+}
+
+// match evaluates a search filter against the attributes of an entry.
+func match(filter *ber.Packet, attrs map[string][]string) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !match(child, attrs) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if match(child, attrs) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !match(filter.Children[0], attrs)
+	case ldap.FilterPresent:
+		name := filter.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(values(attrs, name)) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		name := filter.Children[0].Data.String()
+		want := filter.Children[1].Data.String()
+		for _, value := range values(attrs, name) {
+			switch filter.Tag {
+			case ldap.FilterEqualityMatch:
+				if strings.EqualFold(value, want) {
+					return true
+				}
+			default:
+				v, err1 := strconv.ParseInt(value, 10, 64)
+				w, err2 := strconv.ParseInt(want, 10, 64)
+				if err1 != nil || err2 != nil {
+					continue
+				}
+				if filter.Tag == ldap.FilterGreaterOrEqual && v >= w || filter.Tag == ldap.FilterLessOrEqual && v <= w {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// values returns the values of the named attribute, matched without regard to
+// case.
+func values(attrs map[string][]string, name string) []string {
+	for attr, values := range attrs {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func message(id int64, op, controls *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	packet.AppendChild(op)
+	if controls != nil {
+		packet.AppendChild(controls)
+	}
+	return packet
+}
+
+func result(tag ber.Tag, code uint16, diagnostic string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, diagnostic, "Diagnostic Message"))
+	return packet
+}
+
+// entry encodes a search result entry holding the requested attributes.
+func entry(attrs map[string][]string, requested []string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attrs["distinguishedName"][0], "Object Name"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, vals := range attrs {
+		if !wanted(name, requested) {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range vals {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	packet.AppendChild(list)
+	return packet
+}
+
+func wanted(name string, requested []string) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	for _, r := range requested {
+		if r == "*" || strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,37 +1,87 @@
 package membercache
 
 import (
+	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/dfsr.v0/dfsr"
 )
 
 // Cache represents a threadsafe DFSR member configuration cache.
+//
+// Entries may be given a limited lifetime, after which they are no longer
+// returned. Entries may also be invalidated explicitly when the underlying
+// directory objects are known to have changed.
 type Cache struct {
-	m     sync.RWMutex
-	cache map[string]dfsr.MemberInfo
+	m        sync.RWMutex
+	cache    map[string]entry
+	lifetime time.Duration
 }
 
-// New returns a new threadsafe DFSR member configuration cache.
+type entry struct {
+	member  dfsr.MemberInfo
+	expires time.Time // Zero if the entry never expires
+}
+
+// New returns a new threadsafe DFSR member configuration cache. Entries in
+// the cache never expire.
 func New() *Cache {
+	return NewWithLifetime(0)
+}
+
+// NewWithLifetime returns a new threadsafe DFSR member configuration cache
+// with entries that expire after the given lifetime. If lifetime is zero the
+// entries never expire.
+func NewWithLifetime(lifetime time.Duration) *Cache {
 	return &Cache{
-		cache: make(map[string]dfsr.MemberInfo),
+		cache:    make(map[string]entry),
+		lifetime: lifetime,
 	}
 }
 
 // Set saves the given DFSR member configuration data in the cache.
 func (mc *Cache) Set(member dfsr.MemberInfo) {
+	e := entry{member: member}
+	if mc.lifetime > 0 {
+		e.expires = time.Now().Add(mc.lifetime)
+	}
+
 	mc.m.Lock()
 	defer mc.m.Unlock()
-	mc.cache[member.DN] = member
+	mc.cache[member.DN] = e
 }
 
 // Retrieve returns the cached DFSR member configuration data for the given
-// distinguished name. If the data is not present in the cache then ok will be
-// false.
+// distinguished name. If the data is not present in the cache or has expired
+// then ok will be false.
 func (mc *Cache) Retrieve(dn string) (member dfsr.MemberInfo, ok bool) {
 	mc.m.RLock()
 	defer mc.m.RUnlock()
-	member, ok = mc.cache[dn]
-	return
+	e, ok := mc.cache[dn]
+	if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+		return dfsr.MemberInfo{}, false
+	}
+	return e.member, true
+}
+
+// Invalidate removes the cached DFSR member configuration data for the given
+// distinguished name.
+func (mc *Cache) Invalidate(dn string) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	delete(mc.cache, dn)
+}
+
+// InvalidateComputer removes the cached DFSR member configuration data of all
+// members that reside on the computer with the given distinguished name.
+// Computer names are matched without regard to case.
+func (mc *Cache) InvalidateComputer(computerDN string) {
+	mc.m.Lock()
+	defer mc.m.Unlock()
+	for dn, e := range mc.cache {
+		if strings.EqualFold(e.member.Computer.DN, computerDN) {
+			delete(mc.cache, dn)
+		}
+	}
 }
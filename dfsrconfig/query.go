@@ -0,0 +1,97 @@
+package dfsrconfig
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
+)
+
+// objectContext returns a context for an individual directory query that is
+// bounded by the configured object timeout.
+func (c *Client) objectContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.ObjectTimeout > 0 {
+		return context.WithTimeout(ctx, c.config.ObjectTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// open opens the object with the given distinguished name. If ctx is done or
+// the object timeout elapses before the directory responds the query is
+// abandoned and the context's error is returned. An abandoned object is closed
+// when the directory eventually returns it.
+func (c *Client) open(ctx context.Context, dn string) (directory.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.objectContext(ctx)
+	defer cancel()
+
+	type result struct {
+		obj directory.Object
+		err error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		obj, err := c.dir.Open(dn)
+		ch <- result{obj: obj, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.obj, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.obj != nil {
+				r.obj.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// children returns the children of obj. It abandons the query in the same
+// manner as open.
+func (c *Client) children(ctx context.Context, obj directory.Object) ([]directory.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.objectContext(ctx)
+	defer cancel()
+
+	type result struct {
+		children []directory.Object
+		err      error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		children, err := obj.Children()
+		ch <- result{children: children, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.children, r.err
+	case <-ctx.Done():
+		go func() {
+			r := <-ch
+			directory.CloseAll(r.children)
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// wait blocks for the given duration or until ctx is done, whichever comes
+// first.
+func wait(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
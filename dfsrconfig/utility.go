@@ -3,6 +3,8 @@ package dfsrconfig
 import (
 	"github.com/google/uuid"
 	"gopkg.in/adsi.v0"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig/directory"
 )
 
 func dnc(client *adsi.Client) (dnc string, err error) {
@@ -20,3 +22,46 @@ func littleEndianToBigEndian(id *uuid.UUID) {
 	id[4], id[5] = id[5], id[4]
 	id[6], id[7] = id[7], id[6]
 }
+
+// optionalString returns the value of a string attribute, or an empty string
+// if the attribute is not present.
+func optionalString(obj directory.Object, name string) (string, error) {
+	value, err := obj.AttrString(name)
+	if err == directory.ErrNoAttribute {
+		return "", nil
+	}
+	return value, err
+}
+
+// optionalInt returns the value of an integer attribute, or zero if the
+// attribute is not present.
+func optionalInt(obj directory.Object, name string) (int, error) {
+	value, err := obj.AttrInt(name)
+	if err == directory.ErrNoAttribute {
+		return 0, nil
+	}
+	return value, err
+}
+
+// optionalBool returns the value of a boolean attribute, or false if the
+// attribute is not present.
+func optionalBool(obj directory.Object, name string) (bool, error) {
+	value, err := obj.AttrBool(name)
+	if err == directory.ErrNoAttribute {
+		return false, nil
+	}
+	return value, err
+}
+
+// optionalSchedule returns the decoded value of a schedule attribute, or nil
+// if the attribute is not present.
+func optionalSchedule(obj directory.Object, name string) (*dfsr.Schedule, error) {
+	value, err := obj.AttrBytes(name)
+	if err == directory.ErrNoAttribute {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dfsr.ParseSchedule(value)
+}
@@ -0,0 +1,134 @@
+package fanout
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Stats holds statistics about a broadcaster.
+type Stats struct {
+	Listeners int    // Number of registered listeners
+	Sent      uint64 // Number of values queued for delivery to listeners
+	Dropped   uint64 // Number of values discarded because a listener buffer was full
+}
+
+// listener is a registered channel. The key is the receive-only form of the
+// channel, which is what listeners hand back when they unregister.
+type listener struct {
+	ch  reflect.Value
+	key interface{}
+}
+
+// Broadcaster delivers values to a set of listener channels.
+//
+// Values are sent without blocking. When a listener's channel buffer is full
+// the value is discarded for that listener and counted, so that a slow
+// listener never delays the sender or the other listeners. Listeners that
+// must not miss values should be given a buffer large enough to absorb bursts.
+//
+// It is safe to initialize a broadcaster with its zero value or to embed a
+// broadcaster in other types.
+type Broadcaster struct {
+	mutex     sync.Mutex
+	listeners []listener
+	closed    bool
+	stats     Stats
+}
+
+// Add registers ch as a listener. The channel must be a bidirectional channel
+// that will be closed by the broadcaster when it is removed or when the
+// broadcaster is closed. If the broadcaster is already closed ch is closed
+// immediately.
+func (b *Broadcaster) Add(ch interface{}) {
+	v := reflect.ValueOf(ch)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		v.Close()
+		return
+	}
+	b.listeners = append(b.listeners, listener{ch: v, key: recvKey(v)})
+}
+
+// Remove unregisters ch and closes it. The channel may be provided in either
+// its bidirectional or its receive-only form. Remove returns true if ch was
+// registered.
+func (b *Broadcaster) Remove(ch interface{}) (found bool) {
+	key := recvKey(reflect.ValueOf(ch))
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i := 0; i < len(b.listeners); i++ {
+		entry := b.listeners[i]
+		if entry.key != key {
+			continue
+		}
+
+		found = true
+		b.listeners = append(b.listeners[:i], b.listeners[i+1:]...)
+		i--
+		entry.ch.Close()
+	}
+	return
+}
+
+// Send delivers value to every listener whose buffer has room for it. The
+// value must be assignable to the element type of the listener channels.
+func (b *Broadcaster) Send(value interface{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, entry := range b.listeners {
+		v := reflect.ValueOf(value)
+		if !v.IsValid() {
+			v = reflect.Zero(entry.ch.Type().Elem())
+		}
+		if entry.ch.TrySend(v) {
+			b.stats.Sent++
+		} else {
+			b.stats.Dropped++
+		}
+	}
+}
+
+// Stats returns statistics about the broadcaster.
+func (b *Broadcaster) Stats() (stats Stats) {
+	b.mutex.Lock()
+	stats = b.stats
+	stats.Listeners = len(b.listeners)
+	b.mutex.Unlock()
+	return
+}
+
+// Close closes the channels of all listeners. Listeners added after the
+// broadcaster is closed are closed immediately.
+func (b *Broadcaster) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for _, entry := range b.listeners {
+		entry.ch.Close()
+	}
+	b.listeners = nil
+}
+
+// recvKey returns the receive-only form of the channel v as an interface
+// value, which compares equal for both forms of the same channel.
+func recvKey(v reflect.Value) interface{} {
+	if v.Type().ChanDir() == reflect.BothDir {
+		v = v.Convert(reflect.ChanOf(reflect.RecvDir, v.Type().Elem()))
+	}
+	return v.Interface()
+}
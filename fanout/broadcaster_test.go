@@ -0,0 +1,70 @@
+package fanout_test
+
+import (
+	"testing"
+
+	"gopkg.in/dfsr.v0/fanout"
+)
+
+func TestBroadcaster(t *testing.T) {
+	var b fanout.Broadcaster
+
+	fast, slow := make(chan int, 4), make(chan int, 1)
+	b.Add(fast)
+	b.Add(slow)
+
+	for i := 1; i <= 3; i++ {
+		b.Send(i)
+	}
+
+	if stats := b.Stats(); stats.Listeners != 2 || stats.Sent != 4 || stats.Dropped != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if v := <-slow; v != 1 {
+		t.Errorf("slow listener received %d, want 1", v)
+	}
+
+	// Listeners hand back the receive-only form of their channel
+	if !b.Remove((<-chan int)(slow)) {
+		t.Error("slow listener was not found")
+	}
+	if _, ok := <-slow; ok {
+		t.Error("removed listener was not closed")
+	}
+	if b.Remove(slow) {
+		t.Error("slow listener was removed twice")
+	}
+
+	b.Close()
+	for i := 1; i <= 3; i++ {
+		if v := <-fast; v != i {
+			t.Errorf("fast listener received %d, want %d", v, i)
+		}
+	}
+	if _, ok := <-fast; ok {
+		t.Error("listener was not closed with the broadcaster")
+	}
+
+	late := make(chan int)
+	b.Add(late)
+	if _, ok := <-late; ok {
+		t.Error("listener added after close was not closed")
+	}
+	b.Send(4) // Must not panic
+}
+
+func TestBroadcasterNil(t *testing.T) {
+	var b fanout.Broadcaster
+	defer b.Close()
+
+	ch := make(chan *int, 1)
+	b.Add(ch)
+	b.Send((*int)(nil))
+	b.Send(nil)
+	if v := <-ch; v != nil {
+		t.Errorf("listener received %v, want nil", v)
+	}
+	if stats := b.Stats(); stats.Sent != 1 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
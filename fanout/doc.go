@@ -0,0 +1,3 @@
+// Package fanout provides a threadsafe broadcaster that delivers values to a
+// set of listener channels without ever blocking the sender.
+package fanout
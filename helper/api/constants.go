@@ -10,4 +10,8 @@ const (
 var (
 	ErrInvalidNamespace = errors.New("the provided name or namespace is invalid")
 	ErrAccessDenied     = errors.New("access denied")
+
+	// ErrInvalidVectorData is returned when a safe array does not contain
+	// version vector data in the expected form.
+	ErrInvalidVectorData = errors.New("invalid version vector data")
 )
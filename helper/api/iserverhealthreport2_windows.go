@@ -29,6 +29,11 @@ func (v *IServerHealthReport) GetReport(group uuid.UUID, server string, referenc
 		return nil, "", ole.NewError(ole.E_OUTOFMEMORY)
 	}
 	defer ole.SysFreeString(sbstr)
+	var reference *ole.SafeArray
+	if referenceVectors != nil {
+		reference = referenceVectors.Array
+	}
+	memberVectors = new(ole.SafeArrayConversion)
 	var rbstr *int16
 	hr, _, _ := syscall.Syscall9(
 		uintptr(v.VTable().GetReport),
@@ -37,7 +42,7 @@ func (v *IServerHealthReport) GetReport(group uuid.UUID, server string, referenc
 		uintptr(unsafe.Pointer(comutil.GUID(group))),
 		uintptr(0),
 		uintptr(unsafe.Pointer(&sbstr)),
-		uintptr(unsafe.Pointer(referenceVectors.Array)),
+		uintptr(unsafe.Pointer(reference)),
 		uintptr(flags),
 		uintptr(unsafe.Pointer(&memberVectors.Array)),
 		uintptr(unsafe.Pointer(&rbstr)),
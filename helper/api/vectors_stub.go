@@ -0,0 +1,19 @@
+// +build !windows
+
+package api
+
+import "github.com/go-ole/go-ole"
+
+// DecodeVersionVectors returns the serialized version vector of each
+// replicated folder contained in the given safe array. The safe array is not
+// released.
+func DecodeVersionVectors(sa *ole.SafeArrayConversion) (vectors [][]byte, err error) {
+	return nil, ole.NewError(ole.E_NOTIMPL)
+}
+
+// EncodeVersionVectors returns a safe array containing the given serialized
+// version vectors. It is the caller's responsibility to release the returned
+// safe array when finished with it.
+func EncodeVersionVectors(vectors [][]byte) (sa *ole.SafeArrayConversion, err error) {
+	return nil, ole.NewError(ole.E_NOTIMPL)
+}
@@ -0,0 +1,117 @@
+// +build windows
+
+package api
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/go-ole/go-ole"
+)
+
+var (
+	modoleaut32 = syscall.NewLazyDLL("oleaut32.dll")
+
+	procSafeArrayCreateVector = modoleaut32.NewProc("SafeArrayCreateVector")
+	procSafeArrayDestroy      = modoleaut32.NewProc("SafeArrayDestroy")
+	procSafeArrayGetElement   = modoleaut32.NewProc("SafeArrayGetElement")
+	procSafeArrayPutElement   = modoleaut32.NewProc("SafeArrayPutElement")
+	procSafeArrayGetLBound    = modoleaut32.NewProc("SafeArrayGetLBound")
+	procSafeArrayGetUBound    = modoleaut32.NewProc("SafeArrayGetUBound")
+	procSafeArrayAccessData   = modoleaut32.NewProc("SafeArrayAccessData")
+	procSafeArrayUnaccessData = modoleaut32.NewProc("SafeArrayUnaccessData")
+)
+
+// DecodeVersionVectors returns the serialized version vector of each
+// replicated folder contained in the given safe array. The safe array is not
+// released.
+//
+// The safe array is expected to be a one-dimensional array of variants, each
+// of which holds an array of bytes.
+func DecodeVersionVectors(sa *ole.SafeArrayConversion) (vectors [][]byte, err error) {
+	if sa == nil || sa.Array == nil {
+		return nil, ErrInvalidVectorData
+	}
+
+	var lower, upper int32
+	if hr, _, _ := procSafeArrayGetLBound.Call(uintptr(unsafe.Pointer(sa.Array)), 1, uintptr(unsafe.Pointer(&lower))); hr != 0 {
+		return nil, convertHresultToError(hr)
+	}
+	if hr, _, _ := procSafeArrayGetUBound.Call(uintptr(unsafe.Pointer(sa.Array)), 1, uintptr(unsafe.Pointer(&upper))); hr != 0 {
+		return nil, convertHresultToError(hr)
+	}
+
+	for i := lower; i <= upper; i++ {
+		var v ole.VARIANT
+		index := i
+		hr, _, _ := procSafeArrayGetElement.Call(uintptr(unsafe.Pointer(sa.Array)), uintptr(unsafe.Pointer(&index)), uintptr(unsafe.Pointer(&v)))
+		if hr != 0 {
+			return nil, convertHresultToError(hr)
+		}
+		if v.VT != ole.VT_ARRAY|ole.VT_UI1 {
+			v.Clear()
+			return nil, ErrInvalidVectorData
+		}
+		vectors = append(vectors, v.ToArray().ToByteArray())
+		v.Clear()
+	}
+
+	return
+}
+
+// EncodeVersionVectors returns a safe array containing the given serialized
+// version vectors. It is the caller's responsibility to release the returned
+// safe array when finished with it.
+//
+// The returned safe array is a one-dimensional array of variants, each of
+// which holds an array of bytes.
+func EncodeVersionVectors(vectors [][]byte) (sa *ole.SafeArrayConversion, err error) {
+	outer, err := createVector(ole.VT_VARIANT, len(vectors))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, data := range vectors {
+		inner, err := createVector(ole.VT_UI1, len(data))
+		if err != nil {
+			destroyVector(outer)
+			return nil, err
+		}
+
+		if len(data) > 0 {
+			var p unsafe.Pointer
+			if hr, _, _ := procSafeArrayAccessData.Call(uintptr(unsafe.Pointer(inner)), uintptr(unsafe.Pointer(&p))); hr != 0 {
+				destroyVector(inner)
+				destroyVector(outer)
+				return nil, convertHresultToError(hr)
+			}
+			copy((*[1 << 30]byte)(p)[:len(data):len(data)], data)
+			procSafeArrayUnaccessData.Call(uintptr(unsafe.Pointer(inner)))
+		}
+
+		// SafeArrayPutElement makes a copy of the variant, so the original is
+		// cleared afterward, which also destroys inner.
+		v := ole.NewVariant(ole.VT_ARRAY|ole.VT_UI1, int64(uintptr(unsafe.Pointer(inner))))
+		index := int32(i)
+		hr, _, _ := procSafeArrayPutElement.Call(uintptr(unsafe.Pointer(outer)), uintptr(unsafe.Pointer(&index)), uintptr(unsafe.Pointer(&v)))
+		v.Clear()
+		if hr != 0 {
+			destroyVector(outer)
+			return nil, convertHresultToError(hr)
+		}
+	}
+
+	return &ole.SafeArrayConversion{Array: outer}, nil
+}
+
+func createVector(vt ole.VT, length int) (*ole.SafeArray, error) {
+	sa, _, _ := procSafeArrayCreateVector.Call(uintptr(vt), 0, uintptr(length))
+	if sa == 0 {
+		return nil, ole.NewError(ole.E_OUTOFMEMORY)
+	}
+	return *(**ole.SafeArray)(unsafe.Pointer(&sa)), nil
+}
+
+func destroyVector(sa *ole.SafeArray) {
+	procSafeArrayDestroy.Call(uintptr(unsafe.Pointer(sa)))
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// BreakerState is the state of an endpoint's circuit breaker.
+type BreakerState int
+
+// Circuit breaker states.
+//
+// While the circuit is closed calls are made normally. While it is open calls
+// fail immediately and the endpoint waits for its backoff delay to elapse
+// before attempting to reconnect. Once a connection has been reestablished the
+// circuit is half-open and a single call is made to probe the server. Other
+// calls wait for the outcome of the probe, which either closes the circuit or
+// opens it again.
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+// String returns a string representation of the breaker state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(s))
+	}
+}
+
+// BreakerEvent describes a transition of an endpoint's circuit breaker from one
+// state to another.
+type BreakerEvent struct {
+	Server   string // Lower-case FQDN of the endpoint
+	Time     time.Time
+	From     BreakerState
+	To       BreakerState
+	Cause    error     // Reason the circuit was opened, if it is not closed
+	Failures uint      // Number of consecutive failures
+	Retry    time.Time // Time of the next connection attempt, if the circuit is open
+}
+
+// backoff returns the amount of time to wait before attempting to reconnect
+// after the given number of consecutive failures.
+//
+// The delay starts at BackoffInitial and is multiplied by BackoffMultiplier
+// for each additional failure, up to OfflineReconnectionInterval. A random
+// fraction of the delay, no greater than BackoffJitter, is then subtracted so
+// that endpoints which failed together do not reconnect in lockstep.
+func (c *EndpointConfig) backoff(failures uint) time.Duration {
+	max := c.OfflineReconnectionInterval
+
+	d := c.BackoffInitial
+	if d <= 0 || (max > 0 && d > max) {
+		d = max
+	}
+
+	if c.BackoffMultiplier > 1 {
+		for i := uint(1); i < failures && (max <= 0 || d < max); i++ {
+			d = time.Duration(float64(d) * c.BackoffMultiplier)
+		}
+		if max > 0 && d > max {
+			d = max
+		}
+	}
+
+	if jitter := c.BackoffJitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+
+	return d
+}
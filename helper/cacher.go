@@ -4,7 +4,6 @@ import (
 	"context"
 	"time"
 
-	"github.com/go-ole/go-ole"
 	"github.com/google/uuid"
 	"gopkg.in/dfsr.v0/callstat"
 	"gopkg.in/dfsr.v0/dfsr"
@@ -38,7 +37,7 @@ func (c *cacher) Backlog(ctx context.Context, vector *versionvector.Vector, trac
 	return c.r.Backlog(ctx, vector, tracker)
 }
 
-func (c *cacher) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error) {
+func (c *cacher) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
 	return c.r.Report(ctx, group, vector, backlog, files)
 }
 
@@ -5,9 +5,11 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/go-ole/go-ole"
 	"github.com/google/uuid"
 	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/fanout"
+	"gopkg.in/dfsr.v0/helper/report"
 	"gopkg.in/dfsr.v0/versionvector"
 )
 
@@ -20,6 +22,8 @@ import (
 // Queries against endpoints that are known to be offline will return a failure
 // immediately.
 type Client struct {
+	bc fanout.Broadcaster // Broadcasts circuit breaker events of all endpoints
+
 	mutex     sync.RWMutex
 	config    EndpointConfig
 	endpoints map[string]*Endpoint // Maps lower-case FQDNs to the Reporter inferface for each server
@@ -64,6 +68,22 @@ func (c *Client) UpdateConfig(config EndpointConfig) {
 	}
 }
 
+// States returns the current state of each endpoint that the client has
+// communicated with, keyed by lower-case fully qualified domain name. If the
+// client has been closed it returns nil.
+func (c *Client) States() (states map[string]EndpointState) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.endpoints == nil {
+		return nil // Already closed
+	}
+	states = make(map[string]EndpointState, len(c.endpoints))
+	for fqdn, e := range c.endpoints {
+		states[fqdn] = e.State()
+	}
+	return
+}
+
 // Close will release any resources consumed by the Client.
 func (c *Client) Close() {
 	c.mutex.Lock()
@@ -76,6 +96,28 @@ func (c *Client) Close() {
 		e.Close()
 	}
 	c.endpoints = nil
+	c.bc.Close()
+}
+
+// Listen returns a channel on which circuit breaker events for all of the
+// client's endpoints will be broadcast. The channel will be closed when the
+// client is closed or when Unlisten is called for the returned channel.
+//
+// The returned channel will use the provided channel buffer size. Events that
+// do not fit in the channel's buffer are discarded, so that a slow listener
+// never delays an endpoint.
+func (c *Client) Listen(chanSize int) <-chan BreakerEvent {
+	ch := make(chan BreakerEvent, chanSize)
+	c.bc.Add(ch)
+	return ch
+}
+
+// Unlisten closes the given listener's channel and removes it from the set of
+// listeners that receive circuit breaker events.
+//
+// Unlisten returns false if the listener was not present.
+func (c *Client) Unlisten(ch <-chan BreakerEvent) (found bool) {
+	return c.bc.Remove(ch)
 }
 
 // Backlog returns the outgoing backlog from one DSFR member to another. The
@@ -100,13 +142,56 @@ func (c *Client) Backlog(ctx context.Context, from, to string, group uuid.UUID)
 	if err != nil {
 		return
 	}
-	defer v.Close()
 
 	backlog, bcall, err := f.Backlog(ctx, v)
 	call.Add(&bcall)
 	return
 }
 
+// BacklogFiles returns the files that are backlogged from one DFSR member to
+// another for the given replication group. Members are identified by their
+// fully qualified domain names. The files are returned as a map of replicated
+// folder IDs to files.
+//
+// The files are retrieved from a health report generated by the sending
+// member, which only lists a limited number of files for each folder. If limit
+// is greater than zero no more than limit files will be returned for each
+// folder.
+func (c *Client) BacklogFiles(ctx context.Context, from, to string, group uuid.UUID, limit int) (files map[uuid.UUID][]dfsr.BacklogFile, call callstat.Call, err error) {
+	call.Begin("Client.BacklogFiles")
+	defer call.Complete(err)
+
+	f, err := c.endpoint(from)
+	if err != nil {
+		return
+	}
+
+	t, err := c.endpoint(to)
+	if err != nil {
+		return
+	}
+
+	v, vcall, err := t.Vector(ctx, group)
+	call.Add(&vcall)
+	if err != nil {
+		return
+	}
+
+	_, data, rcall, err := f.Report(ctx, group, v, true, true)
+	call.Add(&rcall)
+	if err != nil {
+		return
+	}
+
+	r, err := report.ParseString(data)
+	if err != nil {
+		return
+	}
+
+	files = backlogFiles(r, group, to, limit)
+	return
+}
+
 // Vector returns the current reference version vector of the requested
 // replication group on the specified DFSR member. The member is identified by
 // its fully qualified domain name.
@@ -125,7 +210,7 @@ func (c *Client) Vector(ctx context.Context, server string, group uuid.UUID) (ve
 }
 
 // Report generates a report for the requested replication group.
-func (c *Client) Report(ctx context.Context, server string, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error) {
+func (c *Client) Report(ctx context.Context, server string, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
 	call.Begin("Client.Report")
 	defer call.Complete(err)
 
@@ -134,7 +219,7 @@ func (c *Client) Report(ctx context.Context, server string, group uuid.UUID, vec
 		return
 	}
 
-	data, report, rcall, err := e.Report(ctx, group, vector, backlog, files)
+	member, report, rcall, err := e.Report(ctx, group, vector, backlog, files)
 	call.Add(&rcall)
 	return
 }
@@ -164,7 +249,7 @@ func (c *Client) endpoint(fqdn string) (*Endpoint, error) {
 	if found {
 		return e, nil
 	}
-	e = NewEndpoint(fqdn, c.config)
+	e = newEndpoint(fqdn, c.config, &c.bc)
 	c.endpoints[fqdn] = e
 	return e, nil
 }
@@ -9,6 +9,10 @@ const (
 	// DefaultRecoveryInterval specifies the default recovery interval for
 	// client instances.
 	DefaultRecoveryInterval = time.Second * 30
+
+	// minLatencySamples is the minimum number of recorded call durations
+	// required before an endpoint's circuit can be opened by slow calls.
+	minLatencySamples = 5
 )
 
 var (
@@ -21,6 +25,9 @@ var (
 	// the underlying remote procedure call stalls for an unreasonable length of
 	// time.
 	ErrUnresponsive = errors.New("the server is unresponsive")
+	// ErrCircuitOpen is returned from calls to an endpoint when its circuit
+	// breaker is open and no other cause is known.
+	ErrCircuitOpen = errors.New("the circuit breaker for the server is open")
 	// ErrZeroWorkers is returned when zero workers are specified in a call to
 	// NewLimiter.
 	ErrZeroWorkers = errors.New("no workers were specified for the limiter")
@@ -6,9 +6,9 @@ import (
 	"time"
 
 	"github.com/gentlemanautomaton/calltracker"
-	ole "github.com/go-ole/go-ole"
 	"github.com/google/uuid"
 	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/fanout"
 	"gopkg.in/dfsr.v0/versionvector"
 )
 
@@ -21,7 +21,12 @@ var DefaultEndpointConfig = EndpointConfig{
 	Limit:         1,
 	OnlineReconnectionInterval:  time.Minute * 30,
 	OfflineReconnectionInterval: time.Minute * 2,
+	BackoffInitial:              time.Second * 5,
+	BackoffMultiplier:           2,
+	BackoffJitter:               0.2,
 	AcceptableCallDuration:      time.Second * 30,
+	LatencyPercentile:           0.9,
+	LatencySamples:              20,
 }
 
 // EndpointConfig desribes a set of endpoint configuration parameters.
@@ -31,14 +36,38 @@ var DefaultEndpointConfig = EndpointConfig{
 //
 // Limiting instructs the client to limit the maximum number of simultaneous
 // workers that can talk to an endpoint.
+//
+// Backoff parameters determine how long the endpoint waits before attempting
+// to reconnect after its circuit breaker opens. The first attempt is made after
+// BackoffInitial, and the delay is multiplied by BackoffMultiplier after each
+// failed attempt until it reaches OfflineReconnectionInterval. BackoffJitter is
+// the fraction of each delay that is randomized. If BackoffInitial is zero the
+// endpoint waits OfflineReconnectionInterval between attempts.
+//
+// Latency parameters determine when a slow server trips the circuit breaker.
+// The durations of the most recent LatencySamples calls are recorded, and the
+// circuit opens when the call duration at LatencyPercentile exceeds
+// AcceptableCallDuration. If LatencySamples is zero only outstanding calls are
+// considered.
+//
+// Factory is used to create the underlying Reporter for each connection. If it
+// is nil, NewReporter will be used to connect to the server via the DFSR Helper
+// protocol.
 type EndpointConfig struct {
 	Caching                     bool
 	CacheDuration               time.Duration
 	Limiting                    bool
 	Limit                       uint          // Maximum number of simultaneous calls
 	OnlineReconnectionInterval  time.Duration // Time between connection attempts when endpoint is online
-	OfflineReconnectionInterval time.Duration // Time between connection attempts when endpoint is offline
+	OfflineReconnectionInterval time.Duration // Maximum time between connection attempts when endpoint is offline
+	BackoffInitial              time.Duration // Time before the first connection attempt after the circuit opens
+	BackoffMultiplier           float64       // Growth factor of the time between failed connection attempts
+	BackoffJitter               float64       // Fraction of the time between connection attempts that is randomized, from 0 to 1
 	AcceptableCallDuration      time.Duration // Maximum amount of time a remote procedure call is allowed before it is considered unresponsive
+	LatencyPercentile           float64       // Percentile of recent call durations that is compared against AcceptableCallDuration, from 0 to 1
+	LatencySamples              int           // Number of recent call durations that are recorded
+
+	Factory ReporterFactory // Creates reporters for new connections
 
 	// TODO: Use ICMP pings to assess network failure
 	//PingInterval  time.Duration
@@ -53,6 +82,11 @@ type EndpointState struct {
 	Updated   time.Time         // Last time the state was updated
 	IdleSince time.Time         // Last time an action was performed on the endpoint
 	Calls     calltracker.Value // Representation of outstanding calls
+	Breaker   BreakerState      // State of the circuit breaker
+	Cause     error             // Reason the circuit was opened, if it is not closed
+	Failures  uint              // Number of consecutive failures
+	Retry     time.Time         // Time of the next connection attempt, if the circuit is open
+	Latency   time.Duration     // Duration of recent calls at the configured percentile
 }
 
 // Online returns true if the state indicates that the endpoint is online.
@@ -84,11 +118,17 @@ const endpointChanSize = 32
 
 // Endpoint manages a connection to a remote or local server that implements the
 // DFSR Helper protocol. It monitors the health of the connection by checking
-// the errors returned by all queries for RPC connection failures.
+// the errors returned by all queries for RPC connection failures and by
+// measuring the duration of each call.
 //
-// When a connection is determined to be offline the endpoint manager will
-// proactively attempt to reestablish it on a configurable interval. While
-// offline all queries will fail immediately.
+// The endpoint guards the server with a circuit breaker. When a connection is
+// determined to be offline or unresponsive the circuit opens, and all queries
+// fail immediately. The endpoint manager will proactively attempt to
+// reestablish the connection with an exponentially increasing delay between
+// attempts. Once reconnected the circuit is half-open, and the next query is
+// used to probe the server while other queries wait for its outcome. A prompt
+// response closes the circuit. Transitions between states are broadcast to
+// listeners as events.
 //
 // The underlying connection is reset periodically even when the connection is
 // healthy in order to release resources in the RPC layer of remote systems that
@@ -108,22 +148,35 @@ type Endpoint struct {
 	configChange chan EndpointConfig // Receives configuration updates. Consumed by run(). Closure initiates shutdown.
 	stateChange  chan EndpointState  // Receives state changes. Consumed by run(). Closure initiates shutdown.
 	tracker      calltracker.Tracker // Tracks the number and condition of outstanding remote procedure calls.
-
-	mutex    sync.RWMutex
-	config   EndpointConfig
-	state    EndpointState
-	sequence uint64 // Last health update sequence number received
-	r        Reporter
+	bc           fanout.Broadcaster  // Broadcasts circuit breaker events
+	relay        *fanout.Broadcaster // Also broadcasts circuit breaker events if not nil
+
+	mutex     sync.RWMutex
+	config    EndpointConfig
+	state     EndpointState
+	sequence  uint64        // Last health update sequence number received
+	latency   latencyWindow // Durations of recent calls
+	reset     time.Time     // Last time the circuit became half-open
+	probing   bool          // Indicates that a probe is in progress while the circuit is half-open
+	probeDone chan struct{} // Closed when the probe in progress completes
+	r         Reporter
 }
 
 // NewEndpoint creates a new endpoint and returns it without blocking. The
 // returned endpoint will be initialized asynchronously in its own goroutine.
 func NewEndpoint(fqdn string, config EndpointConfig) *Endpoint {
+	return newEndpoint(fqdn, config, nil)
+}
+
+// newEndpoint creates a new endpoint. If relay is not nil circuit breaker
+// events for the endpoint will also be broadcast by relay.
+func newEndpoint(fqdn string, config EndpointConfig, relay *fanout.Broadcaster) *Endpoint {
 	now := time.Now()
 	e := &Endpoint{
 		fqdn:         fqdn,
 		configChange: make(chan EndpointConfig, endpointChanSize),
 		stateChange:  make(chan EndpointState, endpointChanSize),
+		relay:        relay,
 		config:       config,
 		state: EndpointState{
 			Err:     ErrDisconnected,
@@ -131,6 +184,7 @@ func NewEndpoint(fqdn string, config EndpointConfig) *Endpoint {
 			Updated: now,
 		},
 	}
+	e.latency.Reset(config.LatencySamples)
 	e.ready.Add(1)
 	e.closed.Add(1)
 	state := e.state
@@ -173,6 +227,7 @@ func (e *Endpoint) Close() {
 		return
 	}
 	e.state.Err = ErrClosed
+	e.endProbe() // Release callers waiting for a probe
 	// Closing either of these channels causes run() to exit
 	close(e.configChange)
 	close(e.stateChange)
@@ -188,6 +243,29 @@ func (e *Endpoint) Close() {
 		e.r = nil
 	}
 	e.mutex.Unlock()
+
+	e.bc.Close()
+}
+
+// Listen returns a channel on which circuit breaker events for the endpoint
+// will be broadcast. The channel will be closed when the endpoint is closed or
+// when Unlisten is called for the returned channel.
+//
+// The returned channel will use the provided channel buffer size. Events that
+// do not fit in the channel's buffer are discarded, so that a slow listener
+// never delays the endpoint.
+func (e *Endpoint) Listen(chanSize int) <-chan BreakerEvent {
+	ch := make(chan BreakerEvent, chanSize)
+	e.bc.Add(ch)
+	return ch
+}
+
+// Unlisten closes the given listener's channel and removes it from the set of
+// listeners that receive circuit breaker events.
+//
+// Unlisten returns false if the listener was not present.
+func (e *Endpoint) Unlisten(c <-chan BreakerEvent) (found bool) {
+	return e.bc.Remove(c)
 }
 
 // Vector returns the reference version vectors for the requested replication
@@ -196,25 +274,16 @@ func (e *Endpoint) Vector(ctx context.Context, group uuid.UUID) (vector *version
 	call.Begin("Endpoint.Vector")
 	defer call.Complete(err)
 
-	e.ready.Wait()
-	e.mutex.RLock()
-	r, state, threshold, err := e.r, e.state, e.config.AcceptableCallDuration, e.state.Err
-	e.mutex.RUnlock()
-
+	r, probe, err := e.admit(ctx, true)
 	if err != nil {
 		return
 	}
 
-	if state.Unresponsive(threshold) {
-		err = ErrUnresponsive
-		return
-	}
-
 	var subcall callstat.Call
 	vector, subcall, err = r.Vector(ctx, group, &e.tracker)
 	call.Add(&subcall)
 
-	e.updateStateAfterCall(r, err, time.Now())
+	e.updateStateAfterCall(ctx, r, probe, subcall.Duration(), err, time.Now())
 	return
 }
 
@@ -224,47 +293,37 @@ func (e *Endpoint) Backlog(ctx context.Context, vector *versionvector.Vector) (b
 	call.Begin("Endpoint.Backlog")
 	defer call.Complete(err)
 
-	e.ready.Wait()
-	e.mutex.RLock()
-	r, state, threshold, err := e.r, e.state, e.config.AcceptableCallDuration, e.state.Err
-	e.mutex.RUnlock()
-
+	r, probe, err := e.admit(ctx, true)
 	if err != nil {
 		return
 	}
 
-	if state.Unresponsive(threshold) {
-		err = ErrUnresponsive
-		return
-	}
-
 	var subcall callstat.Call
 	backlog, subcall, err = r.Backlog(ctx, vector, &e.tracker)
 	call.Add(&subcall)
 
-	e.updateStateAfterCall(r, err, time.Now())
+	e.updateStateAfterCall(ctx, r, probe, subcall.Duration(), err, time.Now())
 	return
 }
 
 // Report generates a report when compared against the reference version vector.
-func (e *Endpoint) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error) {
+//
+// Reports can take much longer to generate than other queries, so their
+// duration is not held against the endpoint.
+func (e *Endpoint) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
 	call.Begin("Endpoint.Report")
 	defer call.Complete(err)
 
-	e.ready.Wait()
-	e.mutex.RLock()
-	r, err := e.r, e.state.Err
-	e.mutex.RUnlock()
-
+	r, probe, err := e.admit(ctx, false)
 	if err != nil {
 		return
 	}
 
 	var subcall callstat.Call
-	data, report, subcall, err = r.Report(ctx, group, vector, backlog, files)
+	member, report, subcall, err = r.Report(ctx, group, vector, backlog, files)
 	call.Add(&subcall)
 
-	e.updateStateAfterCall(r, err, time.Now())
+	e.updateStateAfterCall(ctx, r, probe, 0, err, time.Now())
 	return
 }
 
@@ -277,7 +336,7 @@ func (e *Endpoint) run(config EndpointConfig, state EndpointState) {
 	// 1. Connection attempts can take a long time to timeout and we want the
 	//    connection interval to exclude that time.
 	// 2. The connection interval changes depending on whether the endpoint is
-	//    online or offline.
+	//    online or offline, and while offline it grows with each failed attempt.
 
 	defer e.closed.Done()
 
@@ -308,19 +367,21 @@ func (e *Endpoint) run(config EndpointConfig, state EndpointState) {
 			case cacheChange || limitChange:
 				resetActiveTimer(connTimer, 0) // Reconnect to apply new configuration
 			case connTimerChange:
-				resetConnectionTimer(connTimer, state.Online(), &config, connTimestamp)
+				resetConnectionTimer(connTimer, &state, &config, connTimestamp)
 			}
 		case newState, ok := <-e.stateChange:
 			if !ok {
 				return // endpoint is closing
 			}
 
-			onlineChange := state.Online() != newState.Online()
+			// Schedule the next connection attempt when the circuit opens or
+			// another failure pushes it back
+			reschedule := newState.Breaker == BreakerOpen && (state.Breaker != BreakerOpen || !newState.Retry.Equal(state.Retry))
 
 			state = newState
 
-			if onlineChange && !state.Online() {
-				resetActiveTimer(connTimer, 0) // Try to reconnect immediately
+			if reschedule {
+				resetActiveTimer(connTimer, state.Retry.Sub(time.Now()))
 			}
 		case <-connTimer.C:
 			var (
@@ -328,7 +389,7 @@ func (e *Endpoint) run(config EndpointConfig, state EndpointState) {
 				err       error
 				makeReady bool
 			)
-			r, connTimestamp, err = createEndpointConnection(e.fqdn, config)
+			r, connTimestamp, err = createEndpointConnection(e.fqdn, config, e.recordLatency)
 			if !initialized {
 				initialized = true
 				makeReady = true
@@ -338,6 +399,8 @@ func (e *Endpoint) run(config EndpointConfig, state EndpointState) {
 			if err == nil {
 				connTimer.Reset(config.OnlineReconnectionInterval)
 			} else {
+				// The timer will be rescheduled according to the backoff delay
+				// when the state change arrives
 				connTimer.Reset(config.OfflineReconnectionInterval)
 			}
 			/*
@@ -380,6 +443,13 @@ func (e *Endpoint) updateConnection(r Reporter, err error, when time.Time, makeR
 		retired = r
 	} else {
 		retired, e.r, e.state.Err = e.r, r, err
+		e.endProbe() // Probes made on the retired connection don't count
+		switch {
+		case err != nil:
+			e.open(err, when)
+		case e.state.Breaker == BreakerOpen:
+			e.transition(BreakerHalfOpen, e.state.Cause, when)
+		}
 		e.updateConnectionState(err, when, false)
 	}
 
@@ -392,10 +462,93 @@ func (e *Endpoint) updateConnection(r Reporter, err error, when time.Time, makeR
 	}
 }
 
+// admit waits for the endpoint to be ready and determines whether a call may
+// be made in its current state. It returns the reporter that the call should
+// be made with.
+//
+// While the circuit is half-open the first caller is admitted as a probe and
+// other callers wait for the outcome of the probe or the cancellation of ctx.
+//
+// If unresponsive is true the circuit will be opened when the oldest
+// outstanding call has exceeded the acceptable call duration, including a
+// probe that has stalled.
+func (e *Endpoint) admit(ctx context.Context, unresponsive bool) (r Reporter, probe bool, err error) {
+	e.ready.Wait()
+
+	for {
+		e.mutex.Lock()
+
+		if err = e.state.Err; err != nil {
+			e.mutex.Unlock()
+			return
+		}
+
+		switch e.state.Breaker {
+		case BreakerOpen:
+			if err = e.state.Cause; err == nil {
+				err = ErrCircuitOpen
+			}
+		case BreakerHalfOpen:
+			if now := time.Now(); e.probing && unresponsive && e.stalled(now) {
+				// The probe has stalled
+				e.endProbe()
+				e.open(ErrUnresponsive, now)
+				e.publishState()
+				err = ErrUnresponsive
+				break
+			}
+			if e.probing {
+				wait := e.probeDone
+				e.mutex.Unlock()
+				select {
+				case <-wait:
+					continue
+				case <-ctx.Done():
+					err = ctx.Err()
+					return
+				}
+			}
+			e.probing, e.probeDone = true, make(chan struct{})
+			probe = true
+		case BreakerClosed:
+			if now := time.Now(); unresponsive && e.stalled(now) {
+				e.open(ErrUnresponsive, now)
+				e.publishState()
+				err = ErrUnresponsive
+			}
+		}
+
+		if err == nil {
+			r = e.r
+		}
+		e.mutex.Unlock()
+		return
+	}
+}
+
+// stalled returns true if the oldest outstanding call has exceeded the
+// acceptable call duration. A call that began before the circuit last became
+// half-open is disregarded, as it was made with a connection that has since
+// been replaced. Only the oldest call can be assessed, so newer calls go
+// unnoticed while such a call remains outstanding.
+//
+// The caller must hold a lock on the endpoint during the function call.
+func (e *Endpoint) stalled(now time.Time) bool {
+	if !e.state.Unresponsive(e.config.AcceptableCallDuration) {
+		return false
+	}
+	return now.Add(-e.state.Calls.MaxElapsed()).After(e.reset)
+}
+
 // updateStateAfterCall will evaluate the provided err to determine whether
 // it indicates a change in the state of the endpoint. If so, it will record the
 // state change. It will also update the endpoint's idle time.
-func (e *Endpoint) updateStateAfterCall(r Reporter, err error, when time.Time) {
+//
+// If the call was a probe its outcome determines whether the circuit closes or
+// opens again. A probe fails if the server is unavailable or if elapsed
+// exceeds the acceptable call duration. A probe that was cancelled is
+// inconclusive, and leaves the circuit half-open for the next caller.
+func (e *Endpoint) updateStateAfterCall(ctx context.Context, r Reporter, probe bool, elapsed time.Duration, err error, when time.Time) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 
@@ -403,16 +556,143 @@ func (e *Endpoint) updateStateAfterCall(r Reporter, err error, when time.Time) {
 		e.state.IdleSince = when
 	}
 
-	if IsUnavailableErr(err) {
-		// The error only affects the current state if it's for the current
-		// connection. The connection could have been reset while this call was
-		// being made.
-		if e.r == r {
-			e.updateConnectionState(err, when, true)
+	// The call only affects the current state if it's for the current
+	// connection. The connection could have been reset while this call was
+	// being made.
+	if e.r != r || e.state.Closed() {
+		return
+	}
+
+	if probe {
+		if !e.probing {
+			probe = false // The probe was abandoned after it stalled
 		}
+		e.endProbe()
+	}
+
+	switch {
+	case IsUnavailableErr(err):
+		if e.state.Breaker != BreakerOpen {
+			e.open(err, when)
+			e.publishState()
+		}
+		e.updateConnectionState(err, when, true)
+	case !probe:
+	case ctx.Err() != nil:
+	case elapsed > e.config.AcceptableCallDuration:
+		e.open(ErrUnresponsive, when)
+		e.publishState()
+	default:
+		e.transition(BreakerClosed, nil, when)
+		e.publishState()
+	}
+}
+
+// recordLatency records the duration of a call that was made with one of the
+// endpoint's connections. If the circuit is closed and the configured
+// percentile of recent call durations exceeds the acceptable call duration the
+// circuit will be opened.
+func (e *Endpoint) recordLatency(d time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// Only collect samples while the circuit is closed, so that calls made
+	// before the circuit opened don't count against it when it closes again
+	if e.state.Closed() || e.state.Breaker != BreakerClosed {
+		return
+	}
+
+	if e.latency.Size() != e.config.LatencySamples {
+		e.latency.Reset(e.config.LatencySamples)
+	}
+	e.latency.Add(d)
+
+	min := minLatencySamples
+	if size := e.latency.Size(); size < min {
+		min = size
+	}
+	if n := e.latency.Len(); n == 0 || n < min {
+		return
+	}
+
+	e.state.Latency = e.latency.Percentile(e.config.LatencyPercentile)
+	if e.state.Latency > e.config.AcceptableCallDuration {
+		e.open(ErrUnresponsive, time.Now())
+		e.publishState()
+	}
+}
+
+// open opens the circuit, or keeps it open, and schedules the next connection
+// attempt according to the number of consecutive failures. Recorded call
+// durations are discarded.
+//
+// The caller must hold a write lock on the endpoint during the function call.
+func (e *Endpoint) open(cause error, when time.Time) {
+	e.state.Failures++
+	e.state.Retry = when.Add(e.config.backoff(e.state.Failures))
+	e.state.Latency = 0
+	e.latency.Reset(e.config.LatencySamples)
+	e.transition(BreakerOpen, cause, when)
+}
+
+// transition changes the state of the circuit breaker. If the state differs
+// from the previous state an event is broadcast to listeners.
+//
+// The caller must hold a write lock on the endpoint during the function call.
+func (e *Endpoint) transition(to BreakerState, cause error, when time.Time) {
+	from := e.state.Breaker
+	e.state.Breaker, e.state.Cause = to, cause
+
+	switch to {
+	case BreakerClosed:
+		e.state.Failures = 0
+		e.state.Retry = time.Time{}
+	case BreakerHalfOpen:
+		e.state.Retry = time.Time{}
+		e.reset = when
+	}
+
+	if from == to {
+		return
+	}
+
+	event := BreakerEvent{
+		Server:   e.fqdn,
+		Time:     when,
+		From:     from,
+		To:       to,
+		Cause:    cause,
+		Failures: e.state.Failures,
+		Retry:    e.state.Retry,
+	}
+	e.bc.Send(event)
+	if e.relay != nil {
+		e.relay.Send(event)
 	}
 }
 
+// endProbe marks the completion of the probe in progress, if there is one,
+// and releases the callers that are waiting for it.
+//
+// The caller must hold a write lock on the endpoint during the function call.
+func (e *Endpoint) endProbe() {
+	if !e.probing {
+		return
+	}
+	e.probing = false
+	close(e.probeDone)
+}
+
+// publishState sends the current state of the endpoint to run().
+//
+// The caller must hold a write lock on the endpoint during the function call.
+func (e *Endpoint) publishState() {
+	if e.state.Closed() {
+		return
+	}
+	e.stateChange <- e.state
+}
+
 // updateHealth will update the endpoint's health state as provided by
 // its call tracker.
 func (e *Endpoint) updateHealth(update calltracker.Update) {
@@ -435,14 +715,23 @@ func (e *Endpoint) updateHealth(update calltracker.Update) {
 	e.stateChange <- e.state
 }
 
-func createEndpointConnection(fqdn string, config EndpointConfig) (r Reporter, timestamp time.Time, err error) {
+func createEndpointConnection(fqdn string, config EndpointConfig, record func(time.Duration)) (r Reporter, timestamp time.Time, err error) {
 	timestamp = time.Now()
 
-	r, err = NewReporter(fqdn)
+	factory := config.Factory
+	if factory == nil {
+		factory = NewReporter
+	}
+
+	r, err = factory(fqdn)
 	if err != nil {
 		return
 	}
 
+	// Measure calls beneath the limiter and cacher so that time spent waiting
+	// for a worker and cached results are excluded
+	r = newTimer(r, record)
+
 	if config.Limiting {
 		rep := r
 		r, err = NewLimiter(r, config.Limit)
@@ -459,14 +748,12 @@ func createEndpointConnection(fqdn string, config EndpointConfig) (r Reporter, t
 	return
 }
 
-func resetConnectionTimer(t *time.Timer, online bool, config *EndpointConfig, connTimestamp time.Time) {
-	var interval time.Duration
-	if online {
-		interval = config.OnlineReconnectionInterval
-	} else {
-		interval = config.OfflineReconnectionInterval
+func resetConnectionTimer(t *time.Timer, state *EndpointState, config *EndpointConfig, connTimestamp time.Time) {
+	if state.Breaker == BreakerOpen {
+		resetActiveTimer(t, state.Retry.Sub(time.Now()))
+		return
 	}
-	d := connTimestamp.Add(interval).Sub(time.Now())
+	d := connTimestamp.Add(config.OnlineReconnectionInterval).Sub(time.Now())
 	resetActiveTimer(t, d)
 }
 
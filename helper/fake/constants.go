@@ -0,0 +1,30 @@
+package fake
+
+import "errors"
+
+var (
+	// ErrUnavailable is returned by simulated members that have been marked
+	// unavailable. Its message matches the one returned by the system so that
+	// helper.IsUnavailableErr recognizes it.
+	ErrUnavailable = errors.New("The RPC server is unavailable.")
+
+	// ErrClosed is returned from calls to a simulated reporter in the event
+	// that the Close() function has already been called.
+	ErrClosed = errors.New("simulated reporter is closing or already closed")
+
+	// ErrGroupNotFound is returned when a simulated member is asked about a
+	// replication group that it does not host.
+	ErrGroupNotFound = errors.New("the replication group is not hosted by the member")
+
+	// ErrUnknownVector is returned when a simulated member is provided with a
+	// version vector that was not issued by a member of the same fleet.
+	ErrUnknownVector = errors.New("the version vector was not issued by the fleet")
+
+	// ErrVectorRequired is returned when a backlog report is requested from a
+	// simulated member without a reference version vector.
+	ErrVectorRequired = errors.New("backlog reports require that a reference member vector is provided")
+
+	// ErrUnknownMember is returned when a reporter is requested for a member
+	// that has not been added to the fleet.
+	ErrUnknownMember = errors.New("the member is not present in the fleet")
+)
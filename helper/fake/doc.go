@@ -0,0 +1,13 @@
+// Package fake provides an in-memory simulation of DFSR members that implement
+// the DFSR Helper protocol.
+//
+// A Fleet of simulated members can be plugged into a helper.Client by way of
+// the Factory field of helper.EndpointConfig. This allows the endpoint state
+// machine and the backlog pipeline to be exercised on systems that lack access
+// to the DFSR Helper protocol server, such as non-windows build servers.
+//
+// The behavior of each member is scriptable. Members can be configured to
+// return particular version vectors, backlog counts and reports, to respond
+// slowly, to hang indefinitely or to behave as though their RPC server is
+// unavailable.
+package fake
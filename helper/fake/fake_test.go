@@ -0,0 +1,228 @@
+package fake_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/helper/fake"
+	"gopkg.in/dfsr.v0/helper/report"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+var (
+	groupID  = uuid.MustParse("3c6a1f4e-5b2d-4e8a-9f10-2b7c8d9e0a11")
+	folderID = uuid.MustParse("7d2e9b10-4c3a-4f5e-8a61-0c1d2e3f4a52")
+	otherID  = uuid.MustParse("9a8b7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c63")
+)
+
+// fullReport is a report of FS1 that includes the backlog to two partners and
+// the backlogged files.
+const fullReport = `<?xml version="1.0" encoding="utf-16"?>
+<ServerReport generated="2017-03-01T12:00:00Z">
+  <Server name="FS1" domain="EXAMPLE" dnsName="fs1.example.com" serviceState="Running" serviceStarted="2017-03-01T09:30:00Z" />
+  <ReplicationGroup name="Example" guid="{3c6a1f4e-5b2d-4e8a-9f10-2b7c8d9e0a11}">
+    <ReplicatedFolder name="Data" guid="{7d2e9b10-4c3a-4f5e-8a61-0c1d2e3f4a52}" path="D:\Data" state="4">
+      <Backlog partner="fs2.example.com" count="2">
+        <File name="a.txt" path="D:\Data\a.txt" uid="{5f1e2d3c-4b5a-4c6d-8e7f-9a0b1c2d3e74}-v1" />
+        <File name="b.txt" path="D:\Data\b.txt" uid="{5f1e2d3c-4b5a-4c6d-8e7f-9a0b1c2d3e74}-v2" />
+      </Backlog>
+      <Backlog partner="FS3" count="9" />
+    </ReplicatedFolder>
+  </ReplicationGroup>
+</ServerReport>`
+
+func newClient(t *testing.T, fleet *fake.Fleet) *helper.Client {
+	t.Helper()
+	config := fleet.Config(helper.DefaultEndpointConfig)
+	config.Caching = false // Vectors are scripted between calls
+	c := helper.NewClientWithConfig(config)
+	t.Cleanup(c.Close)
+	return c
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestBacklog(t *testing.T) {
+	fleet := fake.NewFleet()
+	fs1, fs2 := fleet.Add("FS1.example.com"), fleet.Add("fs2.example.com")
+	fs1.SetBacklog(groupID, "fs2.example.com", 5, 7)
+	fs2.Host(groupID, 2)
+
+	c := newClient(t, fleet)
+	ctx := testContext(t)
+
+	backlog, _, err := c.Backlog(ctx, "fs1.example.com", "fs2.example.com", groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(backlog, []int{5, 7}) {
+		t.Errorf("backlog is %v, want [5 7]", backlog)
+	}
+
+	backlog, _, err = c.Backlog(ctx, "fs2.example.com", "fs1.example.com", groupID)
+	if err != nil || !reflect.DeepEqual(backlog, []int{0, 0}) {
+		t.Errorf("reverse backlog returned %v, %v", backlog, err)
+	}
+
+	if stats := fs1.Stats(); stats.Backlog != 1 || stats.Vector != 1 {
+		t.Errorf("unexpected stats for FS1 %+v", stats)
+	}
+
+	if _, _, err = c.Backlog(ctx, "fs1.example.com", "fs2.example.com", otherID); err != fake.ErrGroupNotFound {
+		t.Errorf("backlog of an unhosted group returned %v", err)
+	}
+}
+
+func TestSetVector(t *testing.T) {
+	fleet := fake.NewFleet()
+	fs1, fs2 := fleet.Add("fs1.example.com"), fleet.Add("fs2.example.com")
+	fs1.SetBacklog(groupID, "fs2.example.com", 3)
+	fs2.Host(groupID, 1)
+
+	scripted := versionvector.New(versionvector.Folder{Entries: []versionvector.Entry{
+		{Database: uuid.MustParse("11111111-1111-4111-8111-111111111111"), Low: 1, High: 500},
+		{Database: uuid.MustParse("22222222-2222-4222-8222-222222222222"), Low: 1, High: 42},
+	}})
+	fs2.SetVector(groupID, scripted)
+
+	c := newClient(t, fleet)
+	ctx := testContext(t)
+
+	vector, _, err := c.Vector(ctx, "fs2.example.com", groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !vector.Equal(scripted) {
+		t.Fatalf("vector is %+v, want %+v", vector, scripted)
+	}
+
+	// Changes to the returned vector must not affect the scripted one
+	vector.Folders[0].Entries[0].High = 1
+	if again, _, _ := c.Vector(ctx, "fs2.example.com", groupID); !again.Equal(scripted) {
+		t.Error("scripted vector was modified by the caller")
+	}
+
+	// The scripted vector is recognized as a reference vector
+	backlog, _, err := c.Backlog(ctx, "fs1.example.com", "fs2.example.com", groupID)
+	if err != nil || !reflect.DeepEqual(backlog, []int{3}) {
+		t.Errorf("backlog against a scripted vector returned %v, %v", backlog, err)
+	}
+
+	fs2.SetVector(groupID, nil)
+	vector, _, err = c.Vector(ctx, "fs2.example.com", groupID)
+	if err != nil || vector.Equal(scripted) || len(vector.Folders) != 1 {
+		t.Errorf("vector was not restored: %+v, %v", vector, err)
+	}
+}
+
+func TestReportFlags(t *testing.T) {
+	fleet := fake.NewFleet()
+	fs1, fs2 := fleet.Add("fs1.example.com"), fleet.Add("fs2.example.com")
+	fs1.Host(groupID, 1)
+	fs1.SetReport(groupID, fullReport)
+	fs2.Host(groupID, 1)
+
+	c := newClient(t, fleet)
+	ctx := testContext(t)
+
+	reference, _, err := c.Vector(ctx, "fs2.example.com", groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backlogs := func(data string) []report.Backlog {
+		t.Helper()
+		r, err := report.ParseString(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, ok := r.Group(groupID)
+		if !ok {
+			t.Fatal("report does not include the group")
+		}
+		f, ok := g.Folder(folderID)
+		if !ok {
+			t.Fatal("report does not include the folder")
+		}
+		return f.Backlogs
+	}
+
+	_, data, _, err := c.Report(ctx, "fs1.example.com", groupID, nil, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := backlogs(data); len(b) != 0 {
+		t.Errorf("report without backlog lists backlogs %+v", b)
+	}
+
+	_, data, _, err = c.Report(ctx, "fs1.example.com", groupID, reference, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := backlogs(data)
+	if len(b) != 1 || b[0].Partner != "fs2.example.com" || b[0].Count != 2 || len(b[0].Files) != 0 {
+		t.Errorf("report without files lists backlogs %+v", b)
+	}
+
+	member, data, _, err := c.Report(ctx, "fs1.example.com", groupID, reference, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b = backlogs(data); len(b) != 1 || len(b[0].Files) != 2 {
+		t.Errorf("report with files lists backlogs %+v", b)
+	}
+	if member == nil || len(member.Folders) != 1 {
+		t.Errorf("report returned member vector %+v", member)
+	}
+
+	files, _, err := c.BacklogFiles(ctx, "fs1.example.com", "fs2.example.com", groupID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := files[folderID]; len(list) != 2 || list[0].Name != "a.txt" {
+		t.Errorf("backlog files are %+v", files)
+	}
+
+	if _, _, _, err = c.Report(ctx, "fs1.example.com", groupID, nil, true, false); err != fake.ErrVectorRequired {
+		t.Errorf("backlog report without a vector returned %v", err)
+	}
+	if _, _, _, err = c.Report(ctx, "fs1.example.com", groupID, versionvector.New(versionvector.Folder{}), true, false); err != fake.ErrUnknownVector {
+		t.Errorf("backlog report with a foreign vector returned %v", err)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	fleet := fake.NewFleet()
+	fs1 := fleet.Add("fs1.example.com")
+	fs1.Host(groupID, 1)
+	fs1.SetUnavailable(true)
+
+	if _, err := fleet.NewReporter("fs1.example.com"); !helper.IsUnavailableErr(err) {
+		t.Errorf("reporter for an unavailable member returned %v", err)
+	}
+	if _, err := fleet.NewReporter("fs9.example.com"); err != fake.ErrUnknownMember {
+		t.Errorf("reporter for an unknown member returned %v", err)
+	}
+
+	fs1.SetUnavailable(false)
+	r, err := fleet.NewReporter("FS1.EXAMPLE.COM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, _, err = r.Vector(context.Background(), groupID, nil); err != fake.ErrClosed {
+		t.Errorf("vector call on a closed reporter returned %v", err)
+	}
+	if !strings.EqualFold(fs1.FQDN(), "fs1.example.com") {
+		t.Errorf("member has FQDN %s", fs1.FQDN())
+	}
+}
@@ -0,0 +1,156 @@
+package fake
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+// origin identifies the member and replication group that issued a version
+// vector.
+type origin struct {
+	member string
+	group  uuid.UUID
+}
+
+// Fleet is a threadsafe collection of simulated DFSR members.
+//
+// The zero value of a fleet is not suitable for use. Fleets should be created
+// with a call to NewFleet().
+type Fleet struct {
+	mutex    sync.RWMutex
+	members  map[string]*Member               // Maps lower-case FQDNs to members
+	origins  map[uuid.UUID]origin             // Maps database GUIDs to their origins
+	scripted map[origin]*versionvector.Vector // Vectors provided by SetVector
+}
+
+// NewFleet returns a new fleet without any members.
+func NewFleet() *Fleet {
+	return &Fleet{
+		members:  make(map[string]*Member),
+		origins:  make(map[uuid.UUID]origin),
+		scripted: make(map[origin]*versionvector.Vector),
+	}
+}
+
+// Add adds a simulated member with the given fully qualified domain name to
+// the fleet and returns it. If the member is already present in the fleet the
+// existing member is returned.
+func (f *Fleet) Add(fqdn string) *Member {
+	fqdn = strings.ToLower(fqdn)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if m, found := f.members[fqdn]; found {
+		return m
+	}
+
+	m := newMember(f, fqdn)
+	f.members[fqdn] = m
+	return m
+}
+
+// Member returns the simulated member with the given fully qualified domain
+// name. If the member is not present in the fleet ok will be false.
+func (f *Fleet) Member(fqdn string) (m *Member, ok bool) {
+	f.mutex.RLock()
+	m, ok = f.members[strings.ToLower(fqdn)]
+	f.mutex.RUnlock()
+	return
+}
+
+// NewReporter creates a new reporter for the simulated member with the given
+// fully qualified domain name. It satisfies the helper.ReporterFactory
+// function signature.
+//
+// If the member is not present in the fleet ErrUnknownMember will be returned.
+// If the member is unavailable ErrUnavailable will be returned.
+func (f *Fleet) NewReporter(server string) (helper.Reporter, error) {
+	m, ok := f.Member(server)
+	if !ok {
+		return nil, ErrUnknownMember
+	}
+	r, err := m.connect()
+	if err != nil {
+		return nil, err // Avoid returning a typed nil
+	}
+	return r, nil
+}
+
+// Config returns a copy of the given endpoint configuration that creates its
+// reporters from the fleet.
+func (f *Fleet) Config(config helper.EndpointConfig) helper.EndpointConfig {
+	config.Factory = f.NewReporter
+	return config
+}
+
+// issue returns a version vector for the given member and replication group
+// with the given number of replicated folders and records its origin.
+//
+// If a vector has been scripted for the member and group a copy of it is
+// returned. Otherwise each folder vector holds a single entry for a database
+// GUID that is derived from the member and group, which allows the origin of
+// the vector to be recovered from its content alone.
+func (f *Fleet) issue(member string, group uuid.UUID, folders int) *versionvector.Vector {
+	db := uuid.NewSHA1(group, []byte(member))
+
+	f.mutex.Lock()
+	f.origins[db] = origin{member: member, group: group}
+	scripted := f.scripted[origin{member: member, group: group}]
+	f.mutex.Unlock()
+
+	if scripted != nil {
+		return scripted.Duplicate()
+	}
+
+	vector := versionvector.New(make([]versionvector.Folder, folders)...)
+	for i := range vector.Folders {
+		vector.Folders[i].Entries = []versionvector.Entry{{Database: db, Low: 1, High: 1}}
+	}
+	return vector
+}
+
+// script records a vector to be issued for the given member and replication
+// group. A nil vector removes the scripted vector.
+func (f *Fleet) script(member string, group uuid.UUID, vector *versionvector.Vector) {
+	o := origin{member: member, group: group}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if vector == nil {
+		delete(f.scripted, o)
+		return
+	}
+	f.scripted[o] = vector.Duplicate()
+}
+
+// origin returns the origin of the given vector. If the vector was not issued
+// by the fleet ok will be false.
+func (f *Fleet) origin(vector *versionvector.Vector) (o origin, ok bool) {
+	if vector == nil {
+		return
+	}
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for o, scripted := range f.scripted {
+		if scripted.Equal(vector) {
+			return o, true
+		}
+	}
+
+	for _, folder := range vector.Folders {
+		for _, entry := range folder.Entries {
+			if o, ok = f.origins[entry.Database]; ok {
+				return
+			}
+		}
+	}
+	return
+}
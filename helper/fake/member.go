@@ -0,0 +1,267 @@
+package fake
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+// Stats holds the number of connections and calls received by a simulated
+// member.
+type Stats struct {
+	Connections int // Number of reporters that have been created
+	Vector      int // Number of version vector calls
+	Backlog     int // Number of backlog calls
+	Report      int // Number of report calls
+}
+
+// group holds the scripted state of a replication group on a simulated member.
+type group struct {
+	folders int
+	backlog map[string][]int // Maps lower-case FQDNs of reference members to backlog counts
+	report  string
+}
+
+// Member is a simulated DFSR member. Its behavior can be scripted by calling
+// its methods at any time, including while calls are in progress.
+//
+// Members should be created by calling Add on a fleet.
+type Member struct {
+	fqdn  string
+	fleet *Fleet
+
+	mutex       sync.RWMutex
+	unavailable bool
+	latency     time.Duration
+	hang        chan struct{} // Non-nil while calls are hung, closed on release
+	groups      map[uuid.UUID]*group
+	stats       Stats
+}
+
+func newMember(fleet *Fleet, fqdn string) *Member {
+	return &Member{
+		fqdn:   fqdn,
+		fleet:  fleet,
+		groups: make(map[uuid.UUID]*group),
+	}
+}
+
+// FQDN returns the fully qualified domain name of the member in lower case.
+func (m *Member) FQDN() string {
+	return m.fqdn
+}
+
+// Host causes the member to host the given replication group with the given
+// number of replicated folders. If the member already hosts the group its
+// folder count is updated and its backlog counts are discarded.
+func (m *Member) Host(groupID uuid.UUID, folders int) {
+	m.mutex.Lock()
+	m.groups[groupID] = &group{
+		folders: folders,
+		backlog: make(map[string][]int),
+	}
+	m.mutex.Unlock()
+}
+
+// Unhost causes the member to stop hosting the given replication group.
+func (m *Member) Unhost(groupID uuid.UUID) {
+	m.mutex.Lock()
+	delete(m.groups, groupID)
+	m.mutex.Unlock()
+}
+
+// SetBacklog sets the outgoing backlog counts of the member when compared
+// against the version vector of the given reference member. One count should
+// be provided for each replicated folder in the group.
+//
+// If the member does not already host the group it will begin to host it
+// with one replicated folder per count.
+func (m *Member) SetBacklog(groupID uuid.UUID, to string, backlog ...int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	g, found := m.groups[groupID]
+	if !found {
+		g = &group{
+			folders: len(backlog),
+			backlog: make(map[string][]int),
+		}
+		m.groups[groupID] = g
+	}
+	g.backlog[strings.ToLower(to)] = append([]int(nil), backlog...)
+}
+
+// SetVector sets the version vector that will be returned by the member for
+// the given replication group, in place of the vector that is derived from
+// the member and group. The vector should hold one folder vector for each
+// replicated folder in the group. A nil vector restores the derived vector.
+//
+// Scripted vectors are also recognized when they are provided as reference
+// vectors to other members of the fleet, so each member should be given a
+// distinct vector. SetVector does not affect the backlog counts of the member.
+func (m *Member) SetVector(groupID uuid.UUID, vector *versionvector.Vector) {
+	m.fleet.script(m.fqdn, groupID, vector)
+}
+
+// SetReport sets the report that will be returned by the member for the given
+// replication group. If the member does not host the group SetReport does
+// nothing.
+//
+// The report should be a complete report that includes the backlog of the
+// member and the backlogged files. Backlog elements are removed from it when
+// the backlog is not requested, or when they are for a partner other than the
+// member that issued the reference vector. File elements are removed when
+// files are not requested.
+func (m *Member) SetReport(groupID uuid.UUID, report string) {
+	m.mutex.Lock()
+	if g, found := m.groups[groupID]; found {
+		g.report = report
+	}
+	m.mutex.Unlock()
+}
+
+// SetUnavailable determines whether the member behaves as though its RPC server
+// is unavailable. While unavailable all connection attempts and calls will
+// fail with ErrUnavailable.
+func (m *Member) SetUnavailable(unavailable bool) {
+	m.mutex.Lock()
+	m.unavailable = unavailable
+	m.mutex.Unlock()
+}
+
+// SetLatency sets the amount of time that each call to the member will take.
+func (m *Member) SetLatency(latency time.Duration) {
+	m.mutex.Lock()
+	m.latency = latency
+	m.mutex.Unlock()
+}
+
+// Hang causes all calls to the member to block until Release is called. Calls
+// that are already in progress are not affected.
+//
+// Hung calls behave like remote procedure calls that neither succeed nor fail.
+// Callers with cancelled contexts will be released but the underlying call
+// will remain outstanding until Release is called.
+func (m *Member) Hang() {
+	m.mutex.Lock()
+	if m.hang == nil {
+		m.hang = make(chan struct{})
+	}
+	m.mutex.Unlock()
+}
+
+// Release unblocks all calls that are hung.
+func (m *Member) Release() {
+	m.mutex.Lock()
+	if m.hang != nil {
+		close(m.hang)
+		m.hang = nil
+	}
+	m.mutex.Unlock()
+}
+
+// Stats returns the number of connections and calls received by the member.
+func (m *Member) Stats() (stats Stats) {
+	m.mutex.RLock()
+	stats = m.stats
+	m.mutex.RUnlock()
+	return
+}
+
+// connect returns a new reporter for the member.
+func (m *Member) connect() (*reporter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.unavailable {
+		return nil, ErrUnavailable
+	}
+
+	m.stats.Connections++
+	return &reporter{member: m}, nil
+}
+
+// wait simulates the latency of a remote procedure call. It blocks while the
+// member is hung and then for the duration of the member's latency.
+//
+// wait does not respond to cancellation, just like the remote procedure calls
+// that it simulates.
+func (m *Member) wait() {
+	m.mutex.RLock()
+	hang, latency := m.hang, m.latency
+	m.mutex.RUnlock()
+
+	if hang != nil {
+		<-hang
+	}
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+// vector returns the number of folders in the given replication group.
+func (m *Member) vector(groupID uuid.UUID) (folders int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats.Vector++
+
+	if m.unavailable {
+		return 0, ErrUnavailable
+	}
+
+	g, found := m.groups[groupID]
+	if !found {
+		return 0, ErrGroupNotFound
+	}
+
+	return g.folders, nil
+}
+
+// backlog returns the backlog of the given replication group when compared
+// against the given reference member.
+func (m *Member) backlog(groupID uuid.UUID, to string) (backlog []int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats.Backlog++
+
+	if m.unavailable {
+		return nil, ErrUnavailable
+	}
+
+	g, found := m.groups[groupID]
+	if !found {
+		return nil, ErrGroupNotFound
+	}
+
+	if values, found := g.backlog[to]; found {
+		return append([]int(nil), values...), nil
+	}
+
+	return make([]int, g.folders), nil
+}
+
+// report returns the report and number of folders for the given replication
+// group.
+func (m *Member) report(groupID uuid.UUID) (report string, folders int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats.Report++
+
+	if m.unavailable {
+		return "", 0, ErrUnavailable
+	}
+
+	g, found := m.groups[groupID]
+	if !found {
+		return "", 0, ErrGroupNotFound
+	}
+
+	return g.report, g.folders, nil
+}
@@ -0,0 +1,47 @@
+package fake
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	backlogElement = regexp.MustCompile(`(?s)[ \t]*<Backlog\b[^>]*?(?:/>|>.*?</Backlog>)[ \t]*\r?\n?`)
+	fileElement    = regexp.MustCompile(`(?s)[ \t]*<File\b[^>]*?(?:/>|>.*?</File>)[ \t]*\r?\n?`)
+	partnerAttr    = regexp.MustCompile(`\bpartner="([^"]*)"`)
+)
+
+// filterReport removes the content of a scripted report that a member would
+// not have included in response to a report request with the given flags.
+//
+// Backlog elements are removed unless backlog is true. When they are kept,
+// only the elements for the given partner, identified by its fully qualified
+// domain name or its short name, are retained. File elements are removed
+// unless files is true.
+func filterReport(report, partner string, backlog, files bool) string {
+	report = backlogElement.ReplaceAllStringFunc(report, func(element string) string {
+		if !backlog || !isPartner(element, partner) {
+			return ""
+		}
+		return element
+	})
+	if !files {
+		report = fileElement.ReplaceAllString(report, "")
+	}
+	return report
+}
+
+// isPartner returns true if the partner attribute of the given backlog element
+// identifies partner, or if the element lacks a partner attribute.
+func isPartner(element, partner string) bool {
+	m := partnerAttr.FindStringSubmatch(element)
+	if m == nil || m[1] == "" {
+		return true
+	}
+
+	short := partner
+	if dot := strings.IndexByte(partner, '.'); dot >= 0 {
+		short = partner[:dot]
+	}
+	return strings.EqualFold(m[1], partner) || strings.EqualFold(m[1], short)
+}
@@ -0,0 +1,205 @@
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+var _ = (helper.Reporter)((*reporter)(nil)) // Compile-time interface compliance check
+
+type vectorResult struct {
+	vector *versionvector.Vector
+	err    error
+}
+
+type backlogResult struct {
+	backlog []int
+	err     error
+}
+
+type reportResult struct {
+	member *versionvector.Vector
+	report string
+	err    error
+}
+
+// reporter provides a simulated implementation of the helper.Reporter
+// interface for a member.
+//
+// Like its real counterpart, reporter serializes calls and abandons calls
+// whose contexts are cancelled while leaving the simulated remote procedure
+// call running in its own goroutine.
+type reporter struct {
+	m      sync.Mutex
+	member *Member
+	closed bool
+}
+
+// Close marks the reporter as closed.
+func (r *reporter) Close() {
+	r.m.Lock()
+	r.closed = true
+	r.m.Unlock()
+}
+
+// Vector returns a version vector for the requested replication group.
+func (r *reporter) Vector(ctx context.Context, group uuid.UUID, tracker dfsr.Tracker) (vector *versionvector.Vector, call callstat.Call, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	call.Begin("Fake.Vector")
+	defer call.Complete(err)
+
+	if err = r.check(ctx); err != nil {
+		return
+	}
+
+	ch := make(chan vectorResult, 1)
+	go func() {
+		defer close(ch)
+		if tracker != nil {
+			tc := tracker.Add()
+			defer tc.Done()
+		}
+
+		r.member.wait()
+
+		folders, err := r.member.vector(group)
+		if err != nil {
+			ch <- vectorResult{err: err}
+			return
+		}
+
+		ch <- vectorResult{vector: r.member.fleet.issue(r.member.fqdn, group, folders)}
+	}()
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case result := <-ch:
+		vector, err = result.vector, result.err
+	}
+
+	return
+}
+
+// Backlog returns the scripted backlog of the member when compared against
+// the given reference version vector.
+func (r *reporter) Backlog(ctx context.Context, vector *versionvector.Vector, tracker dfsr.Tracker) (backlog []int, call callstat.Call, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	call.Begin("Fake.Backlog")
+	defer call.Complete(err)
+
+	if err = r.check(ctx); err != nil {
+		return
+	}
+
+	o, ok := r.member.fleet.origin(vector)
+	if !ok {
+		err = ErrUnknownVector
+		return
+	}
+
+	ch := make(chan backlogResult, 1)
+	go func() {
+		defer close(ch)
+		if tracker != nil {
+			tc := tracker.Add()
+			defer tc.Done()
+		}
+
+		r.member.wait()
+
+		backlog, err := r.member.backlog(o.group, o.member)
+		ch <- backlogResult{backlog: backlog, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case result := <-ch:
+		backlog, err = result.backlog, result.err
+	}
+
+	return
+}
+
+// Report returns the scripted report of the member for the given replication
+// group along with the version vector of the member.
+//
+// The report is filtered according to the backlog and files flags. When the
+// backlog is requested the reference vector must have been issued by a member
+// of the fleet, and only the backlog for that member is reported.
+func (r *reporter) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	call.Begin("Fake.Report")
+	defer call.Complete(err)
+
+	if err = r.check(ctx); err != nil {
+		return
+	}
+
+	var partner string
+	if backlog {
+		if vector == nil {
+			err = ErrVectorRequired
+			return
+		}
+		o, ok := r.member.fleet.origin(vector)
+		if !ok {
+			err = ErrUnknownVector
+			return
+		}
+		partner = o.member
+	}
+
+	ch := make(chan reportResult, 1)
+	go func() {
+		defer close(ch)
+
+		r.member.wait()
+
+		report, folders, err := r.member.report(group)
+		if err != nil {
+			ch <- reportResult{err: err}
+			return
+		}
+
+		ch <- reportResult{
+			member: r.member.fleet.issue(r.member.fqdn, group, folders),
+			report: filterReport(report, partner, backlog, files),
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case result := <-ch:
+		member, report, err = result.member, result.report, result.err
+	}
+
+	return
+}
+
+// check returns an error if the reporter is closed or the context has been
+// cancelled. The caller must hold a lock on the reporter.
+func (r *reporter) check(ctx context.Context) error {
+	if r.closed {
+		return ErrClosed
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package helper
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+var _ = (Reporter)((*timer)(nil)) // Compile-time interface compliance check
+
+// timer provides an implementation of the Reporter interface that measures the
+// duration of vector and backlog calls made through an underlying Reporter.
+//
+// The duration of each call that runs to completion is passed to record.
+// Calls that are abandoned because their context was cancelled are not
+// recorded, as they remain outstanding in the endpoint's call tracker.
+// Calls that fail because the server is unavailable are not recorded either.
+type timer struct {
+	r      Reporter
+	record func(time.Duration)
+}
+
+func newTimer(r Reporter, record func(time.Duration)) Reporter {
+	return &timer{
+		r:      r,
+		record: record,
+	}
+}
+
+func (t *timer) Vector(ctx context.Context, group uuid.UUID, tracker dfsr.Tracker) (vector *versionvector.Vector, call callstat.Call, err error) {
+	vector, call, err = t.r.Vector(ctx, group, tracker)
+	t.observe(ctx, &call, err)
+	return
+}
+
+func (t *timer) Backlog(ctx context.Context, vector *versionvector.Vector, tracker dfsr.Tracker) (backlog []int, call callstat.Call, err error) {
+	backlog, call, err = t.r.Backlog(ctx, vector, tracker)
+	t.observe(ctx, &call, err)
+	return
+}
+
+func (t *timer) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
+	return t.r.Report(ctx, group, vector, backlog, files)
+}
+
+func (t *timer) Close() {
+	t.r.Close()
+}
+
+func (t *timer) observe(ctx context.Context, call *callstat.Call, err error) {
+	if ctx.Err() != nil || IsUnavailableErr(err) {
+		return
+	}
+	t.record(call.Duration())
+}
+
+// latencyWindow holds the durations of the most recent calls made to an
+// endpoint.
+//
+// The zero value of a latency window holds no samples and discards all
+// samples that are added to it until it is reset with a nonzero size.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int // Index of the next sample to overwrite
+	full    bool
+}
+
+// Reset discards all samples and changes the size of the window.
+func (w *latencyWindow) Reset(size int) {
+	if size < 0 {
+		size = 0
+	}
+	if cap(w.samples) >= size {
+		w.samples = w.samples[:size]
+	} else {
+		w.samples = make([]time.Duration, size)
+	}
+	w.next = 0
+	w.full = false
+}
+
+// Size returns the maximum number of samples held by the window.
+func (w *latencyWindow) Size() int {
+	return len(w.samples)
+}
+
+// Len returns the number of samples in the window.
+func (w *latencyWindow) Len() int {
+	if w.full {
+		return len(w.samples)
+	}
+	return w.next
+}
+
+// Add adds a sample to the window, replacing the oldest sample if the window
+// is full.
+func (w *latencyWindow) Add(d time.Duration) {
+	if len(w.samples) == 0 {
+		return
+	}
+	w.samples[w.next] = d
+	w.next++
+	if w.next == len(w.samples) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// Percentile returns the sample at the given percentile of the window, which
+// must be between 0 and 1. It uses the nearest-rank method. If the window is
+// empty it returns zero.
+func (w *latencyWindow) Percentile(p float64) time.Duration {
+	n := w.Len()
+	if n == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(n))) - 1
+	switch {
+	case rank < 0:
+		rank = 0
+	case rank >= n:
+		rank = n - 1
+	}
+	return sorted[rank]
+}
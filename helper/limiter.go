@@ -3,7 +3,6 @@ package helper
 import (
 	"context"
 
-	"github.com/go-ole/go-ole"
 	"github.com/google/uuid"
 	"gopkg.in/dfsr.v0/callstat"
 	"gopkg.in/dfsr.v0/dfsr"
@@ -54,7 +53,7 @@ func (l *limiter) Backlog(ctx context.Context, vector *versionvector.Vector, tra
 	return l.r.Backlog(ctx, vector, tracker)
 }
 
-func (l *limiter) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error) {
+func (l *limiter) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
 	return l.r.Report(ctx, group, vector, backlog, files)
 }
 
@@ -0,0 +1,8 @@
+package report
+
+import "errors"
+
+var (
+	// ErrEmptyReport is returned when a report does not contain any data.
+	ErrEmptyReport = errors.New("the health report is empty")
+)
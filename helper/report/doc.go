@@ -0,0 +1,39 @@
+// Package report decodes the XML health reports generated by DFSR members.
+//
+// Reports are returned as strings by the Report function of helper.Reporter
+// and helper.Client, which call IServerHealthReport2::GetReport on the member.
+// The format of the report is not formally documented. The element and
+// attribute names used by this package have been inferred from reports
+// produced by members and may not cover every variation that members emit.
+// This package decodes the elements and attributes that are relevant to
+// monitoring and ignores the rest. A report has the following general form:
+//
+//   <ServerReport generated="2017-03-01T12:00:00Z">
+//     <Server name="FS1" domain="EXAMPLE" dnsName="fs1.example.com"
+//             osVersion="6.3.9600" serviceState="Running"
+//             serviceStarted="2017-02-20T08:15:00Z" />
+//     <ReplicationGroup name="Example" guid="{...}">
+//       <ReplicatedFolder name="Data" guid="{...}" path="D:\Data" state="4">
+//         <Staging path="D:\Data\DfsrPrivate\Staging" quotaMb="4096" usedMb="512" />
+//         <Conflict path="D:\Data\DfsrPrivate\ConflictAndDeleted" quotaMb="660" usedMb="12" />
+//         <Backlog partner="fs2.example.com" count="2">
+//           <File name="a.txt" path="D:\Data\a.txt" uid="{...}-v100" />
+//         </Backlog>
+//       </ReplicatedFolder>
+//     </ReplicationGroup>
+//     <Errors>
+//       <Error id="4012" time="2017-03-01T11:00:00Z" group="{...}" folder="{...}">...</Error>
+//     </Errors>
+//     <Warnings>
+//       <Warning id="4202" time="2017-03-01T10:00:00Z">...</Warning>
+//     </Warnings>
+//   </ServerReport>
+//
+// Backlog elements are only present when the report was requested with a
+// reference version vector, and File elements are only present when file
+// details were requested as well.
+//
+// Sample reports are provided in the testdata directory of this package. They
+// can be served by simulated members of the helper/fake package through
+// Member.SetReport, which allows consumers of reports to be exercised offline.
+package report
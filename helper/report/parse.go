@@ -0,0 +1,45 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Parse decodes a health report from r.
+func Parse(r io.Reader) (*Report, error) {
+	return parse(xml.NewDecoder(r))
+}
+
+// ParseString decodes a health report from a string, such as the report
+// returned by helper.Client.Report.
+//
+// Members declare their reports to be encoded as UTF-16. The declaration is
+// ignored because the report has already been converted to a Go string.
+func ParseString(report string) (*Report, error) {
+	if strings.TrimSpace(report) == "" {
+		return nil, ErrEmptyReport
+	}
+	d := xml.NewDecoder(strings.NewReader(report))
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return parse(d)
+}
+
+func parse(d *xml.Decoder) (*Report, error) {
+	var report Report
+	if err := d.Decode(&report); err != nil {
+		if err == io.EOF {
+			return nil, ErrEmptyReport
+		}
+		return nil, err
+	}
+	for e := range report.Errors {
+		report.Errors[e].Message = strings.TrimSpace(report.Errors[e].Message)
+	}
+	for w := range report.Warnings {
+		report.Warnings[w].Message = strings.TrimSpace(report.Warnings[w].Message)
+	}
+	return &report, nil
+}
@@ -0,0 +1,155 @@
+package report_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/helper/report"
+)
+
+var (
+	groupID    = uuid.MustParse("3c6a1f4e-5b2d-4e8a-9f10-2b7c8d9e0a11")
+	dataID     = uuid.MustParse("7d2e9b10-4c3a-4f5e-8a61-0c1d2e3f4a52")
+	profilesID = uuid.MustParse("9a8b7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c63")
+)
+
+func load(t *testing.T, name string) *report.Report {
+	t.Helper()
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := report.ParseString(string(data))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return r
+}
+
+func folder(t *testing.T, r *report.Report, id uuid.UUID) *report.Folder {
+	t.Helper()
+	group, ok := r.Group(groupID)
+	if !ok {
+		t.Fatalf("group %v not found", groupID)
+	}
+	f, ok := group.Folder(id)
+	if !ok {
+		t.Fatalf("folder %v not found", id)
+	}
+	return f
+}
+
+func TestParseNormal(t *testing.T) {
+	r := load(t, "testdata/normal.xml")
+
+	if r.Server.Name != "FS1" || r.Server.Host != "fs1.example.com" || r.Server.ServiceState != "Running" {
+		t.Errorf("unexpected server %+v", r.Server)
+	}
+	if got, want := r.Uptime(), 9*24*time.Hour+3*time.Hour+45*time.Minute; got != want {
+		t.Errorf("uptime is %v, want %v", got, want)
+	}
+
+	data := folder(t, r, dataID)
+	if data.Name != "Data" || data.Path != `D:\Data` {
+		t.Errorf("unexpected folder %s at %s", data.Name, data.Path)
+	}
+	if data.State != report.FolderNormal || !data.State.Healthy() {
+		t.Errorf("folder state is %v, want %v", data.State, report.FolderNormal)
+	}
+	if data.Staging.Quota != 4096 || data.Staging.Used != 512 || data.Staging.Percent() != 12.5 {
+		t.Errorf("unexpected staging usage %+v", data.Staging)
+	}
+	if data.Conflict.Quota != 660 || data.Conflict.Used != 12 {
+		t.Errorf("unexpected conflict usage %+v", data.Conflict)
+	}
+
+	if n := r.Backlog(); n != 0 {
+		t.Errorf("backlog is %d, want 0", n)
+	}
+	if len(r.Errors) != 0 || len(r.Warnings) != 0 {
+		t.Errorf("unexpected events: %v errors, %v warnings", len(r.Errors), len(r.Warnings))
+	}
+}
+
+func TestParseBacklog(t *testing.T) {
+	r := load(t, "testdata/backlog.xml")
+
+	if got, want := r.Uptime(), 2*time.Hour+30*time.Minute; got != want {
+		t.Errorf("uptime is %v, want %v", got, want)
+	}
+
+	data := folder(t, r, dataID)
+	if data.State != report.FolderInError || data.State.Healthy() {
+		t.Errorf("folder state is %v, want %v", data.State, report.FolderInError)
+	}
+	if data.Staging.Used != 3980 || data.Conflict.Used != 655 {
+		t.Errorf("unexpected usage: staging %+v, conflict %+v", data.Staging, data.Conflict)
+	}
+	if len(data.Backlogs) != 1 {
+		t.Fatalf("folder has %d backlogs, want 1", len(data.Backlogs))
+	}
+	backlog := data.Backlogs[0]
+	if backlog.Partner != "fs1.example.com" || backlog.Count != 1284 {
+		t.Errorf("unexpected backlog from %s of %d", backlog.Partner, backlog.Count)
+	}
+	if len(backlog.Files) != 3 {
+		t.Fatalf("backlog lists %d files, want 3", len(backlog.Files))
+	}
+	file := backlog.Files[0]
+	if file.Name != "budget.xlsx" || file.Path != `E:\Data\Finance\budget.xlsx` || !strings.HasSuffix(file.UID, "-v10421") {
+		t.Errorf("unexpected file %+v", file)
+	}
+
+	profiles := folder(t, r, profilesID)
+	if profiles.State != report.FolderInitialSync {
+		t.Errorf("folder state is %v, want %v", profiles.State, report.FolderInitialSync)
+	}
+	if len(profiles.Backlogs) != 1 || len(profiles.Backlogs[0].Files) != 0 {
+		t.Errorf("unexpected backlogs %+v", profiles.Backlogs)
+	}
+
+	if n := r.Backlog(); n != 1284+57 {
+		t.Errorf("backlog is %d, want %d", n, 1284+57)
+	}
+
+	if len(r.Errors) != 1 || len(r.Warnings) != 1 {
+		t.Fatalf("report has %d errors and %d warnings, want 1 of each", len(r.Errors), len(r.Warnings))
+	}
+	e := r.Errors[0]
+	if e.ID != 4012 || e.Group != groupID || e.Folder != dataID {
+		t.Errorf("unexpected error %+v", e)
+	}
+	if !e.Time.Equal(time.Date(2017, 3, 1, 11, 42, 0, 0, time.UTC)) {
+		t.Errorf("error time is %v", e.Time)
+	}
+	if !strings.HasPrefix(e.Message, "The DFS Replication service stopped replication") {
+		t.Errorf("error message is not trimmed: %q", e.Message)
+	}
+	if w := r.Warnings[0]; w.ID != 4202 || w.Folder != dataID {
+		t.Errorf("unexpected warning %+v", w)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	for _, s := range []string{"", "  \r\n"} {
+		if _, err := report.ParseString(s); err != report.ErrEmptyReport {
+			t.Errorf("ParseString(%q) returned %v, want %v", s, err, report.ErrEmptyReport)
+		}
+	}
+}
+
+func TestFolderStateText(t *testing.T) {
+	for _, text := range []string{"5", "In Error", "inerror"} {
+		var s report.FolderState
+		if err := s.UnmarshalText([]byte(text)); err != nil || s != report.FolderInError {
+			t.Errorf("UnmarshalText(%q) returned %v, %v", text, s, err)
+		}
+	}
+	var s report.FolderState
+	if err := s.UnmarshalText([]byte("bogus")); err == nil {
+		t.Error("UnmarshalText accepted an unknown state")
+	}
+}
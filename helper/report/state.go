@@ -0,0 +1,61 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FolderState is the replication state of a replicated folder on a member.
+type FolderState int
+
+// Replicated folder states, as used by the State property of the
+// DfsrReplicatedFolderInfo WMI class.
+const (
+	FolderUninitialized FolderState = 0
+	FolderInitialized   FolderState = 1
+	FolderInitialSync   FolderState = 2
+	FolderAutoRecovery  FolderState = 3
+	FolderNormal        FolderState = 4
+	FolderInError       FolderState = 5
+)
+
+var folderStateNames = []string{
+	FolderUninitialized: "Uninitialized",
+	FolderInitialized:   "Initialized",
+	FolderInitialSync:   "Initial Sync",
+	FolderAutoRecovery:  "Auto Recovery",
+	FolderNormal:        "Normal",
+	FolderInError:       "In Error",
+}
+
+// Healthy reports whether the folder is replicating normally.
+func (s FolderState) Healthy() bool {
+	return s == FolderNormal
+}
+
+// String returns a string representation of the folder state.
+func (s FolderState) String() string {
+	if s >= 0 && int(s) < len(folderStateNames) {
+		return folderStateNames[s]
+	}
+	return fmt.Sprintf("Unknown (%d)", int(s))
+}
+
+// UnmarshalText decodes a folder state from its numeric value or its name.
+// Names are matched without regard to case or spacing.
+func (s *FolderState) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if n, err := strconv.Atoi(value); err == nil {
+		*s = FolderState(n)
+		return nil
+	}
+	compact := strings.Replace(value, " ", "", -1)
+	for state, name := range folderStateNames {
+		if strings.EqualFold(strings.Replace(name, " ", "", -1), compact) {
+			*s = FolderState(state)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized replicated folder state \"%s\"", value)
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report is a decoded DFSR health report for a member.
+type Report struct {
+	XMLName   xml.Name  `xml:"ServerReport"`
+	Generated time.Time `xml:"generated,attr"`
+	Server    Server    `xml:"Server"`
+	Groups    []Group   `xml:"ReplicationGroup"`
+	Errors    []Event   `xml:"Errors>Error"`
+	Warnings  []Event   `xml:"Warnings>Warning"`
+}
+
+// Uptime returns the length of time that the DFSR service had been running
+// when the report was generated. It returns zero if the service start time or
+// report generation time are not known.
+func (r *Report) Uptime() time.Duration {
+	if r.Generated.IsZero() || r.Server.ServiceStarted.IsZero() {
+		return 0
+	}
+	return r.Generated.Sub(r.Server.ServiceStarted)
+}
+
+// Group returns the replication group with the given ID.
+func (r *Report) Group(id uuid.UUID) (group *Group, ok bool) {
+	for g := range r.Groups {
+		if r.Groups[g].ID == id {
+			return &r.Groups[g], true
+		}
+	}
+	return nil, false
+}
+
+// Backlog returns the total number of backlogged files across all folders of
+// all groups in the report.
+func (r *Report) Backlog() (total int) {
+	for g := range r.Groups {
+		total += r.Groups[g].Backlog()
+	}
+	return
+}
+
+// Server describes the member that generated a report and the state of its
+// DFSR service.
+type Server struct {
+	Name           string    `xml:"name,attr"`
+	Domain         string    `xml:"domain,attr"`
+	Host           string    `xml:"dnsName,attr"`
+	OSVersion      string    `xml:"osVersion,attr"`
+	ServiceState   string    `xml:"serviceState,attr"`
+	ServiceStarted time.Time `xml:"serviceStarted,attr"`
+}
+
+// Group holds the state of a replication group on a member.
+type Group struct {
+	Name    string    `xml:"name,attr"`
+	ID      uuid.UUID `xml:"guid,attr"`
+	Folders []Folder  `xml:"ReplicatedFolder"`
+}
+
+// Folder returns the replicated folder with the given ID.
+func (g *Group) Folder(id uuid.UUID) (folder *Folder, ok bool) {
+	for f := range g.Folders {
+		if g.Folders[f].ID == id {
+			return &g.Folders[f], true
+		}
+	}
+	return nil, false
+}
+
+// Backlog returns the total number of backlogged files across all folders of
+// the group.
+func (g *Group) Backlog() (total int) {
+	for f := range g.Folders {
+		total += g.Folders[f].Backlog()
+	}
+	return
+}
+
+// Folder holds the state of a replicated folder on a member.
+type Folder struct {
+	Name     string      `xml:"name,attr"`
+	ID       uuid.UUID   `xml:"guid,attr"`
+	Path     string      `xml:"path,attr"`
+	State    FolderState `xml:"state,attr"`
+	Staging  Usage       `xml:"Staging"`
+	Conflict Usage       `xml:"Conflict"`
+	Backlogs []Backlog   `xml:"Backlog"`
+}
+
+// Backlog returns the total number of backlogged files for the folder across
+// all partners.
+func (f *Folder) Backlog() (total int) {
+	for _, b := range f.Backlogs {
+		total += b.Count
+	}
+	return
+}
+
+// Usage describes the space consumed by a staging or conflict and deleted
+// folder.
+type Usage struct {
+	Path  string `xml:"path,attr"`
+	Quota int    `xml:"quotaMb,attr"` // Quota in megabytes
+	Used  int    `xml:"usedMb,attr"`  // Space used in megabytes
+}
+
+// Percent returns the space used as a percentage of the quota. It returns
+// zero if the quota is not known.
+func (u Usage) Percent() float64 {
+	if u.Quota <= 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Quota) * 100
+}
+
+// Backlog describes the files that are backlogged for a replication partner.
+//
+// Count is the number of backlogged files reported by the member. Files may
+// hold fewer entries than Count, as members only list a limited number of
+// files.
+type Backlog struct {
+	Partner string `xml:"partner,attr"`
+	Count   int    `xml:"count,attr"`
+	Files   []File `xml:"File"`
+}
+
+// File describes a backlogged file.
+type File struct {
+	Name string `xml:"name,attr"`
+	Path string `xml:"path,attr"`
+	UID  string `xml:"uid,attr"` // Unique identifier of the file version
+}
+
+// Event is an error or warning reported by a member. Group and Folder are
+// the nil UUID when the event is not specific to a replication group or
+// folder.
+type Event struct {
+	ID      int       `xml:"id,attr"`
+	Time    time.Time `xml:"time,attr"`
+	Group   uuid.UUID `xml:"group,attr"`
+	Folder  uuid.UUID `xml:"folder,attr"`
+	Message string    `xml:",chardata"`
+}
@@ -32,9 +32,15 @@ type Reporter interface {
 	Close()
 	Vector(ctx context.Context, group uuid.UUID, tracker dfsr.Tracker) (vector *versionvector.Vector, call callstat.Call, err error)
 	Backlog(ctx context.Context, vector *versionvector.Vector, tracker dfsr.Tracker) (backlog []int, call callstat.Call, err error)
-	Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error)
+	Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error)
 }
 
+// ReporterFactory is a function that creates a new Reporter for the server
+// with the given fully qualified domain name.
+type ReporterFactory func(server string) (Reporter, error)
+
+var _ = (ReporterFactory)(NewReporter) // Compile-time function signature check
+
 var _ = (Reporter)((*reporter)(nil)) // Compile-time interface compliance check
 
 // reporter provides access to the system API for DFSR health reports.
@@ -155,8 +161,9 @@ func (r *reporter) vector(group uuid.UUID, tracker dfsr.Tracker) <-chan vectorRe
 			ch <- vectorResult{err: err}
 			return
 		}
+		defer sa.Release()
 
-		vector, err := versionvector.New(sa)
+		vector, err := makeVector(sa)
 		ch <- vectorResult{vector: vector, err: err}
 	}()
 	return ch
@@ -231,8 +238,15 @@ func (r *reporter) backlog(vector *versionvector.Vector, tracker dfsr.Tracker) <
 			defer tc.Done()
 		}
 
+		vsa, err := makeSafeArray(vector)
+		if err != nil {
+			ch <- backlogResult{err: err}
+			return
+		}
+		defer vsa.Release()
+
 		// TODO: Check dimensions of the returned vectors for sanity
-		sa, err := r.iface.GetReferenceBacklogCounts(vector.Data())
+		sa, err := r.iface.GetReferenceBacklogCounts(vsa)
 		if err != nil {
 			ch <- backlogResult{err: err}
 			return
@@ -245,7 +259,9 @@ func (r *reporter) backlog(vector *versionvector.Vector, tracker dfsr.Tracker) <
 }
 
 // Report generates a report when compared against the reference version vector.
-func (r *reporter) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (data *ole.SafeArrayConversion, report string, call callstat.Call, err error) {
+//
+// The version vector of the member is returned along with the report.
+func (r *reporter) Report(ctx context.Context, group uuid.UUID, vector *versionvector.Vector, backlog, files bool) (member *versionvector.Vector, report string, call callstat.Call, err error) {
 	if backlog && vector == nil {
 		call.Description = "Reporter.Report"
 		err = errors.New("backlog reports require that a reference member vector is provided")
@@ -281,10 +297,20 @@ func (r *reporter) Report(ctx context.Context, group uuid.UUID, vector *versionv
 
 	var vdata *ole.SafeArrayConversion
 	if backlog {
-		vdata = vector.Data()
+		vdata, err = makeSafeArray(vector)
+		if err != nil {
+			return
+		}
+		defer vdata.Release()
 	}
 
 	// TODO: Check dimensions of the returned backlog for sanity
-	data, report, err = r.iface.GetReport(group, "", vdata, int32(flags))
+	data, report, err := r.iface.GetReport(group, "", vdata, int32(flags))
+	if err != nil {
+		return
+	}
+	defer data.Release()
+
+	member, err = makeVector(data)
 	return
 }
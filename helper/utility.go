@@ -6,8 +6,28 @@ import (
 	"strings"
 
 	"github.com/go-ole/go-ole"
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper/api"
+	"gopkg.in/dfsr.v0/helper/report"
+	"gopkg.in/dfsr.v0/versionvector"
 )
 
+// makeVector decodes the version vector data contained in sa.
+func makeVector(sa *ole.SafeArrayConversion) (vector *versionvector.Vector, err error) {
+	data, err := api.DecodeVersionVectors(sa)
+	if err != nil {
+		return
+	}
+	return versionvector.Decode(data)
+}
+
+// makeSafeArray encodes vector as a safe array. It is the caller's
+// responsibility to release the returned safe array.
+func makeSafeArray(vector *versionvector.Vector) (sa *ole.SafeArrayConversion, err error) {
+	return api.EncodeVersionVectors(vector.Encode())
+}
+
 func makeBacklog(sa *ole.SafeArrayConversion) (backlog []int) {
 	values := sa.ToValueArray()
 
@@ -32,3 +52,45 @@ func IsUnavailableErr(err error) bool {
 	}
 	return strings.Contains(err.Error(), "The RPC server is unavailable")
 }
+
+// backlogFiles collects the backlogged files listed in r for the given
+// replication group and partner, with no more than limit files per folder.
+// Backlogs that don't name a partner are assumed to belong to the partner.
+func backlogFiles(r *report.Report, group uuid.UUID, partner string, limit int) map[uuid.UUID][]dfsr.BacklogFile {
+	files := make(map[uuid.UUID][]dfsr.BacklogFile)
+
+	g, ok := r.Group(group)
+	if !ok {
+		return files
+	}
+
+	short := partner
+	if dot := strings.IndexByte(partner, '.'); dot >= 0 {
+		short = partner[:dot]
+	}
+
+	for f := range g.Folders {
+		folder := &g.Folders[f]
+		var list []dfsr.BacklogFile
+		for _, backlog := range folder.Backlogs {
+			if backlog.Partner != "" && !strings.EqualFold(backlog.Partner, partner) && !strings.EqualFold(backlog.Partner, short) {
+				continue
+			}
+			for _, file := range backlog.Files {
+				if limit > 0 && len(list) >= limit {
+					break
+				}
+				list = append(list, dfsr.BacklogFile{
+					Name: file.Name,
+					Path: file.Path,
+					UID:  file.UID,
+				})
+			}
+		}
+		if len(list) > 0 {
+			files[folder.ID] = list
+		}
+	}
+
+	return files
+}
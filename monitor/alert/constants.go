@@ -0,0 +1,13 @@
+package alert
+
+import "errors"
+
+var (
+	// ErrIncomplete is the sample error for backlog queries that did not
+	// return a valid backlog for every replicated folder.
+	ErrIncomplete = errors.New("backlog query did not return values for all replicated folders")
+
+	// ErrRemoved is the event error for alerts that were resolved because
+	// their connection is no longer present in the domain configuration.
+	ErrRemoved = errors.New("connection is no longer present in the domain configuration")
+)
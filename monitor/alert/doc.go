@@ -0,0 +1,15 @@
+// Package alert provides stateful evaluation of alerting rules against DFSR
+// monitor updates.
+//
+// An Engine consumes the updates broadcast by a monitor and keeps per-connection
+// state for each of its rules across polls. When the condition of a rule is
+// met for a connection the engine emits a firing event, and when the condition
+// clears it emits a resolved event. Rules apply hysteresis so that a
+// connection hovering around a threshold does not produce a flood of events.
+//
+// The following rules are provided:
+//
+//   Threshold  backlog above a value for longer than a duration
+//   Failure    backlog queries failing for a number of consecutive updates
+//   Growth     backlog growing across a number of consecutive updates
+package alert
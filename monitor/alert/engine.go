@@ -0,0 +1,211 @@
+package alert
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/fanout"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+)
+
+// stateKey identifies the state of a rule for a connection.
+type stateKey struct {
+	rule int // Index of the rule within the engine
+	conn Connection
+}
+
+// state holds the state of a rule for a connection.
+type state struct {
+	evaluator Evaluator
+	firing    bool
+	since     time.Time // Time at which the alert started firing
+	last      Sample    // Most recent sample
+}
+
+// Engine evaluates a set of alerting rules against DFSR monitor updates and
+// broadcasts the resulting events to its listeners.
+//
+// The zero value of an engine is not suitable for use. Engines should be
+// created with a call to New().
+type Engine struct {
+	rules []Rule
+
+	bc fanout.Broadcaster // Broadcasts alert events
+
+	mutex  sync.Mutex // Serializes evaluation
+	states map[stateKey]*state
+}
+
+// New returns a new alerting engine for the given rules.
+func New(rules ...Rule) *Engine {
+	return &Engine{
+		rules:  append([]Rule(nil), rules...),
+		states: make(map[stateKey]*state),
+	}
+}
+
+// Consume causes the engine to evaluate the updates received on the given
+// channel in its own goroutine. When the channel is closed the engine will be
+// closed as well.
+func (e *Engine) Consume(updates <-chan *monitor.Update) {
+	consumerutil.Consume(updates, func(update *monitor.Update) {
+		e.EvaluateUpdate(update)
+	}, e.Close)
+}
+
+// Close closes the channels of all listeners. Further evaluation will not
+// produce events.
+func (e *Engine) Close() {
+	e.bc.Close()
+}
+
+// Listen returns a channel on which alert events will be broadcast. The
+// channel will be closed when the engine is closed or when unlisten is called
+// for the returned channel.
+//
+// The returned channel will use the provided channel buffer size. Evaluation
+// never blocks on a listener. Events that do not fit in a listener's buffer
+// are discarded for that listener and counted in the statistics returned by
+// Stats, so listeners that must not miss alerts should be given a buffer that
+// can absorb a burst of events.
+func (e *Engine) Listen(chanSize int) <-chan Event {
+	ch := make(chan Event, chanSize)
+	e.bc.Add(ch)
+	return ch
+}
+
+// Unlisten closes the given listener's channel and removes it from the set of
+// listeners that receive alert events.
+//
+// Unlisten returns false if the listener was not present.
+func (e *Engine) Unlisten(ch <-chan Event) (found bool) {
+	return e.bc.Remove(ch)
+}
+
+// Stats returns statistics about the delivery of alert events to listeners.
+func (e *Engine) Stats() fanout.Stats {
+	return e.bc.Stats()
+}
+
+// EvaluateUpdate evaluates all of the backlog values in update as they are
+// received and returns the resulting events. It blocks until the update has
+// finished.
+//
+// If every value in the update was received, alerts for connections that were
+// not part of the update are resolved with an error of ErrRemoved and their
+// state is discarded.
+func (e *Engine) EvaluateUpdate(update *monitor.Update) (events []Event) {
+	seen := make(map[Connection]bool, update.Size())
+	for backlog := range update.Listen() {
+		seen[makeConnection(backlog)] = true
+		events = append(events, e.Evaluate(backlog)...)
+	}
+
+	if len(seen) < update.Size() {
+		// The update was canceled or contained duplicate connections
+		return
+	}
+
+	return append(events, e.prune(seen)...)
+}
+
+// Evaluate evaluates a single backlog value against all applicable rules and
+// returns the resulting events.
+func (e *Engine) Evaluate(backlog *dfsr.Backlog) (events []Event) {
+	conn := makeConnection(backlog)
+	sample := makeSample(backlog)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for i, rule := range e.rules {
+		if !rule.Applies(conn) {
+			continue
+		}
+
+		key := stateKey{rule: i, conn: conn}
+		s, found := e.states[key]
+		if !found {
+			s = &state{evaluator: rule.NewEvaluator()}
+			e.states[key] = s
+		}
+		s.last = sample
+
+		firing := s.evaluator.Evaluate(sample, s.firing)
+		if firing == s.firing {
+			continue
+		}
+
+		s.firing = firing
+		kind := Resolved
+		if firing {
+			kind = Firing
+			s.since = sample.Time
+		}
+		events = append(events, e.event(kind, rule, conn, s))
+	}
+
+	for _, event := range events {
+		e.bc.Send(event)
+	}
+	return
+}
+
+// Active returns a firing event for each alert that is currently firing,
+// ordered by the time at which they started firing.
+func (e *Engine) Active() (events []Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for key, s := range e.states {
+		if s.firing {
+			events = append(events, e.event(Firing, e.rules[key.rule], key.conn, s))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Since.Before(events[j].Since)
+	})
+	return
+}
+
+// prune discards the state of connections that are not present in seen and
+// returns resolved events for any of them that were firing.
+func (e *Engine) prune(seen map[Connection]bool) (events []Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for key, s := range e.states {
+		if seen[key.conn] {
+			continue
+		}
+		if s.firing {
+			event := e.event(Resolved, e.rules[key.rule], key.conn, s)
+			event.Time = time.Now()
+			event.Err = ErrRemoved
+			events = append(events, event)
+		}
+		delete(e.states, key)
+	}
+
+	for _, event := range events {
+		e.bc.Send(event)
+	}
+	return
+}
+
+// event returns an event for the given state. The caller must hold a lock on
+// the engine.
+func (e *Engine) event(kind Kind, rule Rule, conn Connection, s *state) Event {
+	return Event{
+		Kind:       kind,
+		Rule:       rule.Name(),
+		Connection: conn,
+		Since:      s.since,
+		Time:       s.last.Time,
+		Backlog:    s.last.Backlog,
+		Err:        s.last.Err,
+	}
+}
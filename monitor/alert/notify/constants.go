@@ -0,0 +1,18 @@
+package notify
+
+import "errors"
+
+// queueSize is the number of messages that may be waiting for delivery on a
+// route before further messages are dropped.
+const queueSize = 64
+
+var (
+	// ErrClosed is returned when a dispatcher has been closed.
+	ErrClosed = errors.New("notification dispatcher is closed")
+
+	// ErrNoRecipients is returned when an SMTP notifier has no recipients.
+	ErrNoRecipients = errors.New("no email recipients were provided")
+
+	// ErrUnknownFormat is returned when a webhook format is not recognized.
+	ErrUnknownFormat = errors.New("unknown webhook format")
+)
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"sync"
+
+	"gopkg.in/dfsr.v0/monitor/alert"
+)
+
+// Dispatcher delivers alert events to a set of routes.
+//
+// Each route delivers its messages in its own goroutine, so a slow or
+// unavailable destination does not delay the others or the alert engine.
+//
+// The zero value of a dispatcher is not suitable for use. Dispatchers should
+// be created with a call to New().
+type Dispatcher struct {
+	routes []*route
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// New returns a new dispatcher for the given routes.
+func New(routes ...Route) *Dispatcher {
+	d := &Dispatcher{
+		stop: make(chan struct{}),
+	}
+	for _, config := range routes {
+		r := newRoute(config, d.stop)
+		d.routes = append(d.routes, r)
+		d.wg.Add(1)
+		go r.run(&d.wg)
+	}
+	return d
+}
+
+// Consume causes the dispatcher to deliver the events received on the given
+// channel in its own goroutine. When the channel is closed the dispatcher
+// will be closed as well.
+func (d *Dispatcher) Consume(events <-chan alert.Event) {
+	go func() {
+		defer d.Close()
+		for event := range events {
+			d.Dispatch(event)
+		}
+	}()
+}
+
+// Dispatch passes event to each route that matches it. It does not wait for
+// delivery.
+//
+// If the dispatcher has been closed ErrClosed will be returned.
+func (d *Dispatcher) Dispatch(event alert.Event) error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+
+	for _, r := range d.routes {
+		if r.matches(event) {
+			r.enqueue(event)
+		}
+	}
+	return nil
+}
+
+// Stats returns the number of events processed by each route.
+func (d *Dispatcher) Stats() (stats []RouteStats) {
+	for _, r := range d.routes {
+		stats = append(stats, r.snapshot())
+	}
+	return
+}
+
+// Close stops the dispatcher from accepting further events. Messages that are
+// already queued are attempted once without retries. Close blocks until
+// all routes have finished.
+func (d *Dispatcher) Close() {
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		d.wg.Wait()
+		return
+	}
+	d.closed = true
+	close(d.stop)
+	for _, r := range d.routes {
+		close(r.queue)
+	}
+	d.mutex.Unlock()
+
+	d.wg.Wait()
+}
@@ -0,0 +1,10 @@
+// Package notify delivers alert events to people by way of webhooks and email.
+//
+// A Dispatcher receives events from an alert engine and passes them to a set
+// of routes. Each route renders the event with its template, suppresses
+// duplicates, applies its own rate limit and hands the resulting message to
+// its notifier, retrying failed deliveries with exponential backoff.
+//
+// Notifiers are provided for generic JSON webhooks, Slack-compatible incoming
+// webhooks, the PagerDuty Events API v2 and SMTP.
+package notify
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+
+	"gopkg.in/dfsr.v0/monitor/alert"
+)
+
+// Notifier delivers messages to a destination.
+//
+// All implementations of the Notifier interface must be threadsafe.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Message is a rendered alert event that is ready for delivery.
+type Message struct {
+	Key     string // Deduplication key that is shared by related events
+	Subject string
+	Body    string
+	Event   alert.Event
+}
+
+// Key returns the deduplication key for an event. Firing and resolved events
+// for the same rule and connection share a key.
+func Key(event alert.Event) string {
+	c := event.Connection
+	return fmt.Sprintf("dfsr/%s/%s/%s/%s", event.Rule, c.Group, c.From, c.To)
+}
+
+// DefaultSubject is the default subject template.
+const DefaultSubject = `{{if eq .Kind.String "firing"}}FIRING{{else}}RESOLVED{{end}}: {{.Rule}} on {{.Connection.Group}} from {{.Connection.From}} to {{.Connection.To}}`
+
+// DefaultBody is the default body template.
+const DefaultBody = `Rule: {{.Rule}}
+Replication group: {{.Connection.Group}}
+From: {{.Connection.From}}
+To: {{.Connection.To}}
+Status: {{.Kind}}
+Backlog: {{.Backlog}}
+{{- if .Err}}
+Error: {{.Err}}
+{{- end}}
+Since: {{.Since.Format "2006-01-02 15:04:05 MST"}}
+Time: {{.Time.Format "2006-01-02 15:04:05 MST"}}
+`
+
+// Template renders alert events as messages. Both templates receive the
+// alert.Event being rendered as their data.
+type Template struct {
+	Subject *template.Template
+	Body    *template.Template
+}
+
+// NewTemplate parses the given subject and body templates. If subject or
+// body is empty DefaultSubject or DefaultBody is used in its place.
+func NewTemplate(subject, body string) (*Template, error) {
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	if body == "" {
+		body = DefaultBody
+	}
+
+	s, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+	b, err := template.New("body").Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{Subject: s, Body: b}, nil
+}
+
+// DefaultTemplate is the template used by routes that don't specify one.
+var DefaultTemplate = mustTemplate(NewTemplate("", ""))
+
+// Render returns a message for the given event.
+func (t *Template) Render(event alert.Event) (msg Message, err error) {
+	var buf bytes.Buffer
+	if err = t.Subject.Execute(&buf, event); err != nil {
+		return
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err = t.Body.Execute(&buf, event); err != nil {
+		return
+	}
+	msg.Body = buf.String()
+
+	msg.Key = Key(event)
+	msg.Event = event
+	return
+}
+
+func mustTemplate(t *Template, err error) *Template {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
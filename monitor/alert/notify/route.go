@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/monitor/alert"
+)
+
+// Route describes a destination for alert events and the policy for
+// delivering them.
+type Route struct {
+	Name     string
+	Notifier Notifier
+	Template *Template   // If nil, DefaultTemplate is used
+	Scope    alert.Scope // Connections whose events are delivered
+	Rules    []string    // Names of rules whose events are delivered, or all rules if empty
+
+	// Limit is the maximum number of firing notifications delivered within
+	// each Interval. Resolved notifications are not limited so that every
+	// delivered alert is eventually resolved. A Limit of zero disables rate
+	// limiting.
+	Limit    int
+	Interval time.Duration
+
+	Timeout       time.Duration // Maximum time allowed for each delivery attempt
+	Retries       int           // Number of times a failed delivery is retried
+	RetryDelay    time.Duration // Delay before the first retry, doubled for each subsequent retry
+	MaxRetryDelay time.Duration // Upper limit on the delay between retries
+
+	// OnError is called when a message could not be delivered after all
+	// retries have been exhausted. If nil, delivery failures are ignored.
+	OnError func(msg Message, err error)
+}
+
+// DefaultRoute holds the default delivery policy for routes. Its Notifier must
+// be provided before use.
+var DefaultRoute = Route{
+	Limit:         30,
+	Interval:      time.Hour,
+	Timeout:       30 * time.Second,
+	Retries:       3,
+	RetryDelay:    5 * time.Second,
+	MaxRetryDelay: 2 * time.Minute,
+}
+
+// RouteStats holds the number of events processed by a route.
+type RouteStats struct {
+	Name       string
+	Sent       uint64 // Messages delivered successfully
+	Failed     uint64 // Messages that could not be delivered
+	Duplicates uint64 // Events discarded because their state was already delivered
+	Limited    uint64 // Events discarded by the rate limit
+	Dropped    uint64 // Events discarded because the delivery queue was full
+}
+
+// route holds the state of a route within a dispatcher.
+type route struct {
+	config Route
+	queue  chan Message
+	stop   <-chan struct{}
+
+	mutex  sync.Mutex
+	firing map[string]bool // Keys of alerts whose firing notification was delivered
+	window []time.Time     // Times of recent firing notifications
+	stats  RouteStats
+}
+
+func newRoute(config Route, stop <-chan struct{}) *route {
+	if config.Template == nil {
+		config.Template = DefaultTemplate
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultRoute.Timeout
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultRoute.RetryDelay
+	}
+	if config.MaxRetryDelay < config.RetryDelay {
+		config.MaxRetryDelay = config.RetryDelay
+	}
+	return &route{
+		config: config,
+		queue:  make(chan Message, queueSize),
+		stop:   stop,
+		firing: make(map[string]bool),
+		stats:  RouteStats{Name: config.Name},
+	}
+}
+
+// matches returns true if event should be delivered by the route.
+func (r *route) matches(event alert.Event) bool {
+	if !r.config.Scope.Matches(event.Connection) {
+		return false
+	}
+	if len(r.config.Rules) == 0 {
+		return true
+	}
+	for _, name := range r.config.Rules {
+		if strings.EqualFold(name, event.Rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue applies deduplication and rate limiting to event, then renders it
+// and adds it to the delivery queue. It does not block.
+func (r *route) enqueue(event alert.Event) {
+	key := Key(event)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch event.Kind {
+	case alert.Firing:
+		if r.firing[key] {
+			r.stats.Duplicates++
+			return
+		}
+		if !r.allow() {
+			r.stats.Limited++
+			return
+		}
+	case alert.Resolved:
+		if !r.firing[key] {
+			// The firing notification was never delivered
+			r.stats.Duplicates++
+			return
+		}
+	}
+
+	msg, err := r.config.Template.Render(event)
+	if err != nil {
+		r.stats.Failed++
+		r.report(Message{Key: key, Event: event}, err)
+		return
+	}
+
+	select {
+	case r.queue <- msg:
+	default:
+		r.stats.Dropped++
+		return
+	}
+
+	if event.Kind == alert.Firing {
+		r.firing[key] = true
+		r.window = append(r.window, time.Now())
+	} else {
+		delete(r.firing, key)
+	}
+}
+
+// allow returns true if the rate limit permits another firing notification.
+// The caller must hold a lock on the route.
+func (r *route) allow() bool {
+	if r.config.Limit <= 0 {
+		return true
+	}
+
+	cutoff := time.Now().Add(-r.config.Interval)
+	i := 0
+	for i < len(r.window) && r.window[i].Before(cutoff) {
+		i++
+	}
+	r.window = r.window[i:]
+
+	return len(r.window) < r.config.Limit
+}
+
+// run delivers queued messages until the queue is closed.
+func (r *route) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for msg := range r.queue {
+		err := r.deliver(msg)
+
+		r.mutex.Lock()
+		if err != nil {
+			r.stats.Failed++
+		} else {
+			r.stats.Sent++
+		}
+		r.mutex.Unlock()
+
+		if err != nil {
+			r.report(msg, err)
+		}
+	}
+}
+
+// deliver sends msg to the route's notifier. Failed attempts are retried with
+// exponential backoff until the configured number of retries has been
+// exhausted or the dispatcher is closed.
+func (r *route) deliver(msg Message) (err error) {
+	delay := r.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+		err = r.config.Notifier.Notify(ctx, msg)
+		cancel()
+		if err == nil || attempt >= r.config.Retries {
+			return
+		}
+
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > r.config.MaxRetryDelay {
+			delay = r.config.MaxRetryDelay
+		}
+	}
+}
+
+func (r *route) report(msg Message, err error) {
+	if r.config.OnError != nil {
+		r.config.OnError(msg, err)
+	}
+}
+
+func (r *route) snapshot() (stats RouteStats) {
+	r.mutex.Lock()
+	stats = r.stats
+	r.mutex.Unlock()
+	return
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var _ = (Notifier)((*SMTP)(nil)) // Compile-time interface compliance check
+
+// SMTP is a notifier that sends messages as email.
+//
+// If Username is provided the notifier authenticates with PLAIN
+// authentication, which net/smtp only permits over TLS or to localhost.
+type SMTP struct {
+	Addr     string // Server address in host:port form
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+// Notify sends msg as an email to each of the notifier's recipients.
+//
+// The underlying SMTP conversation does not respond to cancellation, so a
+// cancelled context only causes Notify to stop waiting for it.
+func (s *SMTP) Notify(ctx context.Context, msg Message) error {
+	if len(s.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	data := s.compose(msg)
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- smtp.SendMail(s.Addr, auth, s.From, s.To, data)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-ch:
+		return err
+	}
+}
+
+// compose returns the RFC 5322 representation of msg.
+func (s *SMTP) compose(msg Message) []byte {
+	var b bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+	header("From", s.From)
+	header("To", strings.Join(s.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	header("X-DFSR-Alert-Key", msg.Key)
+	header("X-DFSR-Alert-Status", msg.Event.Kind.String())
+	b.WriteString("\r\n")
+
+	body := strings.Replace(msg.Body, "\r\n", "\n", -1)
+	body = strings.Replace(body, "\n", "\r\n", -1)
+	b.WriteString(body)
+	return b.Bytes()
+}
@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/dfsr.v0/monitor/alert"
+)
+
+var _ = (Notifier)((*Webhook)(nil)) // Compile-time interface compliance check
+
+// Format identifies the shape of the JSON payload sent to a webhook.
+type Format string
+
+// Webhook payload formats.
+const (
+	Generic   Format = "generic"   // The message and event fields
+	Slack     Format = "slack"     // Slack-compatible incoming webhook
+	PagerDuty Format = "pagerduty" // PagerDuty Events API v2
+)
+
+// PagerDutyURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// ParseFormat returns the webhook format with the given name, ignoring case.
+// An empty name returns Generic.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case "":
+		return Generic, nil
+	case Generic, Slack, PagerDuty:
+		return f, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// Webhook is a notifier that POSTs JSON payloads to a URL.
+type Webhook struct {
+	URL        string
+	Format     Format
+	RoutingKey string       // PagerDuty integration key, only used by the PagerDuty format
+	Source     string       // PagerDuty event source, defaults to the sending member
+	Client     *http.Client // If nil, http.DefaultClient is used
+}
+
+// Notify sends msg to the webhook.
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	payload, err := w.payload(msg)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) payload(msg Message) (interface{}, error) {
+	switch w.Format {
+	case Generic, "":
+		return genericPayload(msg), nil
+	case Slack:
+		return slackPayload{Text: "*" + msg.Subject + "*\n" + msg.Body}, nil
+	case PagerDuty:
+		return w.pagerDutyPayload(msg), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type genericMessage struct {
+	Key     string    `json:"key"`
+	Status  string    `json:"status"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	Rule    string    `json:"rule"`
+	Group   string    `json:"group"`
+	GroupID string    `json:"groupId"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Backlog uint      `json:"backlog"`
+	Error   string    `json:"error,omitempty"`
+	Since   time.Time `json:"since"`
+	Time    time.Time `json:"time"`
+}
+
+func genericPayload(msg Message) genericMessage {
+	e := msg.Event
+	return genericMessage{
+		Key:     msg.Key,
+		Status:  e.Kind.String(),
+		Subject: msg.Subject,
+		Body:    msg.Body,
+		Rule:    e.Rule,
+		Group:   e.Connection.Group,
+		GroupID: e.Connection.GroupID.String(),
+		From:    e.Connection.From,
+		To:      e.Connection.To,
+		Backlog: e.Backlog,
+		Error:   errString(e.Err),
+		Since:   e.Since,
+		Time:    e.Time,
+	}
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (w *Webhook) pagerDutyPayload(msg Message) pagerDutyEvent {
+	e := msg.Event
+	event := pagerDutyEvent{
+		RoutingKey:  w.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    msg.Key,
+	}
+	if e.Kind == alert.Resolved {
+		event.EventAction = "resolve"
+		return event
+	}
+
+	source := w.Source
+	if source == "" {
+		source = e.Connection.From
+	}
+
+	// PagerDuty limits summaries to 1024 characters
+	summary := msg.Subject
+	if len(summary) > 1024 {
+		summary = summary[:1024]
+	}
+
+	event.Payload = &pagerDutyPayload{
+		Summary:   summary,
+		Source:    source,
+		Severity:  "warning",
+		Timestamp: e.Time.UTC().Format(time.RFC3339),
+		Component: e.Connection.To,
+		Group:     e.Connection.Group,
+		Class:     e.Rule,
+		CustomDetails: map[string]string{
+			"body":    msg.Body,
+			"backlog": fmt.Sprintf("%d", e.Backlog),
+			"error":   errString(e.Err),
+		},
+	}
+	return event
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,51 @@
+package alert
+
+import "strings"
+
+// Rule describes an alerting condition.
+//
+// All implementations of the Rule interface must be threadsafe.
+type Rule interface {
+	// Name returns the name of the rule, which is included in its events.
+	Name() string
+
+	// Applies returns true if the rule should be evaluated for conn.
+	Applies(conn Connection) bool
+
+	// NewEvaluator returns a new evaluator that holds the state of the rule
+	// for a single connection.
+	NewEvaluator() Evaluator
+}
+
+// Evaluator holds the state of a rule for a single connection. Evaluators are
+// only used by one goroutine at a time and need not be threadsafe.
+type Evaluator interface {
+	// Evaluate updates the state of the evaluator with the given sample and
+	// returns true if the alert should be firing. The firing parameter
+	// indicates whether the alert is currently firing, which allows the
+	// evaluator to apply hysteresis.
+	Evaluate(s Sample, firing bool) bool
+}
+
+// Scope restricts a rule to a subset of connections. Each non-empty field must
+// match the corresponding field of a connection, ignoring case. The zero value
+// of a scope matches all connections.
+type Scope struct {
+	Group string // Replication group name
+	From  string // Fully qualified domain name of the sending member
+	To    string // Fully qualified domain name of the receiving member
+}
+
+// Matches returns true if conn falls within the scope.
+func (s Scope) Matches(conn Connection) bool {
+	if s.Group != "" && !strings.EqualFold(s.Group, conn.Group) {
+		return false
+	}
+	if s.From != "" && !strings.EqualFold(s.From, conn.From) {
+		return false
+	}
+	if s.To != "" && !strings.EqualFold(s.To, conn.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package alert
+
+import "time"
+
+var (
+	_ = (Rule)(Threshold{}) // Compile-time interface compliance check
+	_ = (Rule)(Failure{})   // Compile-time interface compliance check
+	_ = (Rule)(Growth{})    // Compile-time interface compliance check
+)
+
+// Threshold is a rule that fires when the backlog of a connection stays above
+// a value for longer than a duration.
+//
+// Once firing, the alert resolves when the backlog has stayed at or below
+// the Clear value for the ClearFor duration. Setting Clear below Above
+// provides hysteresis for backlogs that hover around the threshold.
+//
+// Failed queries neither trigger nor resolve the alert.
+type Threshold struct {
+	RuleName string
+	Scope    Scope
+	Above    uint          // Backlog above which the alert may fire
+	For      time.Duration // Time that the backlog must stay above the threshold
+	Clear    uint          // Backlog at or below which the alert may resolve
+	ClearFor time.Duration // Time that the backlog must stay at or below Clear
+}
+
+// Name returns the name of the rule.
+func (r Threshold) Name() string {
+	return r.RuleName
+}
+
+// Applies returns true if the rule should be evaluated for conn.
+func (r Threshold) Applies(conn Connection) bool {
+	return r.Scope.Matches(conn)
+}
+
+// NewEvaluator returns a new evaluator for the rule.
+func (r Threshold) NewEvaluator() Evaluator {
+	return &thresholdEvaluator{rule: r}
+}
+
+type thresholdEvaluator struct {
+	rule  Threshold
+	above time.Time // Time at which the backlog rose above the threshold
+	below time.Time // Time at which the backlog fell to or below Clear
+}
+
+func (e *thresholdEvaluator) Evaluate(s Sample, firing bool) bool {
+	if s.Err != nil {
+		return firing
+	}
+
+	if !firing {
+		if s.Backlog <= e.rule.Above {
+			e.above = time.Time{}
+			return false
+		}
+		if e.above.IsZero() {
+			e.above = s.Time
+		}
+		return s.Time.Sub(e.above) >= e.rule.For
+	}
+
+	if s.Backlog > e.rule.Clear {
+		e.below = time.Time{}
+		return true
+	}
+	if e.below.IsZero() {
+		e.below = s.Time
+	}
+	if s.Time.Sub(e.below) < e.rule.ClearFor {
+		return true
+	}
+	e.above, e.below = time.Time{}, time.Time{}
+	return false
+}
+
+// Failure is a rule that fires when backlog queries for a connection fail for
+// a number of consecutive updates.
+//
+// Once firing, the alert resolves when queries have succeeded for ClearCount
+// consecutive updates. Count and ClearCount are treated as 1 if they are
+// less than 1.
+type Failure struct {
+	RuleName   string
+	Scope      Scope
+	Count      int // Number of consecutive failures required to fire
+	ClearCount int // Number of consecutive successes required to resolve
+}
+
+// Name returns the name of the rule.
+func (r Failure) Name() string {
+	return r.RuleName
+}
+
+// Applies returns true if the rule should be evaluated for conn.
+func (r Failure) Applies(conn Connection) bool {
+	return r.Scope.Matches(conn)
+}
+
+// NewEvaluator returns a new evaluator for the rule.
+func (r Failure) NewEvaluator() Evaluator {
+	return &failureEvaluator{
+		count:      atLeastOne(r.Count),
+		clearCount: atLeastOne(r.ClearCount),
+	}
+}
+
+type failureEvaluator struct {
+	count      int
+	clearCount int
+	failures   int // Number of consecutive failures
+	successes  int // Number of consecutive successes
+}
+
+func (e *failureEvaluator) Evaluate(s Sample, firing bool) bool {
+	if s.Err != nil {
+		e.failures++
+		e.successes = 0
+	} else {
+		e.successes++
+		e.failures = 0
+	}
+
+	if !firing {
+		return e.failures >= e.count
+	}
+	return e.successes < e.clearCount
+}
+
+// Growth is a rule that fires when the backlog of a connection grows across a
+// number of consecutive updates.
+//
+// Once firing, the alert resolves when the backlog has not grown for
+// ClearUpdates consecutive updates, or when the backlog reaches zero. Updates
+// and ClearUpdates are treated as 1 if they are less than 1. Backlogs at or
+// below Min are not considered to be growing.
+//
+// Failed queries are ignored and do not interrupt a run of growing backlogs.
+type Growth struct {
+	RuleName     string
+	Scope        Scope
+	Updates      int  // Number of consecutive increases required to fire
+	ClearUpdates int  // Number of consecutive non-increases required to resolve
+	Min          uint // Backlog at or below which growth is ignored
+}
+
+// Name returns the name of the rule.
+func (r Growth) Name() string {
+	return r.RuleName
+}
+
+// Applies returns true if the rule should be evaluated for conn.
+func (r Growth) Applies(conn Connection) bool {
+	return r.Scope.Matches(conn)
+}
+
+// NewEvaluator returns a new evaluator for the rule.
+func (r Growth) NewEvaluator() Evaluator {
+	return &growthEvaluator{
+		updates:      atLeastOne(r.Updates),
+		clearUpdates: atLeastOne(r.ClearUpdates),
+		min:          r.Min,
+	}
+}
+
+type growthEvaluator struct {
+	updates      int
+	clearUpdates int
+	min          uint
+	started      bool // Has a previous backlog been recorded?
+	last         uint // Previous backlog
+	increases    int  // Number of consecutive increases
+	steady       int  // Number of consecutive non-increases
+}
+
+func (e *growthEvaluator) Evaluate(s Sample, firing bool) bool {
+	if s.Err != nil {
+		return firing
+	}
+
+	if e.started && s.Backlog > e.last && s.Backlog > e.min {
+		e.increases++
+		e.steady = 0
+	} else if e.started {
+		e.increases = 0
+		e.steady++
+	}
+	e.started = true
+	e.last = s.Backlog
+
+	if !firing {
+		return e.increases >= e.updates
+	}
+	if s.Backlog == 0 || e.steady >= e.clearUpdates {
+		e.increases, e.steady = 0, 0
+		return false
+	}
+	return true
+}
+
+func atLeastOne(v int) int {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
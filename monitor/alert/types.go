@@ -0,0 +1,105 @@
+package alert
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// Connection identifies a one-way connection between replication group
+// members. Member names are stored in lower case so that connections can be
+// compared and used as map keys.
+type Connection struct {
+	GroupID uuid.UUID
+	Group   string // Replication group name
+	From    string // Fully qualified domain name of the sending member
+	To      string // Fully qualified domain name of the receiving member
+}
+
+// makeConnection returns the connection described by backlog.
+func makeConnection(backlog *dfsr.Backlog) (conn Connection) {
+	if backlog.Group != nil {
+		conn.GroupID = backlog.Group.ID
+		conn.Group = backlog.Group.Name
+	}
+	conn.From = strings.ToLower(backlog.From)
+	conn.To = strings.ToLower(backlog.To)
+	return
+}
+
+// Sample is the result of a single backlog query for a connection.
+type Sample struct {
+	Time    time.Time // Time at which the query started
+	Backlog uint      // Total backlog of all replicated folders
+	Err     error     // Non-nil if the query failed
+}
+
+// makeSample returns a sample for backlog. Queries that did not return a
+// valid value for every replicated folder are given an error of ErrIncomplete
+// unless they already carry an error.
+func makeSample(backlog *dfsr.Backlog) Sample {
+	s := Sample{
+		Time:    backlog.Call.Start,
+		Backlog: backlog.Sum(),
+		Err:     backlog.Err,
+	}
+	if s.Time.IsZero() {
+		s.Time = time.Now()
+	}
+	if s.Err == nil && !complete(backlog) {
+		s.Err = ErrIncomplete
+	}
+	return s
+}
+
+func complete(backlog *dfsr.Backlog) bool {
+	if len(backlog.Folders) == 0 {
+		return false
+	}
+	for f := range backlog.Folders {
+		if backlog.Folders[f].Backlog < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind identifies the kind of an alert event.
+type Kind int
+
+// Alert event kinds.
+const (
+	Firing Kind = iota + 1
+	Resolved
+)
+
+// String returns a string representation of the event kind.
+func (k Kind) String() string {
+	switch k {
+	case Firing:
+		return "firing"
+	case Resolved:
+		return "resolved"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change in the state of an alert for a connection.
+type Event struct {
+	Kind       Kind
+	Rule       string     // Name of the rule that produced the event
+	Connection Connection // Connection that the alert applies to
+	Since      time.Time  // Time at which the alert started firing
+	Time       time.Time  // Time of the sample that produced the event
+	Backlog    uint       // Backlog of the sample that produced the event
+	Err        error      // Error of the sample that produced the event
+}
+
+// Duration returns the amount of time that the alert has been firing as of
+// the event.
+func (e *Event) Duration() time.Duration {
+	return e.Time.Sub(e.Since)
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"time"
+
+	"gopkg.in/dfsr.v0/fanout"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/poller"
+)
+
+// pollTrigger requests immediate updates from a poller, but no more than once
+// per polling interval.
+type pollTrigger struct {
+	poll     func()
+	interval time.Duration
+	last     time.Time
+}
+
+// Trigger calls poll unless it was already called within the last interval
+// before now. It returns true if poll was called.
+func (t *pollTrigger) Trigger(now time.Time) bool {
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	t.poll()
+	return true
+}
+
+// relayBreakerEvents forwards the circuit breaker events of a running
+// instance's client to bc until the client is closed.
+//
+// When the circuit of a member becomes half-open its connection has just been
+// reestablished, so an immediate update is requested from instance and health
+// instead of waiting for the next polling interval. This keeps members that
+// flap from missing entire polling cycles. Each update covers the whole
+// domain, so no more than one is requested per polling interval regardless of
+// how many members recover. If health is nil only instance is updated.
+func relayBreakerEvents(events <-chan helper.BreakerEvent, instance, health *poller.Poller, interval, healthInterval time.Duration, bc *fanout.Broadcaster) {
+	triggers := []*pollTrigger{{poll: instance.Poll, interval: interval}}
+	if health != nil {
+		triggers = append(triggers, &pollTrigger{poll: health.Poll, interval: healthInterval})
+	}
+
+	for event := range events {
+		if event.To == helper.BreakerHalfOpen {
+			now := time.Now()
+			for _, t := range triggers {
+				t.Trigger(now)
+			}
+		}
+		bc.Send(event)
+	}
+}
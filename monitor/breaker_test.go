@@ -0,0 +1,31 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollTrigger(t *testing.T) {
+	polls := 0
+	trigger := pollTrigger{poll: func() { polls++ }, interval: time.Minute}
+
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{0, true},
+		{time.Second, false},
+		{59 * time.Second, false},
+		{time.Minute, true},
+		{time.Minute + time.Second, false},
+		{3 * time.Minute, true},
+	} {
+		if got := trigger.Trigger(start.Add(tt.offset)); got != tt.want {
+			t.Errorf("Trigger at %v returned %t, want %t", tt.offset, got, tt.want)
+		}
+	}
+	if polls != 3 {
+		t.Errorf("poll was called %d times, want 3", polls)
+	}
+}
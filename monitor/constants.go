@@ -2,7 +2,10 @@ package monitor
 
 import "errors"
 
-const updateChanSize = 16
+const (
+	updateChanSize  = 16
+	breakerChanSize = 64 // Buffer size for circuit breaker events received from the client
+)
 
 var (
 	// ErrClosed is returned from calls to a service or interface in the event
@@ -0,0 +1,51 @@
+package consumerutil
+
+import (
+	"strings"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+// Reportable returns true if backlog holds a successful query result for every
+// replicated folder, and may therefore be reported to a metrics system.
+func Reportable(backlog *dfsr.Backlog) bool {
+	if backlog.Err != nil {
+		return false
+	}
+
+	if len(backlog.Folders) == 0 {
+		// Indicates replication group query error
+		return false
+	}
+
+	for f := range backlog.Folders {
+		if backlog.Folders[f].Backlog < 0 {
+			// Indicates per-folder query error
+			return false
+		}
+	}
+
+	return true
+}
+
+// NonFQDN returns the first label of the given fully qualified domain name in
+// upper case, which is typically the NetBIOS name of a member.
+func NonFQDN(fqdn string) string {
+	dot := strings.Index(fqdn, ".")
+	if dot < 1 {
+		return strings.ToUpper(fqdn)
+	}
+	return strings.ToUpper(fqdn[0:dot])
+}
+
+// Consume calls evaluate for each update received on updates in its own
+// goroutine. When the channel is closed done is called.
+func Consume(updates <-chan *monitor.Update, evaluate func(*monitor.Update), done func()) {
+	go func() {
+		defer done()
+		for update := range updates {
+			evaluate(update)
+		}
+	}()
+}
@@ -0,0 +1,3 @@
+// Package consumerutil provides helper functions shared by the consumers of
+// DFSR monitor updates.
+package consumerutil
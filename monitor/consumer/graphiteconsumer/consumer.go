@@ -0,0 +1,123 @@
+package graphiteconsumer
+
+import (
+	"fmt"
+	"strconv"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+	"gopkg.in/dfsr.v0/monitor/consumer/linesender"
+)
+
+// DefaultFormat is the default metric path format for connection backlogs.
+const DefaultFormat = "dfsr.%[1]s.%[4]s.%[5]s.backlog"
+
+// DefaultFolderFormat is the default metric path format for folder backlogs.
+const DefaultFolderFormat = "dfsr.%[1]s.%[4]s.%[5]s.folders.%[6]s.backlog"
+
+// Consumer represents a Graphite consumer of DFSR monitor backlog updates.
+type Consumer struct {
+	sender       *linesender.Sender
+	ch           <-chan *monitor.Update
+	format       string
+	folderFormat string
+}
+
+// New returns a new Graphite consumer of DFSR monitor backlog updates that
+// sends Graphite plaintext protocol lines to the destination described by
+// config. The returned consumer will function until the provided update
+// channel is closed, at which point any buffered lines will be flushed.
+//
+// Metric paths are produced by fmt-style format strings that receive the
+// following arguments:
+//
+//   %[1]s  replication group name
+//   %[2]s  fully qualified domain name of the sending member
+//   %[3]s  fully qualified domain name of the receiving member
+//   %[4]s  host name of the sending member
+//   %[5]s  host name of the receiving member
+//   %[6]s  replicated folder name
+//
+// Each argument is sanitized so that it forms a single Graphite path
+// component. If format is empty DefaultFormat is used. If folderFormat is
+// empty DefaultFolderFormat is used. If folderFormat is "-" per-folder metrics
+// are not sent.
+func New(config linesender.Config, format, folderFormat string, updates <-chan *monitor.Update) (*Consumer, error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+	if folderFormat == "" {
+		folderFormat = DefaultFolderFormat
+	}
+
+	sender, err := linesender.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Consumer{
+		sender:       sender,
+		ch:           updates,
+		format:       format,
+		folderFormat: folderFormat,
+	}
+	go c.run()
+	return c, nil
+}
+
+// Stats returns the number of lines processed by the consumer's sender.
+func (c *Consumer) Stats() linesender.Stats {
+	return c.sender.Stats()
+}
+
+func (c *Consumer) run() {
+	defer c.sender.Close()
+	for {
+		update, ok := <-c.ch
+		if !ok {
+			return
+		}
+		for backlog := range update.Listen() {
+			if !consumerutil.Reportable(backlog) {
+				continue
+			}
+			c.sender.Send(c.lines(backlog)...)
+		}
+	}
+}
+
+func (c *Consumer) lines(backlog *dfsr.Backlog) (lines [][]byte) {
+	var (
+		timestamp = strconv.FormatInt(backlog.Call.Start.Unix(), 10)
+		args      = pathArgs(backlog)
+	)
+
+	lines = append(lines, line(fmt.Sprintf(c.format, args...), uint64(backlog.Sum()), timestamp))
+
+	if c.folderFormat == "-" {
+		return
+	}
+
+	for _, folder := range backlog.Folders {
+		var name string
+		if folder.Folder != nil {
+			name = folder.Folder.Name
+		}
+		args[5] = sanitize(name)
+		lines = append(lines, line(fmt.Sprintf(c.folderFormat, args...), uint64(folder.Backlog), timestamp))
+	}
+
+	return
+}
+
+func line(path string, value uint64, timestamp string) []byte {
+	b := make([]byte, 0, len(path)+len(timestamp)+24)
+	b = append(b, path...)
+	b = append(b, ' ')
+	b = strconv.AppendUint(b, value, 10)
+	b = append(b, ' ')
+	b = append(b, timestamp...)
+	b = append(b, '\n')
+	return b
+}
@@ -0,0 +1,39 @@
+package graphiteconsumer
+
+import (
+	"strings"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+)
+
+// pathArgs returns the metric path format arguments for backlog. The folder
+// argument is left empty.
+func pathArgs(backlog *dfsr.Backlog) []interface{} {
+	var group string
+	if backlog.Group != nil {
+		group = backlog.Group.Name
+	}
+	return []interface{}{
+		sanitize(group),
+		sanitize(backlog.From),
+		sanitize(backlog.To),
+		sanitize(consumerutil.NonFQDN(backlog.From)),
+		sanitize(consumerutil.NonFQDN(backlog.To)),
+		"",
+	}
+}
+
+// sanitize replaces all characters in s that are not letters, digits,
+// hyphens or underscores with underscores, so that s can be used as a single
+// component of a Graphite metric path.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,151 @@
+package influxconsumer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+	"gopkg.in/dfsr.v0/monitor/consumer/linesender"
+)
+
+// DefaultMeasurement is the default measurement name.
+const DefaultMeasurement = "dfsr_backlog"
+
+// DefaultTags is the default set of tag formats.
+var DefaultTags = map[string]string{
+	"group":  "%[1]s",
+	"from":   "%[2]s",
+	"to":     "%[3]s",
+	"folder": "%[6]s",
+}
+
+// Consumer represents an InfluxDB consumer of DFSR monitor backlog updates.
+type Consumer struct {
+	sender      *linesender.Sender
+	ch          <-chan *monitor.Update
+	measurement string
+	tags        []tagFormat
+}
+
+type tagFormat struct {
+	key    string
+	format string
+}
+
+// New returns a new InfluxDB consumer of DFSR monitor backlog updates that
+// sends InfluxDB line protocol to the destination described by config. The
+// returned consumer will function until the provided update channel is
+// closed, at which point any buffered lines will be flushed.
+//
+// For each connection a point is written with a backlog field holding the
+// total backlog and a duration field holding the query duration in seconds.
+// A point with a backlog field is also written for each replicated folder.
+//
+// Tag values are produced by fmt-style format strings that receive the
+// following arguments:
+//
+//   %[1]s  replication group name
+//   %[2]s  fully qualified domain name of the sending member
+//   %[3]s  fully qualified domain name of the receiving member
+//   %[4]s  host name of the sending member
+//   %[5]s  host name of the receiving member
+//   %[6]s  replicated folder name, which is empty for connection points
+//
+// Tags with empty values are omitted. If measurement is empty
+// DefaultMeasurement is used. If tags is nil DefaultTags is used.
+func New(config linesender.Config, measurement string, tags map[string]string, updates <-chan *monitor.Update) (*Consumer, error) {
+	if measurement == "" {
+		measurement = DefaultMeasurement
+	}
+	if tags == nil {
+		tags = DefaultTags
+	}
+
+	sender, err := linesender.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Consumer{
+		sender:      sender,
+		ch:          updates,
+		measurement: escapeMeasurement(measurement),
+	}
+	for key, format := range tags {
+		c.tags = append(c.tags, tagFormat{key: key, format: format})
+	}
+	sort.Slice(c.tags, func(i, j int) bool {
+		return c.tags[i].key < c.tags[j].key
+	})
+
+	go c.run()
+	return c, nil
+}
+
+// Stats returns the number of lines processed by the consumer's sender.
+func (c *Consumer) Stats() linesender.Stats {
+	return c.sender.Stats()
+}
+
+func (c *Consumer) run() {
+	defer c.sender.Close()
+	for {
+		update, ok := <-c.ch
+		if !ok {
+			return
+		}
+		for backlog := range update.Listen() {
+			if !consumerutil.Reportable(backlog) {
+				continue
+			}
+			c.sender.Send(c.lines(backlog)...)
+		}
+	}
+}
+
+func (c *Consumer) lines(backlog *dfsr.Backlog) (lines [][]byte) {
+	var (
+		timestamp = strconv.FormatInt(backlog.Call.Start.UnixNano(), 10)
+		args      = tagArgs(backlog)
+	)
+
+	fields := "backlog=" + strconv.FormatUint(uint64(backlog.Sum()), 10) + "i" +
+		",duration=" + strconv.FormatFloat(backlog.Call.Duration().Seconds(), 'f', -1, 64)
+	lines = append(lines, c.line(args, fields, timestamp))
+
+	for _, folder := range backlog.Folders {
+		var name string
+		if folder.Folder != nil {
+			name = folder.Folder.Name
+		}
+		args[5] = name
+		fields := "backlog=" + strconv.Itoa(folder.Backlog) + "i"
+		lines = append(lines, c.line(args, fields, timestamp))
+	}
+
+	return
+}
+
+func (c *Consumer) line(args []interface{}, fields, timestamp string) []byte {
+	b := make([]byte, 0, 128)
+	b = append(b, c.measurement...)
+	for _, tag := range c.tags {
+		value := fmt.Sprintf(tag.format, args...)
+		if value == "" {
+			continue
+		}
+		b = append(b, ',')
+		b = append(b, escapeTag(tag.key)...)
+		b = append(b, '=')
+		b = append(b, escapeTag(value)...)
+	}
+	b = append(b, ' ')
+	b = append(b, fields...)
+	b = append(b, ' ')
+	b = append(b, timestamp...)
+	b = append(b, '\n')
+	return b
+}
@@ -0,0 +1,38 @@
+package influxconsumer
+
+import (
+	"strings"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
+
+// tagArgs returns the tag format arguments for backlog. The folder argument is
+// left empty.
+func tagArgs(backlog *dfsr.Backlog) []interface{} {
+	var group string
+	if backlog.Group != nil {
+		group = backlog.Group.Name
+	}
+	return []interface{}{
+		group,
+		backlog.From,
+		backlog.To,
+		consumerutil.NonFQDN(backlog.From),
+		consumerutil.NonFQDN(backlog.To),
+		"",
+	}
+}
+
+func escapeMeasurement(s string) string {
+	return measurementEscaper.Replace(s)
+}
+
+func escapeTag(s string) string {
+	return tagEscaper.Replace(s)
+}
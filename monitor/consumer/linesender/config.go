@@ -0,0 +1,107 @@
+package linesender
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config holds the configuration of a line sender.
+type Config struct {
+	// URL identifies the destination of the sender. Its scheme determines the
+	// transport that is used:
+	//
+	//   tcp://host:port
+	//   udp://host:port
+	//   http://host:port/path?query
+	//   https://host:port/path?query
+	//
+	// HTTP and HTTPS destinations receive each batch as the body of a POST
+	// request.
+	URL string
+
+	// ContentType is the content type of HTTP requests. If empty, text/plain
+	// is used.
+	ContentType string
+
+	BatchSize     int           // Maximum number of lines sent at once
+	BufferSize    int           // Maximum number of lines held while waiting to be sent
+	FlushInterval time.Duration // Maximum time that a line waits before being sent
+	Timeout       time.Duration // Maximum time allowed for each attempt to send a batch, and for the final flush on close
+	Retries       int           // Number of times a failed batch is retried
+	RetryDelay    time.Duration // Delay before the first retry, doubled for each subsequent retry
+	MaxRetryDelay time.Duration // Upper limit on the delay between retries
+}
+
+// DefaultConfig is the default line sender configuration. Its URL must be
+// provided before use.
+var DefaultConfig = Config{
+	BatchSize:     500,
+	BufferSize:    10000,
+	FlushInterval: 5 * time.Second,
+	Timeout:       10 * time.Second,
+	Retries:       3,
+	RetryDelay:    time.Second,
+	MaxRetryDelay: 30 * time.Second,
+}
+
+// withDefaults returns a copy of c with unset values replaced by those of
+// DefaultConfig.
+func (c Config) withDefaults() Config {
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultConfig.BatchSize
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = DefaultConfig.BufferSize
+	}
+	if c.BufferSize < c.BatchSize {
+		c.BufferSize = c.BatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultConfig.FlushInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultConfig.Timeout
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	}
+	if c.RetryDelay <= 0 {
+		c.RetryDelay = DefaultConfig.RetryDelay
+	}
+	if c.MaxRetryDelay < c.RetryDelay {
+		c.MaxRetryDelay = c.RetryDelay
+	}
+	if c.ContentType == "" {
+		c.ContentType = "text/plain; charset=utf-8"
+	}
+	return c
+}
+
+// newTransport returns a transport for the destination URL in c.
+func (c Config) newTransport() (transport, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "tcp":
+		if u.Host == "" {
+			return nil, ErrInvalidURL
+		}
+		return &tcpTransport{address: u.Host, timeout: c.Timeout}, nil
+	case "udp":
+		if u.Host == "" {
+			return nil, ErrInvalidURL
+		}
+		return newUDPTransport(u.Host)
+	case "http", "https":
+		if u.Host == "" {
+			return nil, ErrInvalidURL
+		}
+		return newHTTPTransport(u.String(), c.ContentType, c.Timeout), nil
+	default:
+		return nil, ErrUnsupportedScheme
+	}
+}
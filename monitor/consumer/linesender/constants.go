@@ -0,0 +1,21 @@
+package linesender
+
+import "errors"
+
+// maxDatagramSize is the maximum number of bytes sent in a single UDP packet.
+// Lines are never split, so a single line that exceeds this size will be sent
+// in a packet of its own.
+const maxDatagramSize = 1400
+
+var (
+	// ErrClosed is returned when a sender has been closed.
+	ErrClosed = errors.New("line sender is closed")
+
+	// ErrInvalidURL is returned when a destination URL does not include a
+	// host.
+	ErrInvalidURL = errors.New("line sender destination URL does not include a host")
+
+	// ErrUnsupportedScheme is returned when a destination URL has a scheme
+	// other than tcp, udp, http or https.
+	ErrUnsupportedScheme = errors.New("line sender destination URL has an unsupported scheme")
+)
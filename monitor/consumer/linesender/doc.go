@@ -0,0 +1,8 @@
+// Package linesender delivers line-oriented time series data, such as InfluxDB
+// line protocol or Graphite plaintext, over TCP, UDP or HTTP.
+//
+// Lines are buffered and sent in batches by a background goroutine, with
+// retries and exponential backoff when delivery fails. The buffer is bounded
+// and never blocks its callers, which keeps a slow time series database from
+// stalling the DFSR monitor.
+package linesender
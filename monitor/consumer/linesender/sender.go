@@ -0,0 +1,225 @@
+package linesender
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats holds the number of lines processed by a sender.
+type Stats struct {
+	Sent    uint64 // Lines delivered to the destination
+	Dropped uint64 // Lines discarded because the buffer was full
+	Failed  uint64 // Lines discarded after exhausting all retries
+	Pending int    // Lines currently waiting to be sent
+}
+
+// Sender delivers newline-terminated lines to a time series database in
+// batches.
+//
+// Lines are held in a bounded buffer until they are sent. Send never blocks,
+// so a slow or unavailable destination cannot stall the caller. When the
+// buffer is full the oldest lines are discarded to make room for new ones.
+//
+// The zero value of a sender is not suitable for use. Senders should be
+// created with a call to New().
+type Sender struct {
+	config    Config
+	transport transport
+	wake      chan struct{} // Signals that a full batch is available
+	stop      chan struct{} // Closed when the sender is closing
+	done      chan struct{} // Closed when the sender has finished
+
+	mutex  sync.Mutex
+	buffer [][]byte
+	stats  Stats
+	closed bool
+}
+
+// New returns a new sender with the given configuration. Unset values in the
+// configuration are replaced with the values of DefaultConfig.
+//
+// An error is returned if the destination URL is invalid. A destination that
+// is unreachable is not considered an error; delivery will be retried when
+// subsequent batches are sent.
+func New(config Config) (*Sender, error) {
+	config = config.withDefaults()
+	t, err := config.newTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sender{
+		config:    config,
+		transport: t,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Send adds the given lines to the sender's buffer. A trailing newline will be
+// added to each line that lacks one. Send does not block.
+//
+// If the sender has been closed ErrClosed will be returned.
+func (s *Sender) Send(lines ...[]byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if line[len(line)-1] != '\n' {
+			line = append(line[:len(line):len(line)], '\n')
+		}
+		s.buffer = append(s.buffer, line)
+	}
+
+	if over := len(s.buffer) - s.config.BufferSize; over > 0 {
+		s.stats.Dropped += uint64(over)
+		s.buffer = append(s.buffer[:0:0], s.buffer[over:]...)
+	}
+
+	if len(s.buffer) >= s.config.BatchSize {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Stats returns the number of lines processed by the sender.
+func (s *Sender) Stats() (stats Stats) {
+	s.mutex.Lock()
+	stats = s.stats
+	stats.Pending = len(s.buffer)
+	s.mutex.Unlock()
+	return
+}
+
+// Close flushes any buffered lines and releases the resources consumed by the
+// sender. Each remaining batch is attempted once without retries, and the
+// flush as a whole is limited to the configured timeout. Lines that could not
+// be sent before the timeout elapsed are counted as failed. Close blocks until
+// the flush has finished.
+func (s *Sender) Close() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		<-s.done
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.mutex.Unlock()
+
+	<-s.done
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	defer s.transport.Close()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+			for ctx.Err() == nil && s.flush(ctx, false) {
+			}
+			cancel()
+			s.discard()
+			return
+		case <-ticker.C:
+			for s.flush(context.Background(), true) {
+			}
+		case <-s.wake:
+			for s.flush(context.Background(), true) {
+			}
+		}
+	}
+}
+
+// flush sends a single batch from the buffer within the lifetime of ctx. It
+// returns true if more lines remain in the buffer afterward.
+func (s *Sender) flush(ctx context.Context, retry bool) (more bool) {
+	lines := s.take()
+	if len(lines) == 0 {
+		return false
+	}
+
+	batch := bytes.Join(lines, nil)
+	err := s.write(ctx, batch, retry)
+
+	s.mutex.Lock()
+	if err != nil {
+		s.stats.Failed += uint64(len(lines))
+	} else {
+		s.stats.Sent += uint64(len(lines))
+	}
+	more = len(s.buffer) > 0
+	s.mutex.Unlock()
+
+	return more
+}
+
+// discard removes all lines from the buffer and counts them as failed.
+func (s *Sender) discard() {
+	s.mutex.Lock()
+	s.stats.Failed += uint64(len(s.buffer))
+	s.buffer = nil
+	s.mutex.Unlock()
+}
+
+// take removes up to one batch of lines from the buffer and returns them.
+func (s *Sender) take() (lines [][]byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	n := len(s.buffer)
+	if n > s.config.BatchSize {
+		n = s.config.BatchSize
+	}
+	lines = s.buffer[:n:n]
+	s.buffer = s.buffer[n:]
+	return
+}
+
+// write writes the batch to the transport. Each attempt is limited to the
+// configured timeout and to the lifetime of ctx. If retry is true failed
+// attempts will be retried with exponential backoff until the configured
+// number of retries has been exhausted or the sender is closed.
+func (s *Sender) write(ctx context.Context, batch []byte, retry bool) (err error) {
+	delay := s.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+		err = s.transport.Write(attemptCtx, batch)
+		cancel()
+		if err == nil || !retry || attempt >= s.config.Retries {
+			return
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > s.config.MaxRetryDelay {
+			delay = s.config.MaxRetryDelay
+		}
+	}
+}
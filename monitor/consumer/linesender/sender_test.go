@@ -0,0 +1,60 @@
+package linesender
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stallTransport blocks every write until its context is done.
+type stallTransport struct {
+	writes int
+}
+
+func (t *stallTransport) Write(ctx context.Context, batch []byte) error {
+	t.writes++
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (t *stallTransport) Close() {}
+
+func TestCloseDeadline(t *testing.T) {
+	config := DefaultConfig
+	config.BatchSize = 2
+	config.FlushInterval = time.Hour
+	config.Timeout = 200 * time.Millisecond
+	config = config.withDefaults()
+
+	transport := &stallTransport{}
+	s := &Sender{
+		config:    config,
+		transport: transport,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	// Fill the buffer before the sender starts so that nothing is sent early
+	for i := 0; i < 10; i++ {
+		if err := s.Send([]byte("line")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-s.wake
+	go s.run()
+
+	start := time.Now()
+	s.Close()
+	if elapsed := time.Since(start); elapsed > 2*config.Timeout {
+		t.Errorf("Close took %v with a timeout of %v", elapsed, config.Timeout)
+	}
+
+	stats := s.Stats()
+	if stats.Failed != 10 || stats.Sent != 0 || stats.Pending != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if transport.writes != 1 {
+		t.Errorf("transport received %d writes, want 1", transport.writes)
+	}
+}
@@ -0,0 +1,155 @@
+package linesender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// transport delivers batches of newline-terminated lines to a destination.
+//
+// Transports are not threadsafe. They are only used by the goroutine of the
+// sender that owns them.
+type transport interface {
+	Write(ctx context.Context, batch []byte) error
+	Close()
+}
+
+// tcpTransport writes batches to a persistent TCP connection. The connection
+// is established on first use and re-established after a failure.
+type tcpTransport struct {
+	address string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func (t *tcpTransport) Write(ctx context.Context, batch []byte) error {
+	if t.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", t.address)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+
+	t.conn.SetWriteDeadline(deadline(ctx, t.timeout))
+	if _, err := t.conn.Write(batch); err != nil {
+		t.Close()
+		return err
+	}
+	return nil
+}
+
+func (t *tcpTransport) Close() {
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// udpTransport writes batches as a series of UDP datagrams. Lines are packed
+// into each datagram up to maxDatagramSize.
+type udpTransport struct {
+	conn net.Conn
+}
+
+func newUDPTransport(address string) (*udpTransport, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &udpTransport{conn: conn}, nil
+}
+
+func (t *udpTransport) Write(ctx context.Context, batch []byte) error {
+	for len(batch) > 0 {
+		n := packetLength(batch, maxDatagramSize)
+		if _, err := t.conn.Write(batch[:n]); err != nil {
+			return err
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+func (t *udpTransport) Close() {
+	t.conn.Close()
+}
+
+// httpTransport sends each batch as the body of an HTTP POST request.
+type httpTransport struct {
+	url         string
+	contentType string
+	client      *http.Client
+}
+
+func newHTTPTransport(url, contentType string, timeout time.Duration) *httpTransport {
+	return &httpTransport{
+		url:         url,
+		contentType: contentType,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *httpTransport) Write(ctx context.Context, batch []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", t.contentType)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("line sender destination returned HTTP status %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *httpTransport) Close() {
+	t.client.CloseIdleConnections()
+}
+
+// deadline returns the earlier of the context deadline and the current time
+// plus timeout.
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if cd, ok := ctx.Deadline(); ok && cd.Before(d) {
+		return cd
+	}
+	return d
+}
+
+// packetLength returns the number of bytes at the start of batch that make up
+// complete lines and fit within max bytes. If the first line is longer than
+// max its full length is returned.
+func packetLength(batch []byte, max int) int {
+	n := 0
+	for n < len(batch) {
+		i := bytes.IndexByte(batch[n:], '\n')
+		end := len(batch)
+		if i >= 0 {
+			end = n + i + 1
+		}
+		if end > max && n > 0 {
+			break
+		}
+		n = end
+		if n >= max {
+			break
+		}
+	}
+	return n
+}
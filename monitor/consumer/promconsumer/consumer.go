@@ -0,0 +1,214 @@
+package promconsumer
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+var _ = (prometheus.Collector)((*Consumer)(nil)) // Compile-time interface compliance check
+
+var _ = (http.Handler)((*Consumer)(nil)) // Compile-time interface compliance check
+
+// Consumer represents a Prometheus consumer of DFSR monitor backlog updates.
+// It can also consume member health updates by way of ConsumeHealth.
+//
+// Consumer implements the prometheus.Collector interface and can be
+// registered with any Prometheus registry. It also implements http.Handler
+// and can serve its own metrics directly in the Prometheus exposition format.
+type Consumer struct {
+	ch      <-chan *monitor.Update
+	handler http.Handler
+
+	mutex       sync.RWMutex
+	connections map[connKey]*connState  // Last known state of each connection
+	errors      map[connKey]uint64      // Cumulative query error counts
+	members     map[string]*memberState // Last known health of each member
+	healthErrs  map[string]uint64       // Cumulative health query error counts
+	updates     uint64                  // Number of completed updates
+	last        time.Time               // Completion time of the last update
+	duration    time.Duration           // Wall time of the last update
+}
+
+// New returns a new Prometheus consumer of DFSR monitor backlog updates. The
+// returned consumer will function until the provided update channel is
+// closed.
+func New(updates <-chan *monitor.Update) *Consumer {
+	c := &Consumer{
+		ch:          updates,
+		connections: make(map[connKey]*connState),
+		errors:      make(map[connKey]uint64),
+		members:     make(map[string]*memberState),
+		healthErrs:  make(map[string]uint64),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	c.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	go c.run()
+	return c
+}
+
+// ConsumeHealth causes the consumer to collect metrics from the given channel
+// of member health updates, such as the staging and conflict usage of each
+// replicated folder. It will do so until the channel is closed.
+func (c *Consumer) ConsumeHealth(updates <-chan *monitor.MemberHealth) {
+	go c.runHealth(updates)
+}
+
+// ServeHTTP serves the metrics of the consumer in the Prometheus exposition
+// format. It is typically mounted at /metrics.
+func (c *Consumer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.handler.ServeHTTP(w, r)
+}
+
+// Describe sends the descriptors of all metrics collected by the consumer to
+// the provided channel.
+func (c *Consumer) Describe(ch chan<- *prometheus.Desc) {
+	ch <- backlogDesc
+	ch <- folderBacklogDesc
+	ch <- callDurationDesc
+	ch <- queryErrorsDesc
+	ch <- updateDurationDesc
+	ch <- updateTimestampDesc
+	ch <- updatesDesc
+	ch <- stagingUsedDesc
+	ch <- stagingQuotaDesc
+	ch <- conflictUsedDesc
+	ch <- conflictQuotaDesc
+	ch <- folderStateDesc
+	ch <- memberUptimeDesc
+	ch <- memberEventsDesc
+	ch <- healthErrorsDesc
+}
+
+// Collect sends the current value of all metrics collected by the consumer to
+// the provided channel.
+func (c *Consumer) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for key, state := range c.connections {
+		ch <- prometheus.MustNewConstMetric(callDurationDesc, prometheus.GaugeValue, state.duration.Seconds(), key.group, key.from, key.to)
+		if !state.valid {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(backlogDesc, prometheus.GaugeValue, float64(state.sum), key.group, key.from, key.to)
+		// A folder may be listed more than once in a backlog, but the
+		// registry rejects metrics with duplicate labels
+		seen := make(map[uuid.UUID]bool, len(state.folders))
+		for _, folder := range state.folders {
+			if seen[folder.id] {
+				continue
+			}
+			seen[folder.id] = true
+			ch <- prometheus.MustNewConstMetric(folderBacklogDesc, prometheus.GaugeValue, float64(folder.backlog), key.group, key.from, key.to, folder.name)
+		}
+	}
+
+	for key, count := range c.errors {
+		ch <- prometheus.MustNewConstMetric(queryErrorsDesc, prometheus.CounterValue, float64(count), key.group, key.from, key.to)
+	}
+
+	ch <- prometheus.MustNewConstMetric(updatesDesc, prometheus.CounterValue, float64(c.updates))
+	if c.updates > 0 {
+		ch <- prometheus.MustNewConstMetric(updateDurationDesc, prometheus.GaugeValue, c.duration.Seconds())
+		ch <- prometheus.MustNewConstMetric(updateTimestampDesc, prometheus.GaugeValue, float64(c.last.UnixNano())/1e9)
+	}
+
+	for member, state := range c.members {
+		if !state.valid {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(memberUptimeDesc, prometheus.GaugeValue, state.uptime.Seconds(), member)
+		ch <- prometheus.MustNewConstMetric(memberEventsDesc, prometheus.GaugeValue, float64(state.errors), member, "error")
+		ch <- prometheus.MustNewConstMetric(memberEventsDesc, prometheus.GaugeValue, float64(state.warnings), member, "warning")
+		for _, f := range state.folders {
+			ch <- prometheus.MustNewConstMetric(stagingUsedDesc, prometheus.GaugeValue, megabytes(f.stagingUsed), member, f.group, f.folder)
+			ch <- prometheus.MustNewConstMetric(stagingQuotaDesc, prometheus.GaugeValue, megabytes(f.stagingQuota), member, f.group, f.folder)
+			ch <- prometheus.MustNewConstMetric(conflictUsedDesc, prometheus.GaugeValue, megabytes(f.conflictUsed), member, f.group, f.folder)
+			ch <- prometheus.MustNewConstMetric(conflictQuotaDesc, prometheus.GaugeValue, megabytes(f.conflictQuota), member, f.group, f.folder)
+			ch <- prometheus.MustNewConstMetric(folderStateDesc, prometheus.GaugeValue, float64(f.state), member, f.group, f.folder)
+		}
+	}
+
+	for member, count := range c.healthErrs {
+		ch <- prometheus.MustNewConstMetric(healthErrorsDesc, prometheus.CounterValue, float64(count), member)
+	}
+}
+
+func (c *Consumer) run() {
+	for {
+		update, ok := <-c.ch
+		if !ok {
+			return
+		}
+
+		seen := make(map[connKey]bool, update.Size())
+		for backlog := range update.Listen() {
+			seen[c.record(backlog)] = true
+		}
+
+		c.complete(seen, update.End(), update.Duration())
+	}
+}
+
+// record updates the state of the connection described by backlog and
+// returns its key.
+func (c *Consumer) record(backlog *dfsr.Backlog) connKey {
+	key := makeConnKey(backlog)
+	state := makeConnState(backlog)
+
+	c.mutex.Lock()
+	c.connections[key] = state
+	if !state.valid {
+		c.errors[key]++
+	}
+	c.mutex.Unlock()
+
+	return key
+}
+
+// complete records the completion of an update and removes the state of any
+// connections that were not part of it.
+func (c *Consumer) complete(seen map[connKey]bool, end time.Time, duration time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.connections {
+		if !seen[key] {
+			delete(c.connections, key)
+		}
+	}
+
+	c.updates++
+	c.last = end
+	c.duration = duration
+}
+
+func (c *Consumer) runHealth(updates <-chan *monitor.MemberHealth) {
+	for health := range updates {
+		c.recordHealth(health)
+	}
+}
+
+// recordHealth updates the health of the member described by health.
+func (c *Consumer) recordHealth(health *monitor.MemberHealth) {
+	member := strings.ToLower(health.Host)
+	state := makeMemberState(health)
+
+	c.mutex.Lock()
+	c.members[member] = state
+	if health.Err != nil {
+		c.healthErrs[member]++
+	}
+	c.mutex.Unlock()
+}
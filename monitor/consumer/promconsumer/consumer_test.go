@@ -0,0 +1,161 @@
+package promconsumer
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper/report"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+var (
+	group   = &dfsr.Group{Name: "Example"}
+	data    = &dfsr.Folder{ID: uuid.MustParse("7e000000-0002-4000-8000-000000000001"), Name: "Data"}
+	profile = &dfsr.Folder{ID: uuid.MustParse("7e000000-0002-4000-8000-000000000002"), Name: "Profiles"}
+)
+
+func newConsumer(t *testing.T) *Consumer {
+	t.Helper()
+	updates := make(chan *monitor.Update)
+	t.Cleanup(func() { close(updates) })
+	return New(updates)
+}
+
+// scrape retrieves the metrics of c over HTTP and returns the lines of the
+// response that are not comments.
+func scrape(t *testing.T, c *Consumer) map[string]bool {
+	t.Helper()
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape returned %s: %s", resp.Status, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("scrape returned content type %q", ct)
+	}
+
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(string(body), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+func expect(t *testing.T, lines map[string]bool, want ...string) {
+	t.Helper()
+	for _, line := range want {
+		if !lines[line] {
+			t.Errorf("scrape is missing %s", line)
+		}
+	}
+}
+
+func TestScrape(t *testing.T) {
+	c := newConsumer(t)
+
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	good := &dfsr.Backlog{
+		Group:   group,
+		From:    "fs1.example.com",
+		To:      "fs2.example.com",
+		Folders: []dfsr.FolderBacklog{{Folder: data, Backlog: 12}, {Folder: profile, Backlog: 3}},
+	}
+	good.Call.Start, good.Call.End = start, start.Add(1500*time.Millisecond)
+	bad := &dfsr.Backlog{
+		Group: group,
+		From:  "fs2.example.com",
+		To:    "fs1.example.com",
+		Err:   errors.New("access denied"),
+	}
+	seen := map[connKey]bool{c.record(good): true, c.record(bad): true}
+	c.complete(seen, start.Add(2*time.Second), 2*time.Second)
+
+	c.recordHealth(&monitor.MemberHealth{
+		Host:    "FS1.example.com",
+		Service: monitor.ServiceHealth{Uptime: time.Hour},
+		Folders: []monitor.FolderHealth{{
+			Group:   group,
+			Folder:  data,
+			State:   report.FolderNormal,
+			Staging: report.Usage{Quota: 4096, Used: 512},
+		}},
+		Errors: []report.Event{{ID: 4012}},
+	})
+
+	lines := scrape(t, c)
+	expect(t, lines,
+		`dfsr_backlog_files{from="fs1.example.com",group="Example",to="fs2.example.com"} 15`,
+		`dfsr_folder_backlog_files{folder="Data",from="fs1.example.com",group="Example",to="fs2.example.com"} 12`,
+		`dfsr_folder_backlog_files{folder="Profiles",from="fs1.example.com",group="Example",to="fs2.example.com"} 3`,
+		`dfsr_backlog_query_duration_seconds{from="fs1.example.com",group="Example",to="fs2.example.com"} 1.5`,
+		`dfsr_backlog_query_errors_total{from="fs2.example.com",group="Example",to="fs1.example.com"} 1`,
+		`dfsr_updates_total 1`,
+		`dfsr_update_duration_seconds 2`,
+		`dfsr_member_service_uptime_seconds{member="fs1.example.com"} 3600`,
+		`dfsr_member_events{member="fs1.example.com",severity="error"} 1`,
+		`dfsr_staging_used_bytes{folder="Data",group="Example",member="fs1.example.com"} 5.36870912e+08`,
+		`dfsr_folder_state{folder="Data",group="Example",member="fs1.example.com"} 4`,
+	)
+	for line := range lines {
+		if strings.HasPrefix(line, "dfsr_backlog_files") && strings.Contains(line, `from="fs2.example.com"`) {
+			t.Errorf("failed query was reported: %s", line)
+		}
+	}
+
+	// Connections that are absent from the next update are dropped
+	c.complete(map[connKey]bool{c.record(bad): true}, start.Add(time.Minute), time.Second)
+	lines = scrape(t, c)
+	expect(t, lines,
+		`dfsr_backlog_query_errors_total{from="fs2.example.com",group="Example",to="fs1.example.com"} 2`,
+		`dfsr_updates_total 2`,
+	)
+	for line := range lines {
+		if strings.HasPrefix(line, "dfsr_backlog_files") {
+			t.Errorf("stale connection was reported: %s", line)
+		}
+	}
+}
+
+func TestScrapeDuplicateFolders(t *testing.T) {
+	c := newConsumer(t)
+
+	backlog := &dfsr.Backlog{
+		Group: group,
+		From:  "fs1.example.com",
+		To:    "fs2.example.com",
+		Folders: []dfsr.FolderBacklog{
+			{Folder: data, Backlog: 12},
+			{Folder: data, Backlog: 12},
+			{Folder: profile, Backlog: 3},
+		},
+	}
+	c.complete(map[connKey]bool{c.record(backlog): true}, time.Now(), time.Second)
+
+	// The registry fails the scrape if a metric is sent more than once
+	lines := scrape(t, c)
+	expect(t, lines,
+		`dfsr_folder_backlog_files{folder="Data",from="fs1.example.com",group="Example",to="fs2.example.com"} 12`,
+		`dfsr_folder_backlog_files{folder="Profiles",from="fs1.example.com",group="Example",to="fs2.example.com"} 3`,
+	)
+}
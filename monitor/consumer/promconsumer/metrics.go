@@ -0,0 +1,87 @@
+package promconsumer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const namespace = "dfsr"
+
+var connLabels = []string{"group", "from", "to"}
+
+var folderLabels = []string{"member", "group", "folder"}
+
+var (
+	backlogDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "backlog_files"),
+		"Number of files backlogged on a replication connection, summed across all replicated folders.",
+		connLabels, nil,
+	)
+	folderBacklogDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "folder_backlog_files"),
+		"Number of files backlogged on a replication connection for a replicated folder.",
+		append(connLabels[:len(connLabels):len(connLabels)], "folder"), nil,
+	)
+	callDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "backlog", "query_duration_seconds"),
+		"Wall time of the most recent backlog query for a replication connection.",
+		connLabels, nil,
+	)
+	queryErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "backlog", "query_errors_total"),
+		"Number of backlog queries for a replication connection that failed.",
+		connLabels, nil,
+	)
+	updateDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "update", "duration_seconds"),
+		"Wall time of the most recent monitor update.",
+		nil, nil,
+	)
+	updateTimestampDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "update", "timestamp_seconds"),
+		"Completion time of the most recent monitor update in seconds since the Unix epoch.",
+		nil, nil,
+	)
+	updatesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "updates_total"),
+		"Number of monitor updates that have completed.",
+		nil, nil,
+	)
+	stagingUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "staging", "used_bytes"),
+		"Space used by the staging folder of a replicated folder on a member.",
+		folderLabels, nil,
+	)
+	stagingQuotaDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "staging", "quota_bytes"),
+		"Quota of the staging folder of a replicated folder on a member.",
+		folderLabels, nil,
+	)
+	conflictUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "conflict", "used_bytes"),
+		"Space used by the conflict and deleted folder of a replicated folder on a member.",
+		folderLabels, nil,
+	)
+	conflictQuotaDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "conflict", "quota_bytes"),
+		"Quota of the conflict and deleted folder of a replicated folder on a member.",
+		folderLabels, nil,
+	)
+	folderStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "folder", "state"),
+		"Replication state of a replicated folder on a member. 4 is normal and 5 is in error.",
+		folderLabels, nil,
+	)
+	memberUptimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "member", "service_uptime_seconds"),
+		"Uptime of the DFSR service on a member when its health was last reported.",
+		[]string{"member"}, nil,
+	)
+	memberEventsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "member", "events"),
+		"Number of error and warning events listed in the most recent health reports of a member.",
+		[]string{"member", "severity"}, nil,
+	)
+	healthErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "health", "query_errors_total"),
+		"Number of health report queries for a member that failed.",
+		[]string{"member"}, nil,
+	)
+)
@@ -0,0 +1,109 @@
+package promconsumer
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+)
+
+// connKey identifies a connection between replication group members.
+type connKey struct {
+	group string
+	from  string
+	to    string
+}
+
+func makeConnKey(backlog *dfsr.Backlog) connKey {
+	var group string
+	if backlog.Group != nil {
+		group = backlog.Group.Name
+	}
+	return connKey{
+		group: group,
+		from:  backlog.From,
+		to:    backlog.To,
+	}
+}
+
+// connState holds the most recent backlog query results for a connection.
+type connState struct {
+	valid    bool // Did the query succeed for all folders?
+	sum      uint
+	folders  []folderState
+	duration time.Duration
+}
+
+// folderState holds the most recent backlog of a replicated folder.
+type folderState struct {
+	id      uuid.UUID
+	name    string
+	backlog int
+}
+
+func makeConnState(backlog *dfsr.Backlog) *connState {
+	state := &connState{
+		valid:    consumerutil.Reportable(backlog),
+		duration: backlog.Call.Duration(),
+	}
+	if !state.valid {
+		return state
+	}
+
+	state.sum = backlog.Sum()
+	state.folders = make([]folderState, len(backlog.Folders))
+	for i, folder := range backlog.Folders {
+		if folder.Folder != nil {
+			state.folders[i].id = folder.Folder.ID
+			state.folders[i].name = folder.Folder.Name
+		}
+		state.folders[i].backlog = folder.Backlog
+	}
+	return state
+}
+
+// memberState holds the most recent health of a member.
+type memberState struct {
+	valid    bool // Was any report retrieved?
+	uptime   time.Duration
+	errors   int
+	warnings int
+	folders  []folderHealth
+}
+
+// folderHealth holds the most recent health of a replicated folder on a
+// member.
+type folderHealth struct {
+	group         string
+	folder        string
+	state         int
+	stagingUsed   int
+	stagingQuota  int
+	conflictUsed  int
+	conflictQuota int
+}
+
+func makeMemberState(health *monitor.MemberHealth) *memberState {
+	state := &memberState{
+		valid:    health.Err == nil || len(health.Folders) > 0,
+		uptime:   health.Service.Uptime,
+		errors:   len(health.Errors),
+		warnings: len(health.Warnings),
+		folders:  make([]folderHealth, len(health.Folders)),
+	}
+	for i, folder := range health.Folders {
+		f := &state.folders[i]
+		if folder.Group != nil {
+			f.group = folder.Group.Name
+		}
+		if folder.Folder != nil {
+			f.folder = folder.Folder.Name
+		}
+		f.state = int(folder.State)
+		f.stagingUsed, f.stagingQuota = folder.Staging.Used, folder.Staging.Quota
+		f.conflictUsed, f.conflictQuota = folder.Conflict.Used, folder.Conflict.Quota
+	}
+	return state
+}
@@ -0,0 +1,6 @@
+package promconsumer
+
+// megabytes returns the number of bytes in the given number of megabytes.
+func megabytes(mb int) float64 {
+	return float64(mb) * 1024 * 1024
+}
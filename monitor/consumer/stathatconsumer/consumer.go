@@ -2,11 +2,11 @@ package stathatconsumer
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/stathat/go"
 	"gopkg.in/dfsr.v0/dfsr"
 	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
 )
 
 // Consumer represents a StatHat consumer of DFSR monitor backlog updates.
@@ -42,7 +42,7 @@ func (c *Consumer) run() {
 			return
 		}
 		for backlog := range update.Listen() {
-			if !reportable(backlog) {
+			if !consumerutil.Reportable(backlog) {
 				continue
 			}
 			c.send(backlog)
@@ -59,33 +59,5 @@ func (c *Consumer) send(backlog *dfsr.Backlog) {
 }
 
 func (c *Consumer) statName(backlog *dfsr.Backlog) string {
-	return fmt.Sprintf(c.format, backlog.Group.Name, backlog.From, backlog.To, nonFQDN(backlog.From), nonFQDN(backlog.To))
-}
-
-func nonFQDN(fqdn string) string {
-	dot := strings.Index(fqdn, ".")
-	if dot < 1 {
-		return strings.ToUpper(fqdn)
-	}
-	return strings.ToUpper(fqdn[0:dot])
-}
-
-func reportable(backlog *dfsr.Backlog) bool {
-	if backlog.Err != nil {
-		return false
-	}
-
-	if len(backlog.Folders) == 0 {
-		// Indicates replication group query error
-		return false
-	}
-
-	for f := range backlog.Folders {
-		if backlog.Folders[f].Backlog < 0 {
-			// Indicates per-folder query error
-			return false
-		}
-	}
-
-	return true
+	return fmt.Sprintf(c.format, backlog.Group.Name, backlog.From, backlog.To, consumerutil.NonFQDN(backlog.From), consumerutil.NonFQDN(backlog.To))
 }
@@ -0,0 +1,203 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/fanout"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/helper/report"
+)
+
+// MemberHealth holds the health of a DFSR member as described by the health
+// reports it generates for each of its replication groups.
+//
+// If the report for a group cannot be retrieved Err holds the first error
+// encountered, and the remaining fields hold the data from the reports that
+// were retrieved. Values are shared by all listeners and must not be
+// modified.
+type MemberHealth struct {
+	Host      string
+	Timestamp time.Time // Time at which the reports were requested
+	Service   ServiceHealth
+	Folders   []FolderHealth
+	Errors    []report.Event // Error events listed in the reports
+	Warnings  []report.Event // Warning events listed in the reports
+	Call      callstat.Call
+	Err       error
+}
+
+// ServiceHealth describes the state of the DFSR service on a member.
+type ServiceHealth struct {
+	State     string
+	Uptime    time.Duration
+	OSVersion string
+}
+
+// FolderHealth describes the state of a replicated folder on a member.
+type FolderHealth struct {
+	Group    *dfsr.Group
+	Folder   *dfsr.Folder
+	State    report.FolderState // State of the folder's replication database
+	Staging  report.Usage
+	Conflict report.Usage
+}
+
+// healthWorker acts as a polling source for poller. It retrieves domain
+// configuration data from a configuration source, requests a health report
+// for each replication group hosted by each member in the domain and sends
+// member health updates via a broadcaster.
+type healthWorker struct {
+	source Source
+	client *helper.Client // Shared with the backlog worker, which closes it
+	bc     *fanout.Broadcaster
+}
+
+func (w *healthWorker) Close() {
+}
+
+func (w *healthWorker) Poll(ctx context.Context) {
+	if cancelRequested(ctx) {
+		return
+	}
+
+	domain, _, err := w.source.Value()
+	if err != nil {
+		return
+	}
+
+	hosts := memberGroups(domain)
+
+	var wg sync.WaitGroup
+	wg.Add(len(hosts))
+	for _, host := range hosts {
+		go func(host hostGroups) {
+			defer wg.Done()
+			health := w.compute(ctx, host.host, host.groups)
+			if cancelRequested(ctx) {
+				return
+			}
+			w.bc.Send(health)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// compute retrieves the health reports of the given groups from a member and
+// combines them.
+func (w *healthWorker) compute(ctx context.Context, host string, groups []*dfsr.Group) (health *MemberHealth) {
+	health = &MemberHealth{
+		Host:      host,
+		Timestamp: time.Now(),
+	}
+	health.Call.Begin("Monitor.Health")
+
+	var firstErr error
+	seen := make(map[report.Event]bool)
+	for _, group := range groups {
+		if cancelRequested(ctx) {
+			firstErr = ctx.Err()
+			break
+		}
+
+		_, data, call, err := w.client.Report(ctx, host, group.ID, nil, false, false)
+		health.Call.Add(&call)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		r, err := report.ParseString(data)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		health.add(group, r, seen)
+	}
+
+	health.Err = firstErr
+	health.Call.Complete(firstErr)
+	return
+}
+
+// add merges the contents of a health report for group into h. Events that
+// have already been seen are skipped, as members include the same events in
+// the reports for each of their groups.
+func (h *MemberHealth) add(group *dfsr.Group, r *report.Report, seen map[report.Event]bool) {
+	h.Service = ServiceHealth{
+		State:     r.Server.ServiceState,
+		Uptime:    r.Uptime(),
+		OSVersion: r.Server.OSVersion,
+	}
+
+	if rg, ok := r.Group(group.ID); ok {
+		for f := range group.Folders {
+			folder := &group.Folders[f]
+			rf, ok := rg.Folder(folder.ID)
+			if !ok {
+				continue
+			}
+			h.Folders = append(h.Folders, FolderHealth{
+				Group:    group,
+				Folder:   folder,
+				State:    rf.State,
+				Staging:  rf.Staging,
+				Conflict: rf.Conflict,
+			})
+		}
+	}
+
+	for _, event := range r.Errors {
+		if !seen[event] {
+			seen[event] = true
+			h.Errors = append(h.Errors, event)
+		}
+	}
+	for _, event := range r.Warnings {
+		if !seen[event] {
+			seen[event] = true
+			h.Warnings = append(h.Warnings, event)
+		}
+	}
+}
+
+// hostGroups holds the replication groups hosted by a member.
+type hostGroups struct {
+	host   string
+	groups []*dfsr.Group
+}
+
+// memberGroups returns the replication groups hosted by each member of the
+// domain.
+func memberGroups(domain *dfsr.Domain) (output []hostGroups) {
+	index := make(map[string]int) // Maps lower-case host names to output entries
+	for gi := 0; gi < len(domain.Groups); gi++ {
+		group := &domain.Groups[gi]
+
+		for mi := 0; mi < len(group.Members); mi++ {
+			host := group.Members[mi].Computer.Host
+			if host == "" {
+				continue
+			}
+
+			key := strings.ToLower(host)
+			i, ok := index[key]
+			if !ok {
+				i = len(output)
+				index[key] = i
+				output = append(output, hostGroups{host: host})
+			}
+			output[i].groups = append(output[i].groups, group)
+		}
+	}
+	return
+}
@@ -0,0 +1,23 @@
+package history
+
+import (
+	"errors"
+	"time"
+)
+
+// pruneInterval is the minimum amount of time between retention passes.
+const pruneInterval = 10 * time.Minute
+
+var (
+	bucketSeries = []byte("series") // Maps series keys to connection metadata
+	bucketRaw    = []byte("raw")    // Holds a bucket of raw samples for each series
+)
+
+var (
+	// ErrClosed is returned when a store has been closed.
+	ErrClosed = errors.New("history store is closed")
+
+	// ErrNotFound is returned when a query refers to a connection that has no
+	// recorded history.
+	ErrNotFound = errors.New("no history has been recorded for the connection")
+)
@@ -0,0 +1,12 @@
+// Package history provides an embedded store of DFSR backlog history.
+//
+// A Store records every backlog value produced by a DFSR monitor in a local
+// bbolt database. Raw samples are kept alongside downsampled rollups, each
+// with their own retention period, so that recent history is available at
+// full resolution while older history is kept in summarized form.
+//
+// The store supports range queries for the total backlog of a connection or
+// the backlog of an individual replicated folder, with optional downsampling
+// at query time. This allows questions about trends and the onset of backlogs
+// to be answered without an external time series database.
+package history
@@ -0,0 +1,285 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Query describes a range of backlog history for a connection.
+type Query struct {
+	Connection Connection
+	Folder     uuid.UUID // Replicated folder, or the zero UUID for the total backlog
+	Start      time.Time // Inclusive start of the range, or zero for the earliest data
+	End        time.Time // Exclusive end of the range, or zero for the latest data
+
+	// Step is the width of each point in the result. If zero, raw samples are
+	// returned. Otherwise samples are aggregated into intervals of the given
+	// width, using stored rollups when raw samples are no longer available.
+	Step time.Duration
+}
+
+// Point is a value in a backlog time series. Raw samples are returned as
+// points with a single sample.
+type Point struct {
+	Time    time.Time // Start of the interval, or time of the sample
+	Samples int       // Number of successful queries
+	Errors  int       // Number of failed queries
+	Min     int
+	Max     int
+	Mean    float64
+	Last    int
+	Err     string // Error of a raw sample
+}
+
+// Valid returns true if the point includes at least one successful query.
+func (p *Point) Valid() bool {
+	return p.Samples > 0
+}
+
+func makePoint(t time.Time, a aggregate) Point {
+	p := Point{
+		Time:    t,
+		Samples: a.N,
+		Errors:  a.Errors,
+		Min:     a.Min,
+		Max:     a.Max,
+		Last:    a.Last,
+	}
+	if a.N > 0 {
+		p.Mean = float64(a.Sum) / float64(a.N)
+	}
+	return p
+}
+
+// Series returns the connections that have recorded history.
+func (s *Store) Series() (series []Series, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketRaw)
+		return tx.Bucket(bucketSeries).ForEach(func(k, v []byte) error {
+			var info seriesInfo
+			if err := json.Unmarshal(v, &info); err != nil {
+				return err
+			}
+			entry := Series{
+				Connection: Connection{GroupID: info.GroupID, From: info.From, To: info.To},
+				Group:      info.Group,
+				Folders:    info.Folders,
+			}
+			if b := raw.Bucket(k); b != nil {
+				if first, _ := b.Cursor().First(); first != nil {
+					entry.First = keyTime(first)
+				}
+				if last, _ := b.Cursor().Last(); last != nil {
+					entry.Last = keyTime(last)
+				}
+			}
+			series = append(series, entry)
+			return nil
+		})
+	})
+	return
+}
+
+// Query returns the points within the range described by q in chronological
+// order. If no history has been recorded for the connection ErrNotFound will be
+// returned.
+func (s *Store) Query(q Query) (points []Point, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		key := q.Connection.key()
+		if tx.Bucket(bucketSeries).Get(key) == nil {
+			return ErrNotFound
+		}
+
+		if q.Step <= 0 {
+			points, err = queryRaw(tx.Bucket(bucketRaw).Bucket(key), q)
+			return err
+		}
+
+		resolution := s.resolution(q)
+		if resolution == 0 {
+			points, err = queryRawAggregate(tx.Bucket(bucketRaw).Bucket(key), q)
+			return err
+		}
+		points, err = queryRollup(tx.Bucket(rollupBucket(resolution)).Bucket(key), q)
+		return err
+	})
+	return
+}
+
+// Since returns the time at which the backlog of a connection or folder most
+// recently rose above threshold and has stayed above it since, based on raw
+// samples. Failed queries are skipped.
+//
+// If the most recent valid sample is not above threshold ok will be false.
+func (s *Store) Since(conn Connection, folder uuid.UUID, threshold int) (since time.Time, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		key := conn.key()
+		if tx.Bucket(bucketSeries).Get(key) == nil {
+			return ErrNotFound
+		}
+		b := tx.Bucket(bucketRaw).Bucket(key)
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var smp sample
+			if err := json.Unmarshal(v, &smp); err != nil {
+				return err
+			}
+			value, valid := smp.value(folder)
+			if !valid {
+				continue
+			}
+			if value <= threshold {
+				return nil
+			}
+			since, ok = keyTime(k), true
+		}
+		return nil
+	})
+	return
+}
+
+// resolution returns the resolution of the data that should be used to
+// answer q. It prefers the finest resolution that does not exceed the step
+// of q and whose retention covers the start of q.
+func (s *Store) resolution(q Query) time.Duration {
+	covers := func(p Policy) bool {
+		if p.Retention <= 0 {
+			return true
+		}
+		return !q.Start.IsZero() && !q.Start.Before(time.Now().Add(-p.Retention))
+	}
+
+	if covers(s.raw) {
+		return 0
+	}
+
+	var candidate time.Duration
+	for _, policy := range s.rollups {
+		if policy.Resolution > q.Step {
+			break
+		}
+		candidate = policy.Resolution
+		if covers(policy) {
+			return policy.Resolution
+		}
+	}
+	if candidate == 0 && len(s.rollups) > 0 {
+		// No rollup is fine enough, so use the finest one available
+		return s.rollups[0].Resolution
+	}
+	return candidate
+}
+
+// bounds returns the cursor key range for q.
+func bounds(q Query) (start, end []byte) {
+	if !q.Start.IsZero() {
+		start = timeKey(q.Start)
+	}
+	if !q.End.IsZero() {
+		end = timeKey(q.End)
+	}
+	return
+}
+
+// scan calls fn for each entry of b within the range of q.
+func scan(b *bolt.Bucket, q Query, fn func(t time.Time, v []byte) error) error {
+	if b == nil {
+		return nil
+	}
+	start, end := bounds(q)
+	c := b.Cursor()
+	k, v := c.First()
+	if start != nil {
+		k, v = c.Seek(start)
+	}
+	for ; k != nil; k, v = c.Next() {
+		if end != nil && bytes.Compare(k, end) >= 0 {
+			break
+		}
+		if err := fn(keyTime(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryRaw(b *bolt.Bucket, q Query) (points []Point, err error) {
+	err = scan(b, q, func(t time.Time, v []byte) error {
+		var smp sample
+		if err := json.Unmarshal(v, &smp); err != nil {
+			return err
+		}
+		var a aggregate
+		a.add(smp.value(q.Folder))
+		p := makePoint(t, a)
+		p.Err = smp.Err
+		points = append(points, p)
+		return nil
+	})
+	return
+}
+
+func queryRawAggregate(b *bolt.Bucket, q Query) (points []Point, err error) {
+	var acc accumulator
+	err = scan(b, q, func(t time.Time, v []byte) error {
+		var smp sample
+		if err := json.Unmarshal(v, &smp); err != nil {
+			return err
+		}
+		var a aggregate
+		a.add(smp.value(q.Folder))
+		acc.add(t.Truncate(q.Step), a)
+		return nil
+	})
+	return acc.points(), err
+}
+
+func queryRollup(b *bolt.Bucket, q Query) (points []Point, err error) {
+	if !q.Start.IsZero() {
+		// Include the rollup interval that contains the start of the range
+		q.Start = q.Start.Truncate(q.Step)
+	}
+	var acc accumulator
+	err = scan(b, q, func(t time.Time, v []byte) error {
+		var r rollup
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		acc.add(t.Truncate(q.Step), r.aggregate(q.Folder))
+		return nil
+	})
+	return acc.points(), err
+}
+
+// accumulator merges aggregates into chronologically ordered intervals.
+type accumulator struct {
+	times []time.Time
+	aggs  []aggregate
+}
+
+// add merges a into the interval starting at t. Intervals must be added in
+// chronological order.
+func (acc *accumulator) add(t time.Time, a aggregate) {
+	n := len(acc.times)
+	if n == 0 || !acc.times[n-1].Equal(t) {
+		acc.times = append(acc.times, t)
+		acc.aggs = append(acc.aggs, aggregate{})
+		n++
+	}
+	acc.aggs[n-1].merge(a)
+}
+
+func (acc *accumulator) points() (points []Point) {
+	for i := range acc.times {
+		points = append(points, makePoint(acc.times[i], acc.aggs[i]))
+	}
+	return
+}
@@ -0,0 +1,277 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+// Store is a threadsafe embedded store of DFSR backlog history.
+//
+// The zero value of a store is not suitable for use. Stores should be created
+// with a call to Open().
+type Store struct {
+	db        *bolt.DB
+	raw       Policy
+	rollups   []Policy // Sorted by increasing resolution
+	wg        sync.WaitGroup
+	pruneMu   sync.Mutex
+	lastPrune time.Time
+}
+
+// Open opens the history store at the given path, creating it if necessary.
+// If options is nil DefaultOptions will be used.
+//
+// Only one process may have a store open at a time. Open blocks for up to one
+// second waiting for other processes to release the store.
+func Open(path string, options *Options) (*Store, error) {
+	if options == nil {
+		options = &DefaultOptions
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{db: db}
+	for _, policy := range options.Policies {
+		if policy.Resolution <= 0 {
+			s.raw = policy
+			continue
+		}
+		s.rollups = append(s.rollups, policy)
+	}
+	sort.Slice(s.rollups, func(i, j int) bool {
+		return s.rollups[i].Resolution < s.rollups[j].Resolution
+	})
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range s.bucketNames() {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close waits for any updates being consumed by the store to be recorded, then
+// closes the underlying database.
+func (s *Store) Close() error {
+	s.wg.Wait()
+	return s.db.Close()
+}
+
+// Consume causes the store to record the updates received on the given
+// channel in its own goroutine until the channel is closed.
+//
+// Errors encountered while recording updates are passed to onError if it is
+// not nil.
+func (s *Store) Consume(updates <-chan *monitor.Update, onError func(error)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for update := range updates {
+			if err := s.RecordUpdate(update); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+}
+
+// RecordUpdate records the backlog values of an update. It blocks until all
+// values have been received and then records them in a single transaction.
+func (s *Store) RecordUpdate(update *monitor.Update) error {
+	var values []*dfsr.Backlog
+	for backlog := range update.Listen() {
+		values = append(values, backlog)
+	}
+	return s.Record(values...)
+}
+
+// Record records the given backlog values. Each value is recorded at the
+// start time of its call.
+func (s *Store) Record(values ...*dfsr.Backlog) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, backlog := range values {
+			if err := s.record(tx, backlog); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.maybePrune()
+}
+
+func (s *Store) record(tx *bolt.Tx, backlog *dfsr.Backlog) error {
+	conn := makeConnection(backlog)
+	key := conn.key()
+
+	t := backlog.Call.Start
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	// Update the series metadata
+	info := seriesInfo{GroupID: conn.GroupID, From: conn.From, To: conn.To}
+	if backlog.Group != nil {
+		info.Group = backlog.Group.Name
+		info.Folders = backlog.Group.Folders
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(bucketSeries).Put(key, data); err != nil {
+		return err
+	}
+
+	// Record the raw sample
+	smp := makeSample(backlog)
+	if data, err = json.Marshal(smp); err != nil {
+		return err
+	}
+	raw, err := tx.Bucket(bucketRaw).CreateBucketIfNotExists(key)
+	if err != nil {
+		return err
+	}
+	if err := raw.Put(timeKey(t), data); err != nil {
+		return err
+	}
+
+	// Update each rollup
+	for _, policy := range s.rollups {
+		b, err := tx.Bucket(rollupBucket(policy.Resolution)).CreateBucketIfNotExists(key)
+		if err != nil {
+			return err
+		}
+		k := timeKey(t.Truncate(policy.Resolution))
+		var r rollup
+		if data := b.Get(k); data != nil {
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+		}
+		r.add(&smp)
+		if data, err = json.Marshal(r); err != nil {
+			return err
+		}
+		if err := b.Put(k, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Prune discards all data that is older than the retention period of its
+// policy as of the given time.
+func (s *Store) Prune(now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := prune(tx.Bucket(bucketRaw), s.raw.Retention, now); err != nil {
+			return err
+		}
+		for _, policy := range s.rollups {
+			if err := prune(tx.Bucket(rollupBucket(policy.Resolution)), policy.Retention, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// maybePrune prunes the store if it hasn't been pruned recently.
+func (s *Store) maybePrune() error {
+	s.pruneMu.Lock()
+	now := time.Now()
+	due := now.Sub(s.lastPrune) >= pruneInterval
+	if due {
+		s.lastPrune = now
+	}
+	s.pruneMu.Unlock()
+
+	if !due {
+		return nil
+	}
+	return s.Prune(now)
+}
+
+func (s *Store) bucketNames() (names [][]byte) {
+	names = append(names, bucketSeries, bucketRaw)
+	for _, policy := range s.rollups {
+		names = append(names, rollupBucket(policy.Resolution))
+	}
+	return
+}
+
+// prune deletes the entries of each series bucket within parent that are
+// older than retention.
+func prune(parent *bolt.Bucket, retention time.Duration, now time.Time) error {
+	if retention <= 0 {
+		return nil
+	}
+	cutoff := timeKey(now.Add(-retention))
+
+	var series [][]byte
+	err := parent.ForEach(func(k, v []byte) error {
+		if v == nil {
+			series = append(series, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range series {
+		b := parent.Bucket(name)
+		var expired [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func rollupBucket(resolution time.Duration) []byte {
+	return []byte(fmt.Sprintf("rollup-%d", int64(resolution/time.Second)))
+}
+
+func timeKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return k
+}
+
+func keyTime(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k)))
+}
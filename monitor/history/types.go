@@ -0,0 +1,197 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// Connection identifies a one-way connection between replication group
+// members. Member names are stored in lower case.
+type Connection struct {
+	GroupID uuid.UUID
+	From    string // Fully qualified domain name of the sending member
+	To      string // Fully qualified domain name of the receiving member
+}
+
+func makeConnection(backlog *dfsr.Backlog) (conn Connection) {
+	if backlog.Group != nil {
+		conn.GroupID = backlog.Group.ID
+	}
+	conn.From = strings.ToLower(backlog.From)
+	conn.To = strings.ToLower(backlog.To)
+	return
+}
+
+// key returns the series key of the connection.
+func (c Connection) key() []byte {
+	return []byte(c.GroupID.String() + "|" + strings.ToLower(c.From) + "|" + strings.ToLower(c.To))
+}
+
+// Series describes a connection that has recorded history.
+type Series struct {
+	Connection
+	Group   string        // Replication group name as of the most recent sample
+	Folders []dfsr.Folder // Replicated folders as of the most recent sample
+	First   time.Time     // Time of the earliest raw sample
+	Last    time.Time     // Time of the most recent raw sample
+}
+
+// Policy describes a level of resolution at which history is kept and how
+// long it is retained.
+type Policy struct {
+	Resolution time.Duration // Width of each rollup interval, or zero for raw samples
+	Retention  time.Duration // Age after which data is discarded, or zero to keep it forever
+}
+
+// Options holds the configuration of a store.
+type Options struct {
+	// Policies determine the resolutions at which history is kept. Exactly one
+	// policy should have a resolution of zero, which controls the retention of
+	// raw samples. If no such policy is present raw samples are kept forever.
+	Policies []Policy
+}
+
+// DefaultOptions holds the default store configuration. Raw samples are kept
+// for a week, hourly rollups for 90 days and daily rollups for two years.
+var DefaultOptions = Options{
+	Policies: []Policy{
+		{Resolution: 0, Retention: 7 * 24 * time.Hour},
+		{Resolution: time.Hour, Retention: 90 * 24 * time.Hour},
+		{Resolution: 24 * time.Hour, Retention: 2 * 365 * 24 * time.Hour},
+	},
+}
+
+// sample is the stored form of a raw backlog value.
+type sample struct {
+	Duration time.Duration  `json:"d,omitempty"` // Query duration
+	Err      string         `json:"e,omitempty"` // Query error
+	Total    int            `json:"t"`           // Total backlog, or -1 if the query failed
+	Folders  map[string]int `json:"f,omitempty"` // Maps folder IDs to backlogs, -1 indicates failure
+}
+
+func makeSample(backlog *dfsr.Backlog) (s sample) {
+	s.Duration = backlog.Call.Duration()
+	s.Total = -1
+	if backlog.Err != nil {
+		s.Err = backlog.Err.Error()
+		return
+	}
+	if len(backlog.Folders) == 0 {
+		s.Err = "backlog query did not return values for any replicated folders"
+		return
+	}
+
+	complete := true
+	s.Folders = make(map[string]int, len(backlog.Folders))
+	for _, folder := range backlog.Folders {
+		if folder.Folder == nil {
+			continue
+		}
+		s.Folders[folder.Folder.ID.String()] = folder.Backlog
+		if folder.Backlog < 0 {
+			complete = false
+		}
+	}
+	if complete {
+		s.Total = int(backlog.Sum())
+	}
+	return
+}
+
+// value returns the value of the sample for the given folder, or for the
+// total backlog if folder is the zero UUID. If the value is not available ok
+// will be false.
+func (s *sample) value(folder uuid.UUID) (value int, ok bool) {
+	if folder == uuid.Nil {
+		return s.Total, s.Total >= 0
+	}
+	value, ok = s.Folders[folder.String()]
+	return value, ok && value >= 0
+}
+
+// aggregate summarizes a set of values.
+type aggregate struct {
+	N      int   `json:"n"`           // Number of valid values
+	Errors int   `json:"e,omitempty"` // Number of failed queries
+	Sum    int64 `json:"s"`
+	Min    int   `json:"lo"`
+	Max    int   `json:"hi"`
+	Last   int   `json:"l"`
+}
+
+func (a *aggregate) add(value int, ok bool) {
+	if !ok {
+		a.Errors++
+		return
+	}
+	if a.N == 0 || value < a.Min {
+		a.Min = value
+	}
+	if a.N == 0 || value > a.Max {
+		a.Max = value
+	}
+	a.N++
+	a.Sum += int64(value)
+	a.Last = value
+}
+
+func (a *aggregate) merge(b aggregate) {
+	a.Errors += b.Errors
+	if b.N == 0 {
+		return
+	}
+	if a.N == 0 || b.Min < a.Min {
+		a.Min = b.Min
+	}
+	if a.N == 0 || b.Max > a.Max {
+		a.Max = b.Max
+	}
+	a.N += b.N
+	a.Sum += b.Sum
+	a.Last = b.Last
+}
+
+// rollup is the stored form of a downsampled interval.
+type rollup struct {
+	Total   aggregate            `json:"t"`
+	Folders map[string]aggregate `json:"f,omitempty"`
+}
+
+func (r *rollup) add(s *sample) {
+	r.Total.add(s.Total, s.Total >= 0)
+	if r.Folders == nil {
+		r.Folders = make(map[string]aggregate)
+	}
+	if s.Folders == nil {
+		// The query failed for the replication group as a whole
+		for id, a := range r.Folders {
+			a.Errors++
+			r.Folders[id] = a
+		}
+		return
+	}
+	for id, value := range s.Folders {
+		a := r.Folders[id]
+		a.add(value, value >= 0)
+		r.Folders[id] = a
+	}
+}
+
+func (r *rollup) aggregate(folder uuid.UUID) aggregate {
+	if folder == uuid.Nil {
+		return r.Total
+	}
+	return r.Folders[folder.String()]
+}
+
+// seriesInfo is the stored form of series metadata.
+type seriesInfo struct {
+	GroupID uuid.UUID     `json:"gid"`
+	Group   string        `json:"group"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Folders []dfsr.Folder `json:"folders,omitempty"`
+}
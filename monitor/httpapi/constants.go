@@ -0,0 +1,23 @@
+package httpapi
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	updateChanSize   = 16
+	trendChanSize    = 256              // Number of estimates buffered between the estimator and the stream hub
+	streamBufferSize = 256              // Number of events buffered for each stream client
+	streamKeepAlive  = 30 * time.Second // Interval between keep-alive messages
+	streamWriteWait  = 10 * time.Second // Maximum time allowed for a WebSocket write
+)
+
+var (
+	// ErrNoUpdate is returned when a backlog update has not yet completed.
+	ErrNoUpdate = errors.New("a backlog update has not yet completed")
+
+	// ErrNotRunning is returned when endpoint states are requested from a
+	// monitor that is not running.
+	ErrNotRunning = errors.New("the backlog monitor is not running")
+)
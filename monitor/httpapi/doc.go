@@ -0,0 +1,26 @@
+// Package httpapi provides an HTTP handler that exposes the state of a DFSR
+// monitor as JSON.
+//
+// The handler serves the following resources relative to the path at which it
+// is mounted:
+//
+//   GET  /domain     current domain configuration
+//   GET  /update     most recently completed backlog update
+//   POST /update     request an immediate backlog update
+//   GET  /trends     drain rate and estimated completion of each backlog
+//   GET  /endpoints  current state of each queried DFSR member
+//   GET  /stream     live stream of update events
+//
+// The stream resource delivers server-sent events, or JSON messages when the
+// request is a WebSocket upgrade. A start event is sent when an update begins,
+// a backlog event for each backlog value as it is retrieved, a trend event with
+// the updated trend estimate of each connection, and an end event when the
+// update finishes. A breaker event is sent whenever the circuit breaker of a
+// DFSR member changes state. Each client has a bounded buffer so that a slow
+// client never delays the monitor. Events that do not fit in a client's
+// buffer are discarded, and the client is sent a dropped event with the
+// number of events it missed once it catches up.
+//
+// This allows dashboards and scripts to read the state of the monitor without
+// issuing their own queries against Active Directory or the DFSR members.
+package httpapi
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"net/http"
+	"sync"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/trend"
+)
+
+var _ = (http.Handler)((*Handler)(nil)) // Compile-time interface compliance check
+
+// Handler serves the state of a DFSR monitor as JSON.
+//
+// The zero value of a handler is not suitable for use. Handlers should be
+// created with a call to New().
+type Handler struct {
+	source monitor.Source
+	mon    *monitor.Monitor
+	mux    *http.ServeMux
+	trends *trend.Estimator
+
+	hub    hub
+	mutex  sync.RWMutex
+	latest *updateResponse // Most recently completed update
+}
+
+// New returns a new handler that serves the domain configuration of source
+// and the backlog updates, backlog trends and endpoint states of mon.
+// Typically source is the same configuration source that was used to create
+// mon.
+//
+// The handler listens for updates from mon until the monitor is closed.
+func New(source monitor.Source, mon *monitor.Monitor) *Handler {
+	h := &Handler{
+		source: source,
+		mon:    mon,
+		mux:    http.NewServeMux(),
+		trends: trend.New(trend.DefaultConfig),
+	}
+	h.mux.HandleFunc("/domain", h.serveDomain)
+	h.mux.HandleFunc("/update", h.serveUpdate)
+	h.mux.HandleFunc("/trends", h.serveTrends)
+	h.mux.HandleFunc("/endpoints", h.serveEndpoints)
+	h.mux.HandleFunc("/stream", h.serveStream)
+
+	go h.runTrends(h.trends.Listen(trendChanSize))
+	h.trends.Consume(mon.Listen(updateChanSize))
+	go h.run(mon.Listen(updateChanSize))
+	go h.runBreakers(mon.ListenBreaker(updateChanSize))
+	return h
+}
+
+// ServeHTTP serves the requested resource.
+//
+// The handler expects request paths relative to its own root. When it is
+// mounted below the root of a server it should be wrapped with
+// http.StripPrefix.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// StreamStats returns statistics about the event streams of the handler.
+func (h *Handler) StreamStats() StreamStats {
+	return h.hub.Stats()
+}
+
+func (h *Handler) run(ch <-chan *monitor.Update) {
+	defer h.hub.Close()
+
+	for update := range ch {
+		start, size := update.Start(), update.Size()
+		h.publish(eventStart, startEvent{Start: start, Size: size})
+
+		values := make([]*dfsr.Backlog, 0, size)
+		for value := range update.Listen() {
+			values = append(values, value)
+			h.publish(eventBacklog, makeBacklog(value))
+		}
+
+		end := update.End()
+		h.publish(eventEnd, endEvent{
+			Start:    start,
+			End:      end,
+			Duration: end.Sub(start).Seconds(),
+			Size:     size,
+			Received: len(values),
+			Complete: len(values) == size,
+		})
+
+		latest := makeUpdateResponse(start, end, size, values)
+
+		h.mutex.Lock()
+		h.latest = latest
+		h.mutex.Unlock()
+	}
+}
+
+func (h *Handler) runTrends(ch <-chan trend.Estimate) {
+	for estimate := range ch {
+		h.publish(eventTrend, makeTrend(&estimate))
+	}
+}
+
+func (h *Handler) runBreakers(ch <-chan helper.BreakerEvent) {
+	for event := range ch {
+		h.publish(eventBreaker, makeBreakerEvent(&event))
+	}
+}
+
+func (h *Handler) publish(kind string, v interface{}) {
+	e, err := makeEvent(kind, v)
+	if err != nil {
+		return
+	}
+	h.hub.Publish(e)
+}
+
+func (h *Handler) serveDomain(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	domain, timestamp, err := h.source.Value()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, domainResponse{Timestamp: timestamp, Domain: domain})
+}
+
+func (h *Handler) serveUpdate(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		h.mon.Update()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	h.mutex.RLock()
+	latest := h.latest
+	h.mutex.RUnlock()
+
+	if latest == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrNoUpdate)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, latest)
+}
+
+func (h *Handler) serveTrends(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	estimates := h.trends.Estimates()
+	trends := make([]backlogTrend, 0, len(estimates))
+	for i := range estimates {
+		trends = append(trends, makeTrend(&estimates[i]))
+	}
+
+	writeJSON(w, http.StatusOK, trends)
+}
+
+func (h *Handler) serveEndpoints(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	states := h.mon.States()
+	if states == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrNotRunning)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, makeEndpoints(states))
+}
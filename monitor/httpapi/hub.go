@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// StreamStats holds statistics about the event streams of a handler.
+type StreamStats struct {
+	Clients   int    // Number of connected stream clients
+	Published uint64 // Number of events published
+	Sent      uint64 // Number of events queued for delivery to clients
+	Dropped   uint64 // Number of events discarded because a client buffer was full
+}
+
+// event is a message sent to stream clients.
+type event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func makeEvent(kind string, v interface{}) (event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return event{}, err
+	}
+	return event{Type: kind, Data: data}, nil
+}
+
+// subscriber is a stream client with a bounded event buffer.
+type subscriber struct {
+	ch chan event
+
+	mutex   sync.Mutex
+	dropped uint64 // Number of events dropped since the last call to takeDropped
+}
+
+// takeDropped returns the number of events that have been dropped since it
+// was last called.
+func (s *subscriber) takeDropped() (n uint64) {
+	s.mutex.Lock()
+	n, s.dropped = s.dropped, 0
+	s.mutex.Unlock()
+	return
+}
+
+// hub distributes events to stream clients without blocking. When a client's
+// buffer is full the event is discarded for that client and counted.
+type hub struct {
+	mutex       sync.RWMutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+	stats       StreamStats
+}
+
+// Subscribe returns a new subscriber with a buffer of the given size. If the
+// hub is closed the channel of the returned subscriber will be closed.
+func (h *hub) Subscribe(bufSize int) *subscriber {
+	s := &subscriber{ch: make(chan event, bufSize)}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		close(s.ch)
+		return s
+	}
+	if h.subscribers == nil {
+		h.subscribers = make(map[*subscriber]struct{})
+	}
+	h.subscribers[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes s from the hub and closes its channel.
+func (h *hub) Unsubscribe(s *subscriber) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+	delete(h.subscribers, s)
+	close(s.ch)
+}
+
+// Publish sends e to all subscribers without blocking.
+func (h *hub) Publish(e event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.stats.Published++
+	for s := range h.subscribers {
+		select {
+		case s.ch <- e:
+			h.stats.Sent++
+		default:
+			h.stats.Dropped++
+			s.mutex.Lock()
+			s.dropped++
+			s.mutex.Unlock()
+		}
+	}
+}
+
+// Stats returns statistics about the hub.
+func (h *hub) Stats() (stats StreamStats) {
+	h.mutex.RLock()
+	stats = h.stats
+	stats.Clients = len(h.subscribers)
+	h.mutex.RUnlock()
+	return
+}
+
+// Close closes the channels of all subscribers.
+func (h *hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+
+	for s := range h.subscribers {
+		close(s.ch)
+	}
+	h.subscribers = nil
+}
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// serveStream streams live update events to the client. WebSocket upgrade
+// requests receive each event as a JSON text message. All other requests
+// receive a stream of server-sent events.
+func (h *Handler) serveStream(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		h.serveWebSocket(w, r)
+	} else {
+		h.serveEvents(w, r)
+	}
+}
+
+// serveEvents streams events to the client in the text/event-stream format.
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported by the server"))
+		return
+	}
+
+	sub := h.hub.Subscribe(streamBufferSize)
+	defer h.hub.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable buffering by nginx proxies
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	write := func(e event) error {
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data)
+		return err
+	}
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.ch:
+			if !ok {
+				return // The monitor has been closed
+			}
+			if err := write(e); err != nil {
+				return
+			}
+			if err := writeDropped(sub, write); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// serveWebSocket streams events to the client over a WebSocket connection.
+// Messages received from the client are discarded.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // The upgrader has already responded to the client
+	}
+	defer conn.Close()
+
+	sub := h.hub.Subscribe(streamBufferSize)
+	defer h.hub.Unsubscribe(sub)
+
+	// Read messages until the client disconnects so that control frames are
+	// processed
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	write := func(e event) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+		return conn.WriteJSON(e)
+	}
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-keepAlive.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case e, ok := <-sub.ch:
+			if !ok {
+				// The monitor has been closed
+				msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "monitor closed")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteWait))
+				return
+			}
+			if err := write(e); err != nil {
+				return
+			}
+			if err := writeDropped(sub, write); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeDropped writes a dropped event if any events have been discarded for
+// sub since the last one was written.
+func writeDropped(sub *subscriber, write func(event) error) error {
+	n := sub.takeDropped()
+	if n == 0 {
+		return nil
+	}
+	e, err := makeEvent(eventDropped, droppedEvent{Dropped: n})
+	if err != nil {
+		return err
+	}
+	return write(e)
+}
@@ -0,0 +1,284 @@
+package httpapi
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/callstat"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/monitor/trend"
+)
+
+// errorResponse is the body of unsuccessful requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// domainResponse is the body of domain requests.
+type domainResponse struct {
+	Timestamp time.Time    `json:"timestamp"`
+	Domain    *dfsr.Domain `json:"domain"`
+}
+
+// updateResponse is the body of update requests.
+type updateResponse struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"duration"` // In seconds
+	Size     int       `json:"size"`     // Number of expected backlogs
+	Complete bool      `json:"complete"` // False if the update was canceled
+	Backlogs []backlog `json:"backlogs"`
+}
+
+func makeUpdateResponse(start, end time.Time, size int, values []*dfsr.Backlog) *updateResponse {
+	resp := &updateResponse{
+		Start:    start,
+		End:      end,
+		Duration: end.Sub(start).Seconds(),
+		Size:     size,
+		Complete: len(values) == size,
+		Backlogs: make([]backlog, 0, len(values)),
+	}
+	for _, value := range values {
+		resp.Backlogs = append(resp.Backlogs, makeBacklog(value))
+	}
+	sort.Slice(resp.Backlogs, func(i, j int) bool {
+		a, b := &resp.Backlogs[i], &resp.Backlogs[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return resp
+}
+
+// backlog is the JSON representation of a dfsr.Backlog.
+type backlog struct {
+	Group   string          `json:"group"`
+	GroupID uuid.UUID       `json:"groupId"`
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Backlog *uint           `json:"backlog"` // Null if any folder could not be queried
+	Folders []folderBacklog `json:"folders"`
+	Closed  bool            `json:"scheduleClosed,omitempty"` // Connection was scheduled closed
+	Error   string          `json:"error,omitempty"`
+	Call    call            `json:"call"`
+}
+
+func makeBacklog(value *dfsr.Backlog) backlog {
+	b := backlog{
+		From:    value.From,
+		To:      value.To,
+		Folders: make([]folderBacklog, 0, len(value.Folders)),
+		Closed:  value.Closed,
+		Error:   errString(value.Err),
+		Call:    makeCall(&value.Call),
+	}
+	if value.Group != nil {
+		b.Group = value.Group.Name
+		b.GroupID = value.Group.ID
+	}
+
+	valid := value.Err == nil && len(value.Folders) > 0
+	for _, folder := range value.Folders {
+		fb := folderBacklog{Backlog: folder.Backlog}
+		if folder.Folder != nil {
+			fb.Name = folder.Folder.Name
+			fb.ID = folder.Folder.ID
+		}
+		if folder.Backlog < 0 {
+			valid = false
+		}
+		b.Folders = append(b.Folders, fb)
+	}
+	if valid {
+		sum := value.Sum()
+		b.Backlog = &sum
+	}
+	return b
+}
+
+// folderBacklog is the JSON representation of a dfsr.FolderBacklog.
+type folderBacklog struct {
+	Name    string    `json:"name"`
+	ID      uuid.UUID `json:"id"`
+	Backlog int       `json:"backlog"` // -1 if the folder could not be queried
+}
+
+// backlogTrend is the JSON representation of a trend.Estimate.
+type backlogTrend struct {
+	Group      string     `json:"group"`
+	GroupID    uuid.UUID  `json:"groupId"`
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	Time       time.Time  `json:"time"` // Time of the most recent sample
+	Backlog    uint       `json:"backlog"`
+	Samples    int        `json:"samples"`
+	Rate       float64    `json:"rate"` // Change in backlog per second
+	Trend      string     `json:"trend"`
+	ETA        *float64   `json:"eta,omitempty"` // In seconds, if draining
+	Completion *time.Time `json:"completion,omitempty"`
+	Closed     bool       `json:"scheduleClosed,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func makeTrend(e *trend.Estimate) backlogTrend {
+	t := backlogTrend{
+		Group:   e.Connection.Group,
+		GroupID: e.Connection.GroupID,
+		From:    e.Connection.From,
+		To:      e.Connection.To,
+		Time:    e.Time,
+		Backlog: e.Backlog,
+		Samples: e.Samples,
+		Rate:    e.Rate,
+		Trend:   e.Trend.String(),
+		Closed:  e.Closed,
+		Error:   errString(e.Err),
+	}
+	if completion, ok := e.Completion(); ok {
+		eta := e.ETA.Seconds()
+		t.ETA = &eta
+		t.Completion = &completion
+	}
+	return t
+}
+
+// call is the JSON representation of a callstat.Call.
+type call struct {
+	Description string    `json:"description"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    float64   `json:"duration"` // In seconds
+	Error       string    `json:"error,omitempty"`
+	Inner       []call    `json:"inner,omitempty"`
+}
+
+func makeCall(c *callstat.Call) call {
+	out := call{
+		Description: c.Description,
+		Start:       c.Start,
+		End:         c.End,
+		Duration:    c.Duration().Seconds(),
+		Error:       errString(c.Err),
+	}
+	for i := range c.Inner {
+		out.Inner = append(out.Inner, makeCall(&c.Inner[i]))
+	}
+	return out
+}
+
+// endpoint is the JSON representation of a helper.EndpointState.
+type endpoint struct {
+	Server      string     `json:"server"`
+	Online      bool       `json:"online"`
+	Error       string     `json:"error,omitempty"`
+	Changed     time.Time  `json:"changed"`
+	Updated     time.Time  `json:"updated"`
+	IdleSince   time.Time  `json:"idleSince"`
+	Calls       int        `json:"calls"`       // Number of outstanding calls
+	MaxCallTime float64    `json:"maxCallTime"` // Elapsed time of the oldest outstanding call in seconds
+	Latency     float64    `json:"latency"`     // Duration of recent calls at the configured percentile in seconds
+	Breaker     string     `json:"breaker"`     // State of the circuit breaker
+	Cause       string     `json:"cause,omitempty"`
+	Failures    uint       `json:"failures,omitempty"` // Number of consecutive failures
+	Retry       *time.Time `json:"retry,omitempty"`    // Time of the next connection attempt
+}
+
+func makeEndpoints(states map[string]helper.EndpointState) []endpoint {
+	endpoints := make([]endpoint, 0, len(states))
+	for server, state := range states {
+		endpoints = append(endpoints, endpoint{
+			Server:      server,
+			Online:      state.Online(),
+			Error:       errString(state.Err),
+			Changed:     state.Changed,
+			Updated:     state.Updated,
+			IdleSince:   state.IdleSince,
+			Calls:       state.Calls.Len(),
+			MaxCallTime: state.Calls.MaxElapsed().Seconds(),
+			Latency:     state.Latency.Seconds(),
+			Breaker:     state.Breaker.String(),
+			Cause:       errString(state.Cause),
+			Failures:    state.Failures,
+			Retry:       timePtr(state.Retry),
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Server < endpoints[j].Server
+	})
+	return endpoints
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Stream event types.
+const (
+	eventStart   = "start"   // An update has started
+	eventBacklog = "backlog" // A backlog value has been retrieved
+	eventEnd     = "end"     // An update has finished
+	eventTrend   = "trend"   // A backlog trend has been estimated
+	eventBreaker = "breaker" // The circuit breaker of an endpoint has changed state
+	eventDropped = "dropped" // Events were discarded because the client fell behind
+)
+
+// startEvent is the data of a start event.
+type startEvent struct {
+	Start time.Time `json:"start"`
+	Size  int       `json:"size"` // Number of expected backlogs
+}
+
+// endEvent is the data of an end event.
+type endEvent struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration float64   `json:"duration"` // In seconds
+	Size     int       `json:"size"`     // Number of expected backlogs
+	Received int       `json:"received"` // Number of backlogs retrieved
+	Complete bool      `json:"complete"` // False if the update was canceled
+}
+
+// droppedEvent is the data of a dropped event.
+type droppedEvent struct {
+	Dropped uint64 `json:"dropped"` // Number of events discarded since the last dropped event
+}
+
+// breakerEvent is the data of a breaker event.
+type breakerEvent struct {
+	Server   string     `json:"server"`
+	Time     time.Time  `json:"time"`
+	From     string     `json:"from"`
+	To       string     `json:"to"`
+	Cause    string     `json:"cause,omitempty"`
+	Failures uint       `json:"failures,omitempty"` // Number of consecutive failures
+	Retry    *time.Time `json:"retry,omitempty"`    // Time of the next connection attempt
+}
+
+func makeBreakerEvent(e *helper.BreakerEvent) breakerEvent {
+	return breakerEvent{
+		Server:   e.Server,
+		Time:     e.Time,
+		From:     e.From.String(),
+		To:       e.To.String(),
+		Cause:    errString(e.Cause),
+		Failures: e.Failures,
+		Retry:    timePtr(e.Retry),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"gopkg.in/dfsr.v0/fanout"
 	"gopkg.in/dfsr.v0/helper"
 	"gopkg.in/dfsr.v0/poller"
 	"gopkg.in/dfsr.v0/valuesink"
@@ -11,23 +12,32 @@ import (
 
 // Monitor represents a DFSR backlog monitor for a domain.
 type Monitor struct {
-	sink valuesink.Sink // Will hold last known global current state. Not yet used.
-	bc   broadcaster    // Broadcasts configuration updates
+	sink valuesink.Sink     // Will hold last known global current state. Not yet used.
+	bc   broadcaster        // Broadcasts configuration updates
+	hbc  fanout.Broadcaster // Broadcasts member health updates
+	ebc  fanout.Broadcaster // Broadcasts endpoint circuit breaker events
 
-	mutex    sync.Mutex
-	source   Source
-	interval time.Duration
-	timeout  time.Duration
-	cache    time.Duration
-	limit    uint
-	instance *poller.Poller
-	closed   bool
+	mutex          sync.Mutex
+	source         Source
+	interval       time.Duration
+	timeout        time.Duration
+	cache          time.Duration
+	limit          uint
+	healthInterval time.Duration
+	instance       *poller.Poller
+	health         *poller.Poller // Health instance, if health polling is enabled
+	client         *helper.Client // Client of the running instance
+	closed         bool
 }
 
 // New creates a new Monitor with the given source and polling interval.
 //
 // If cache is nonzero then the monitor will cache version vectors for
-// the given duration.
+// the given duration. It will also compare the cached vectors of the members
+// of each connection and skip the backlog query of connections whose
+// receiving member already holds every version of the sending member. A
+// backlog that builds up within the cache duration may therefore be reported
+// as zero until the sending member's vector is refreshed.
 //
 // If limit is nonzero then the monitor will limit the number of active
 // queries to an individual DFSR member to the given value.
@@ -54,13 +64,23 @@ func (m *Monitor) Close() {
 	}
 	m.closed = true
 
-	if m.instance != nil {
-		m.instance.Close() // Blocks until the instance completely winds down
-		m.instance = nil
-	}
+	m.stop()
 
 	m.sink.Close()
 	m.bc.Close()
+	m.hbc.Close()
+	m.ebc.Close()
+}
+
+// SetHealthInterval enables periodic retrieval of member health reports with
+// the given polling interval. If interval is zero health reports will not be
+// retrieved, which is the default.
+//
+// The interval takes effect the next time the monitor is started.
+func (m *Monitor) SetHealthInterval(interval time.Duration) {
+	m.mutex.Lock()
+	m.healthInterval = interval
+	m.mutex.Unlock()
 }
 
 // Start starts the monitor. If the monitor is already running start does
@@ -97,13 +117,25 @@ func (m *Monitor) Start() error {
 
 	client := helper.NewClientWithConfig(config)
 
+	m.client = client
 	m.instance = poller.New(&worker{
 		client: client,
 		source: m.source,
 		sink:   &m.sink,
 		bc:     &m.bc,
+		triage: config.Caching,
 	}, m.interval, m.timeout)
 
+	if m.healthInterval > 0 {
+		m.health = poller.New(&healthWorker{
+			client: client,
+			source: m.source,
+			bc:     &m.hbc,
+		}, m.healthInterval, m.timeout)
+	}
+
+	go relayBreakerEvents(client.Listen(breakerChanSize), m.instance, m.health, m.interval, m.healthInterval, &m.ebc)
+
 	return nil
 }
 
@@ -111,11 +143,23 @@ func (m *Monitor) Start() error {
 // start is called again.
 func (m *Monitor) Stop() {
 	m.mutex.Lock()
+	m.stop() // TODO: Decide whether blocking here is acceptable
+	m.mutex.Unlock()
+}
+
+// stop closes the running instances of the monitor. The health instance is
+// closed first because it shares the client that is closed by the backlog
+// instance. The caller must hold a lock on the monitor.
+func (m *Monitor) stop() {
+	if m.health != nil {
+		m.health.Close() // Blocks until the instance completely winds down
+		m.health = nil
+	}
 	if m.instance != nil {
-		m.instance.Close() // TODO: Decide whether blocking here is acceptable
+		m.instance.Close() // Blocks until the instance completely winds down
 		m.instance = nil
+		m.client = nil
 	}
-	m.mutex.Unlock()
 }
 
 // Update requests immediate retrieval of DFSR backlogs. It does not wait for
@@ -131,6 +175,32 @@ func (m *Monitor) Update() {
 	m.mutex.Unlock()
 }
 
+// UpdateHealth requests immediate retrieval of member health reports. It does
+// not wait for the retrieval to complete.
+//
+// If the monitor has not been started or health polling is not enabled
+// UpdateHealth will do nothing.
+func (m *Monitor) UpdateHealth() {
+	m.mutex.Lock()
+	if !m.closed && m.health != nil {
+		m.health.Poll()
+	}
+	m.mutex.Unlock()
+}
+
+// States returns the current state of each DFSR endpoint that the monitor has
+// queried, keyed by lower-case fully qualified domain name. If the monitor is
+// not running it returns nil.
+func (m *Monitor) States() map[string]helper.EndpointState {
+	m.mutex.Lock()
+	client := m.client
+	m.mutex.Unlock()
+	if client == nil {
+		return nil
+	}
+	return client.States()
+}
+
 // Listen returns a channel on which DFSR backlog updates will be broadcast.
 // The channel will be closed when the monitor is closed or when unlisten is
 // called for the returned channel.
@@ -160,3 +230,55 @@ func (m *Monitor) Listen(chanSize int) <-chan *Update {
 func (m *Monitor) Unlisten(c <-chan *Update) (found bool) {
 	return m.bc.Unlisten(c)
 }
+
+// ListenHealth returns a channel on which member health updates will be
+// broadcast. One update is sent for each member in the domain each time the
+// health reports are polled. The channel will be closed when the monitor is
+// closed or when UnlistenHealth is called for the returned channel.
+//
+// The returned channel will use the provided channel buffer size. Unlike
+// Listen, the monitor does not block when a listener's channel buffer is full.
+// The update is discarded for that listener instead, so that a slow listener
+// never delays polling.
+//
+// Health updates are only sent when health polling has been enabled with a
+// call to SetHealthInterval.
+func (m *Monitor) ListenHealth(chanSize int) <-chan *MemberHealth {
+	ch := make(chan *MemberHealth, chanSize)
+	m.hbc.Add(ch)
+	return ch
+}
+
+// UnlistenHealth closes the given listener's channel and removes it from the
+// set of listeners that receive member health updates.
+//
+// UnlistenHealth returns false if the listener was not present.
+func (m *Monitor) UnlistenHealth(c <-chan *MemberHealth) (found bool) {
+	return m.hbc.Remove(c)
+}
+
+// ListenBreaker returns a channel on which the circuit breaker events of the
+// DFSR endpoints queried by the monitor will be broadcast. The channel will be
+// closed when the monitor is closed or when UnlistenBreaker is called for the
+// returned channel.
+//
+// The returned channel will use the provided channel buffer size. As with
+// ListenHealth, events that do not fit in a listener's channel buffer are
+// discarded.
+//
+// When the circuit of an endpoint becomes half-open the monitor requests an
+// immediate update, so that a member which has come back online is queried
+// without waiting for the next polling interval.
+func (m *Monitor) ListenBreaker(chanSize int) <-chan helper.BreakerEvent {
+	ch := make(chan helper.BreakerEvent, chanSize)
+	m.ebc.Add(ch)
+	return ch
+}
+
+// UnlistenBreaker closes the given listener's channel and removes it from the
+// set of listeners that receive circuit breaker events.
+//
+// UnlistenBreaker returns false if the listener was not present.
+func (m *Monitor) UnlistenBreaker(c <-chan helper.BreakerEvent) (found bool) {
+	return m.ebc.Remove(c)
+}
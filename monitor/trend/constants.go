@@ -0,0 +1,24 @@
+package trend
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// DefaultConfig is the default configuration of an Estimator.
+var DefaultConfig = Config{
+	Samples:    12,
+	MinSamples: 3,
+	MaxAge:     2 * time.Hour,
+}
+
+// maxETA is the longest ETA that will be reported. Very slow drain rates
+// would otherwise overflow time.Duration.
+const maxETA = time.Duration(math.MaxInt64)
+
+var (
+	// ErrIncomplete is the estimate error for backlog queries that did not
+	// return a valid backlog for every replicated folder.
+	ErrIncomplete = errors.New("backlog query did not return values for all replicated folders")
+)
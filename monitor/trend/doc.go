@@ -0,0 +1,23 @@
+// Package trend estimates how quickly DFSR backlogs are draining.
+//
+// An Estimator consumes the updates broadcast by a monitor and keeps a short
+// series of recent backlog samples for each connection. After each sample it
+// fits a least-squares regression line to the series. The slope of the line
+// is the rate at which the backlog is changing. When the backlog is draining
+// the estimator projects the time at which it will reach zero.
+//
+// Estimates are broadcast to listeners as they are produced, and the most
+// recent estimate for each connection can be retrieved at any time:
+//
+//   est := trend.New(trend.DefaultConfig)
+//   est.Consume(mon.Listen(16))
+//   for estimate := range est.Listen(16) {
+//     if estimate.Trend == trend.Draining {
+//       log.Printf("%s: done in %v", estimate.Connection, estimate.ETA)
+//     }
+//   }
+//
+// Samples taken while a connection's schedule is closed are included in the
+// series. Such estimates are marked as closed, because a backlog cannot drain
+// while replication is not permitted.
+package trend
@@ -0,0 +1,270 @@
+package trend
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/fanout"
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/consumerutil"
+)
+
+// series holds the recent samples of a connection.
+type series struct {
+	samples  []sample
+	estimate Estimate // Most recent estimate
+}
+
+// Estimator estimates backlog trends from DFSR monitor updates and broadcasts
+// the resulting estimates to its listeners.
+//
+// The zero value of an estimator is not suitable for use. Estimators should be
+// created with a call to New().
+type Estimator struct {
+	config Config
+
+	bc fanout.Broadcaster // Broadcasts estimates
+
+	mutex  sync.Mutex // Serializes evaluation
+	series map[Connection]*series
+}
+
+// New returns a new estimator with the given configuration.
+func New(config Config) *Estimator {
+	if config.Samples < 2 {
+		config.Samples = 2
+	}
+	if config.MinSamples < 2 {
+		config.MinSamples = 2
+	}
+	return &Estimator{
+		config: config,
+		series: make(map[Connection]*series),
+	}
+}
+
+// Consume causes the estimator to evaluate the updates received on the given
+// channel in its own goroutine. When the channel is closed the estimator will
+// be closed as well.
+func (e *Estimator) Consume(updates <-chan *monitor.Update) {
+	consumerutil.Consume(updates, func(update *monitor.Update) {
+		e.EvaluateUpdate(update)
+	}, e.Close)
+}
+
+// Close closes the channels of all listeners. Further evaluation will not
+// broadcast estimates.
+func (e *Estimator) Close() {
+	e.bc.Close()
+}
+
+// Listen returns a channel on which estimates will be broadcast. The channel
+// will be closed when the estimator is closed or when unlisten is called for
+// the returned channel.
+//
+// The returned channel will use the provided channel buffer size. Evaluation
+// never blocks on a listener. Estimates that do not fit in a listener's buffer
+// are discarded for that listener and counted in the statistics returned by
+// Stats. Each update produces one estimate per connection.
+func (e *Estimator) Listen(chanSize int) <-chan Estimate {
+	ch := make(chan Estimate, chanSize)
+	e.bc.Add(ch)
+	return ch
+}
+
+// Unlisten closes the given listener's channel and removes it from the set of
+// listeners that receive estimates.
+//
+// Unlisten returns false if the listener was not present.
+func (e *Estimator) Unlisten(ch <-chan Estimate) (found bool) {
+	return e.bc.Remove(ch)
+}
+
+// Stats returns statistics about the delivery of estimates to listeners.
+func (e *Estimator) Stats() fanout.Stats {
+	return e.bc.Stats()
+}
+
+// EvaluateUpdate evaluates all of the backlog values in update as they are
+// received and returns the resulting estimates. It blocks until the update
+// has finished.
+//
+// If every value in the update was received, the samples of connections that
+// were not part of the update are discarded.
+func (e *Estimator) EvaluateUpdate(update *monitor.Update) (estimates []Estimate) {
+	seen := make(map[Connection]bool, update.Size())
+	for backlog := range update.Listen() {
+		seen[makeConnection(backlog)] = true
+		estimates = append(estimates, e.Evaluate(backlog))
+	}
+
+	if len(seen) < update.Size() {
+		// The update was canceled or contained duplicate connections
+		return
+	}
+
+	e.prune(seen)
+	return
+}
+
+// Evaluate records a single backlog value and returns the resulting estimate
+// for its connection.
+func (e *Estimator) Evaluate(backlog *dfsr.Backlog) Estimate {
+	conn := makeConnection(backlog)
+
+	t := backlog.Call.Start
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	err := backlog.Err
+	if err == nil && !complete(backlog) {
+		err = ErrIncomplete
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	s, found := e.series[conn]
+	if !found {
+		s = &series{}
+		e.series[conn] = s
+	}
+
+	if err == nil {
+		s.add(sample{time: t, backlog: backlog.Sum()}, e.config)
+		s.estimate = s.evaluate(conn, e.config)
+	}
+	s.estimate.Connection = conn
+	s.estimate.Closed = backlog.Closed
+	s.estimate.Err = err
+
+	e.bc.Send(s.estimate)
+	return s.estimate
+}
+
+// Estimate returns the most recent estimate for the given connection. Member
+// names are matched without regard to case.
+func (e *Estimator) Estimate(conn Connection) (estimate Estimate, ok bool) {
+	conn.From = strings.ToLower(conn.From)
+	conn.To = strings.ToLower(conn.To)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	s, ok := e.series[conn]
+	if !ok {
+		return Estimate{}, false
+	}
+	return s.estimate, true
+}
+
+// Estimates returns the most recent estimate for each connection, ordered by
+// group, sending member and receiving member.
+func (e *Estimator) Estimates() (estimates []Estimate) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	estimates = make([]Estimate, 0, len(e.series))
+	for _, s := range e.series {
+		estimates = append(estimates, s.estimate)
+	}
+	sort.Slice(estimates, func(i, j int) bool {
+		a, b := &estimates[i].Connection, &estimates[j].Connection
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return
+}
+
+// prune discards the samples of connections that are not present in seen.
+func (e *Estimator) prune(seen map[Connection]bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for conn := range e.series {
+		if !seen[conn] {
+			delete(e.series, conn)
+		}
+	}
+}
+
+// add appends a sample to the series and discards samples that exceed the
+// configured count or age.
+func (s *series) add(next sample, config Config) {
+	// Samples must be ordered in time, so start over if the clock went back
+	if n := len(s.samples); n > 0 && !next.time.After(s.samples[n-1].time) {
+		s.samples = s.samples[:0]
+	}
+	s.samples = append(s.samples, next)
+
+	start := 0
+	if excess := len(s.samples) - config.Samples; excess > 0 {
+		start = excess
+	}
+	if config.MaxAge > 0 {
+		for start < len(s.samples)-1 && next.time.Sub(s.samples[start].time) > config.MaxAge {
+			start++
+		}
+	}
+	if start > 0 {
+		s.samples = append(s.samples[:0], s.samples[start:]...)
+	}
+}
+
+// evaluate returns an estimate for the samples in the series.
+func (s *series) evaluate(conn Connection, config Config) (estimate Estimate) {
+	last := s.samples[len(s.samples)-1]
+	estimate = Estimate{
+		Connection: conn,
+		Time:       last.time,
+		Backlog:    last.backlog,
+		Samples:    len(s.samples),
+	}
+
+	if last.backlog == 0 {
+		estimate.Trend = Idle
+		return
+	}
+
+	if len(s.samples) < config.MinSamples {
+		return
+	}
+
+	slope, ok := fit(s.samples)
+	if !ok {
+		return
+	}
+
+	estimate.Rate = slope
+	estimate.Trend = classify(s.samples, slope)
+	if estimate.Trend == Draining {
+		seconds := float64(last.backlog) / -slope
+		if seconds < maxETA.Seconds() {
+			estimate.ETA = time.Duration(seconds * float64(time.Second))
+		} else {
+			estimate.ETA = maxETA
+		}
+	}
+	return
+}
+
+func complete(backlog *dfsr.Backlog) bool {
+	if len(backlog.Folders) == 0 {
+		return false
+	}
+	for f := range backlog.Folders {
+		if backlog.Folders[f].Backlog < 0 {
+			return false
+		}
+	}
+	return true
+}
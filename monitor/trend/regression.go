@@ -0,0 +1,55 @@
+package trend
+
+import "math"
+
+// fit returns the slope of the least-squares regression line through the
+// given samples, in backlog per second. It returns false if the samples do
+// not span any time.
+func fit(samples []sample) (slope float64, ok bool) {
+	n := float64(len(samples))
+	if n < 2 {
+		return 0, false
+	}
+
+	// Measure time relative to the first sample to preserve precision
+	origin := samples[0].time
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.time.Sub(origin).Seconds()
+		sumY += float64(s.backlog)
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxy, sxx float64
+	for _, s := range samples {
+		dx := s.time.Sub(origin).Seconds() - meanX
+		dy := float64(s.backlog) - meanY
+		sxy += dx * dy
+		sxx += dx * dx
+	}
+	if sxx == 0 {
+		return 0, false
+	}
+
+	return sxy / sxx, true
+}
+
+// classify determines the trend of a series of samples with the given slope.
+// A backlog is considered stalled if the regression line changes by less than
+// one file over the span of the samples.
+func classify(samples []sample, slope float64) Trend {
+	last := samples[len(samples)-1]
+	if last.backlog == 0 {
+		return Idle
+	}
+
+	span := last.time.Sub(samples[0].time).Seconds()
+	switch change := slope * span; {
+	case math.Abs(change) < 1:
+		return Stalled
+	case change < 0:
+		return Draining
+	default:
+		return Growing
+	}
+}
@@ -0,0 +1,108 @@
+package trend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+)
+
+// Config holds the configuration of an Estimator.
+type Config struct {
+	Samples    int           // Maximum number of samples retained for each connection
+	MinSamples int           // Minimum number of samples needed to estimate a trend
+	MaxAge     time.Duration // Samples older than this are discarded, if nonzero
+}
+
+// Connection identifies a one-way connection between replication group
+// members. Member names are stored in lower case so that connections can be
+// compared and used as map keys.
+type Connection struct {
+	GroupID uuid.UUID
+	Group   string // Replication group name
+	From    string // Fully qualified domain name of the sending member
+	To      string // Fully qualified domain name of the receiving member
+}
+
+// String returns a string representation of the connection.
+func (c Connection) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Group, c.From, c.To)
+}
+
+// makeConnection returns the connection described by backlog.
+func makeConnection(backlog *dfsr.Backlog) (conn Connection) {
+	if backlog.Group != nil {
+		conn.GroupID = backlog.Group.ID
+		conn.Group = backlog.Group.Name
+	}
+	conn.From = strings.ToLower(backlog.From)
+	conn.To = strings.ToLower(backlog.To)
+	return
+}
+
+// Trend describes the direction in which a backlog is moving.
+type Trend int
+
+// Backlog trends.
+const (
+	Unknown  Trend = iota // Not enough samples to estimate a trend
+	Idle                  // No backlog
+	Draining              // Backlog is shrinking
+	Stalled               // Backlog is present but not changing
+	Growing               // Backlog is growing
+)
+
+// String returns a string representation of the trend.
+func (t Trend) String() string {
+	switch t {
+	case Unknown:
+		return "unknown"
+	case Idle:
+		return "idle"
+	case Draining:
+		return "draining"
+	case Stalled:
+		return "stalled"
+	case Growing:
+		return "growing"
+	default:
+		return fmt.Sprintf("trend %d", int(t))
+	}
+}
+
+// Estimate describes the recent behavior of the backlog of a connection.
+//
+// Rate is the change in backlog per second. It is negative when the backlog
+// is draining. ETA is the estimated time from Time until the backlog reaches
+// zero, and is only populated when Trend is Draining.
+//
+// If the most recent backlog query failed, Err holds its error and the
+// remaining fields describe the samples that were collected before it.
+type Estimate struct {
+	Connection Connection
+	Time       time.Time // Time of the most recent sample
+	Backlog    uint      // Most recent backlog
+	Samples    int       // Number of samples the estimate is based on
+	Rate       float64
+	Trend      Trend
+	ETA        time.Duration
+	Closed     bool // The connection was scheduled closed at the most recent query
+	Err        error
+}
+
+// Completion returns the estimated time at which the backlog will reach zero.
+// It returns false if the backlog is not draining.
+func (e *Estimate) Completion() (t time.Time, ok bool) {
+	if e.Trend != Draining {
+		return
+	}
+	return e.Time.Add(e.ETA), true
+}
+
+// sample is a single successful backlog query.
+type sample struct {
+	time    time.Time
+	backlog uint
+}
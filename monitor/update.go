@@ -52,7 +52,7 @@ func (u *Update) Listen() <-chan *dfsr.Backlog {
 		ch <- v
 	}
 
-	if u.canceled {
+	if u.canceled || u.remaining == 0 {
 		close(ch)
 		return ch
 	}
@@ -28,9 +28,10 @@ func connections(domain *dfsr.Domain) (output []*dfsr.Backlog) {
 				}
 
 				output = append(output, &dfsr.Backlog{
-					Group: group,
-					From:  from,
-					To:    to,
+					Group:      group,
+					Connection: conn,
+					From:       from,
+					To:         to,
 				})
 			}
 		}
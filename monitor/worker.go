@@ -5,19 +5,29 @@ import (
 	"sync"
 	"time"
 
+	"gopkg.in/dfsr.v0/callstat"
 	"gopkg.in/dfsr.v0/dfsr"
 	"gopkg.in/dfsr.v0/helper"
 	"gopkg.in/dfsr.v0/valuesink"
+	"gopkg.in/dfsr.v0/versionvector"
 )
 
 // worker acts as a polling source for poller. It retrieves domain configuration
 // data from a configuration source, queries the DFSR backlog for all enabled
 // DFSR connections in the domain and sends backlog updates via a broadcaster.
+//
+// When triage is true the version vectors of the members of each connection
+// are compared before the backlog is queried. Connections whose receiving
+// member already holds every version of the sending member have no backlog,
+// so their backlog query is skipped. With vector caching enabled this costs
+// one vector query per member and group, which makes a full mesh much cheaper
+// to poll when most of its connections are in sync.
 type worker struct {
 	source Source
 	client *helper.Client
 	sink   *valuesink.Sink
 	bc     *broadcaster
+	triage bool
 }
 
 func (w *worker) Close() {
@@ -81,8 +91,18 @@ func (w *worker) compute(ctx context.Context, backlog *dfsr.Backlog, updates []*
 		return
 	}
 
+	backlog.Annotate(time.Now())
+
 	var values []int
-	values, backlog.Call, backlog.Err = w.client.Backlog(ctx, backlog.From, backlog.To, backlog.Group.ID)
+	backlog.Call.Begin("Monitor.Backlog")
+	if w.triage && w.inSync(ctx, backlog) {
+		values = make([]int, len(backlog.Group.Folders))
+	} else {
+		var call callstat.Call
+		values, call, backlog.Err = w.client.Backlog(ctx, backlog.From, backlog.To, backlog.Group.ID)
+		backlog.Call.Add(&call)
+	}
+	backlog.Call.Complete(backlog.Err)
 	computed.Done()
 
 	if n := len(values); n == len(backlog.Group.Folders) {
@@ -99,3 +119,24 @@ func (w *worker) compute(ctx context.Context, backlog *dfsr.Backlog, updates []*
 	}
 	sent.Done()
 }
+
+// inSync returns true if the receiving member of backlog holds every version
+// held by the sending member, according to their version vectors. The vector
+// queries are recorded in the call of backlog. Any failure is treated as
+// divergence, so that the backlog query that follows can report it.
+func (w *worker) inSync(ctx context.Context, backlog *dfsr.Backlog) bool {
+	from, fcall, err := w.client.Vector(ctx, backlog.From, backlog.Group.ID)
+	backlog.Call.Add(&fcall)
+	if err != nil {
+		return false
+	}
+
+	to, tcall, err := w.client.Vector(ctx, backlog.To, backlog.Group.ID)
+	backlog.Call.Add(&tcall)
+	if err != nil {
+		return false
+	}
+
+	delta, err := versionvector.Diff(from, to)
+	return err == nil && delta.Empty()
+}
@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/helper"
+	"gopkg.in/dfsr.v0/helper/fake"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+func TestWorkerTriage(t *testing.T) {
+	var (
+		groupID = uuid.MustParse("3c6a1f4e-5b2d-4e8a-9f10-2b7c8d9e0a11")
+		db1     = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+		db2     = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+		group   = &dfsr.Group{ID: groupID, Name: "Example", Folders: []dfsr.Folder{{Name: "Data"}}}
+	)
+
+	fleet := fake.NewFleet()
+	fs1, fs2 := fleet.Add("fs1.example.com"), fleet.Add("fs2.example.com")
+	fs1.Host(groupID, 1)
+	fs2.Host(groupID, 1)
+	fs2.SetBacklog(groupID, "fs1.example.com", 7)
+
+	// FS2 holds every version of FS1, but not the other way around
+	fs1.SetVector(groupID, versionvector.New(versionvector.Folder{Entries: []versionvector.Entry{
+		{Database: db1, Low: 1, High: 100},
+	}}))
+	fs2.SetVector(groupID, versionvector.New(versionvector.Folder{Entries: []versionvector.Entry{
+		{Database: db1, Low: 1, High: 100},
+		{Database: db2, Low: 1, High: 7},
+	}}))
+
+	client := helper.NewClientWithConfig(fleet.Config(helper.DefaultEndpointConfig))
+	defer client.Close()
+	w := &worker{client: client, triage: true}
+
+	compute := func(from, to string) *dfsr.Backlog {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		backlog := &dfsr.Backlog{Group: group, From: from, To: to}
+		var computed, sent sync.WaitGroup
+		computed.Add(1)
+		sent.Add(1)
+		w.compute(ctx, backlog, nil, &computed, &sent)
+		if backlog.Err != nil {
+			t.Fatalf("backlog from %s to %s failed: %v", from, to, backlog.Err)
+		}
+		return backlog
+	}
+
+	if backlog := compute("fs1.example.com", "fs2.example.com"); !backlog.IsZero() {
+		t.Errorf("in-sync connection has backlog %+v", backlog.Folders)
+	}
+	if n := fs1.Stats().Backlog; n != 0 {
+		t.Errorf("backlog of an in-sync connection was queried %d times", n)
+	}
+
+	backlog := compute("fs2.example.com", "fs1.example.com")
+	if len(backlog.Folders) != 1 || backlog.Folders[0].Backlog != 7 {
+		t.Errorf("diverged connection has backlog %+v", backlog.Folders)
+	}
+	if n := fs2.Stats().Backlog; n != 1 {
+		t.Errorf("backlog of a diverged connection was queried %d times", n)
+	}
+
+	// Vectors are cached, so each member is only asked for its vector once
+	if n := []int{fs1.Stats().Vector, fs2.Stats().Vector}; !reflect.DeepEqual(n, []int{1, 1}) {
+		t.Errorf("members were asked for their vectors %v times", n)
+	}
+}
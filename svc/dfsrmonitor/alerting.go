@@ -0,0 +1,114 @@
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/alert"
+	"gopkg.in/dfsr.v0/monitor/alert/notify"
+)
+
+const alertChanSize = 64
+
+// startAlerting evaluates alert rules against the updates of mon and delivers
+// the resulting notifications to the destinations given in settings. If no
+// destinations are configured it does nothing.
+func startAlerting(settings Settings, mon *monitor.Monitor) error {
+	routes, err := alertRoutes(settings)
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+
+	rules := alertRules(settings)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	engine := alert.New(rules...)
+	dispatcher := notify.New(routes...)
+	dispatcher.Consume(engine.Listen(alertChanSize))
+	engine.Consume(mon.Listen(updateChanSize))
+	return nil
+}
+
+func alertRules(settings Settings) (rules []alert.Rule) {
+	if settings.AlertBacklog > 0 {
+		rules = append(rules, alert.Threshold{
+			RuleName: "backlog",
+			Above:    settings.AlertBacklog,
+			For:      settings.AlertBacklogDuration,
+			Clear:    settings.AlertBacklog / 2,
+		})
+	}
+	if settings.AlertFailures > 0 {
+		rules = append(rules, alert.Failure{
+			RuleName: "failure",
+			Count:    settings.AlertFailures,
+		})
+	}
+	if settings.AlertGrowth > 0 {
+		rules = append(rules, alert.Growth{
+			RuleName: "growth",
+			Updates:  settings.AlertGrowth,
+		})
+	}
+	return
+}
+
+func alertRoutes(settings Settings) (routes []notify.Route, err error) {
+	route := func(name string, notifier notify.Notifier) notify.Route {
+		r := notify.DefaultRoute
+		r.Name = name
+		r.Notifier = notifier
+		r.Limit = settings.NotifyLimit
+		r.Interval = time.Hour
+		r.OnError = func(msg notify.Message, err error) {
+			elog.Warning(1, fmt.Sprintf("Failed to deliver %s notification \"%s\": %v", name, msg.Subject, err))
+		}
+		return r
+	}
+
+	if settings.WebhookURL != "" || settings.PagerDutyKey != "" {
+		format, err := notify.ParseFormat(settings.WebhookFormat)
+		if err != nil {
+			return nil, err
+		}
+		url := settings.WebhookURL
+		if settings.PagerDutyKey != "" {
+			format = notify.PagerDuty
+			if url == "" {
+				url = notify.PagerDutyURL
+			}
+		}
+		routes = append(routes, route("webhook", &notify.Webhook{
+			URL:        url,
+			Format:     format,
+			RoutingKey: settings.PagerDutyKey,
+		}))
+	}
+
+	if settings.SMTPServer != "" {
+		routes = append(routes, route("email", &notify.SMTP{
+			Addr:     settings.SMTPServer,
+			From:     settings.SMTPFrom,
+			To:       splitList(settings.SMTPTo),
+			Username: settings.SMTPUsername,
+			Password: settings.SMTPPassword,
+		}))
+	}
+
+	return
+}
+
+func splitList(s string) (values []string) {
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return
+}
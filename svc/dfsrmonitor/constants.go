@@ -2,6 +2,11 @@
 
 package main
 
+import (
+	"errors"
+	"time"
+)
+
 // Windows Service Properties
 const (
 	DefaultServiceName = "dfsrmonitor"
@@ -9,12 +14,28 @@ const (
 	DefaultDescription = "Monitors DFSR backlog counts"
 )
 
+// secretsFileName is the name of the file that holds secret settings. It is
+// stored in the same directory as the service executable.
+const secretsFileName = "secrets.json"
+
+// configRetryInterval is the time between attempts to reach the domain while
+// the service is running from a configuration snapshot.
+const configRetryInterval = time.Minute
+
+// errConfigTimeout is returned when the domain configuration is not retrieved
+// within the configuration polling timeout at startup.
+var errConfigTimeout = errors.New("timed out waiting for the domain configuration")
+
+// redacted replaces secret values in logged settings.
+const redacted = "[redacted]"
+
 // Error constants
 const (
 	_ = iota // 0 == success
 	ErrGeneric
 	ErrConfigInitFailure
 	ErrBacklogInitFailure
+	ErrConsumerInitFailure
 )
 
 // Event constants
@@ -22,4 +43,5 @@ const (
 	EventInitProgress = iota + 1
 	EventInitComplete
 	EventInitFailure
+	EventConfigChange
 )
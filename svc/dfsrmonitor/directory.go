@@ -0,0 +1,96 @@
+// +build windows
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/go-ldap/ldap/v3/gssapi"
+	"gopkg.in/adsi.v0"
+	"gopkg.in/dfsr.v0/dfsrconfig"
+	"gopkg.in/dfsr.v0/dfsrconfig/ldapconfig"
+)
+
+// Directory settings values.
+const (
+	directoryADSI = "adsi" // Query Active Directory through ADSI
+	directoryLDAP = "ldap" // Query a domain controller located through ADSI over LDAP
+)
+
+// newConfigMonitor returns a domain configuration monitor that polls the
+// directory selected by settings. The returned function releases the resources
+// held by the directory. It must be called after the monitor has been closed.
+//
+// LDAP directories support change tracking, which allows the monitor to
+// re-fetch only the replication groups that have changed since it last polled.
+// ADSI does not.
+func newConfigMonitor(settings Settings) (cfg *dfsrconfig.DomainMonitor, release func(), err error) {
+	var dial func() (*ldap.Conn, error)
+	switch strings.ToLower(settings.Directory) {
+	case directoryADSI:
+		cfg = dfsrconfig.NewDomainMonitor(settings.Domain, settings.ConfigPollingInterval, settings.ConfigPollingTimeout)
+		return cfg, func() {}, nil
+	case "", directoryLDAP:
+		dial = func() (*ldap.Conn, error) {
+			host, err := domainController()
+			if err != nil {
+				return nil, err
+			}
+			return dialLDAP("ldap://"+host, settings.ConfigPollingTimeout)
+		}
+	default:
+		dial = func() (*ldap.Conn, error) {
+			return dialLDAP(settings.Directory, settings.ConfigPollingTimeout)
+		}
+	}
+
+	conn := ldapconfig.NewRedialer(dial)
+	domain := settings.Domain
+	if domain == "" {
+		domain, err = ldapconfig.DefaultNamingContext(conn)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	cfg = dfsrconfig.NewDomainMonitorWithDirectory(ldapconfig.NewDirectory(conn), domain, settings.ConfigPollingInterval, settings.ConfigPollingTimeout)
+	return cfg, conn.Close, nil
+}
+
+// domainController returns the DNS name of a domain controller for the
+// domain of the local computer, as located by ADSI.
+func domainController() (host string, err error) {
+	client, err := adsi.NewClient()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	rootDSE, err := client.Open("LDAP://RootDSE")
+	if err != nil {
+		return
+	}
+	defer rootDSE.Close()
+
+	return rootDSE.AttrString("dnsHostName")
+}
+
+// dialLDAP connects to the directory server at url and binds with the
+// credentials of the account the service is running as.
+func dialLDAP(url string, timeout time.Duration) (*ldap.Conn, error) {
+	client, err := gssapi.NewSSPIClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return ldapconfig.Dial(ldapconfig.Config{
+		URL:          url,
+		Timeout:      timeout,
+		Bind:         ldapconfig.GSSAPI,
+		GSSAPIClient: client,
+	})
+}
@@ -49,6 +49,14 @@ func installService(env *Environment) error {
 		}
 	}
 
+	// Keep secrets out of the service command line, which any user can read
+	if secrets := env.Settings.Secrets(); !secrets.IsZero() {
+		path := secretsPath(env.InstallPath)
+		if err = saveSecrets(path, secrets, env.Account); err != nil {
+			return fmt.Errorf("unable to save secrets to \"%s\": %v", path, err)
+		}
+	}
+
 	// Prep the service configuration
 	conf := mgr.Config{
 		StartType:   mgr.StartAutomatic,
@@ -0,0 +1,94 @@
+// +build windows
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hectane/go-acl"
+	"github.com/hectane/go-acl/api"
+	"golang.org/x/sys/windows"
+)
+
+// Secrets holds the settings that are kept out of the service's command line.
+// The command line is stored in the ImagePath of the service, which can be
+// read by all users. Secrets are stored in a file next to the service
+// executable that only the service account and administrators can read.
+type Secrets struct {
+	SMTPPassword string `json:"smtpPassword,omitempty"`
+	PagerDutyKey string `json:"pagerDutyKey,omitempty"`
+}
+
+// IsZero returns true if s holds no secrets.
+func (s Secrets) IsZero() bool {
+	return s == Secrets{}
+}
+
+// secretsPath returns the path of the secrets file for the service executable
+// at exePath.
+func secretsPath(exePath string) string {
+	return filepath.Join(filepath.Dir(exePath), secretsFileName)
+}
+
+// loadSecrets reads secrets from the file at path. If the file does not exist
+// it returns empty secrets without an error.
+func loadSecrets(path string) (secrets Secrets, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &secrets)
+	return
+}
+
+// saveSecrets writes secrets to the file at path. Access to the file is
+// restricted to the local system account, administrators and the given
+// service account, if any, before the secrets are written.
+func saveSecrets(path string, secrets Secrets, account string) error {
+	data, err := json.MarshalIndent(secrets, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	if err = protect(path, account); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// protect replaces the access control list of path with one that grants full
+// control to the local system account and administrators, and read access to
+// the given account, if any. Inherited permissions are removed.
+func protect(path, account string) error {
+	system, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	if err != nil {
+		return err
+	}
+	admins, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	if err != nil {
+		return err
+	}
+
+	entries := []api.ExplicitAccess{
+		acl.GrantSid(windows.GENERIC_ALL, system),
+		acl.GrantSid(windows.GENERIC_ALL, admins),
+	}
+	if account != "" {
+		entries = append(entries, acl.GrantName(windows.GENERIC_READ, account))
+	}
+	return acl.Apply(path, true, false, entries...)
+}
@@ -6,13 +6,22 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"gopkg.in/dfsr.v0/dfsr/snapshot"
 	"gopkg.in/dfsr.v0/dfsrconfig"
 	"gopkg.in/dfsr.v0/monitor"
+	"gopkg.in/dfsr.v0/monitor/consumer/graphiteconsumer"
+	"gopkg.in/dfsr.v0/monitor/consumer/influxconsumer"
+	"gopkg.in/dfsr.v0/monitor/consumer/linesender"
+	"gopkg.in/dfsr.v0/monitor/consumer/promconsumer"
 	"gopkg.in/dfsr.v0/monitor/consumer/stathatconsumer"
+	"gopkg.in/dfsr.v0/monitor/history"
+	"gopkg.in/dfsr.v0/monitor/httpapi"
 
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/debug"
@@ -42,40 +51,143 @@ func (m *dfsrmonitor) Execute(args []string, r <-chan svc.ChangeRequest, changes
 		} else if len(os.Args) > 1 {
 			settings.Parse(os.Args[1:], flag.ExitOnError)
 		}
-		elog.Info(1, fmt.Sprintf("Service Settings: %+v", settings))
+	}
+	if exe, err := os.Executable(); err == nil {
+		secrets, err := loadSecrets(secretsPath(exe))
+		if err != nil {
+			elog.Warning(EventInitProgress, fmt.Sprintf("Failed to load secrets: %v", err))
+		}
+		settings.ApplySecrets(secrets)
+	}
+	if !environment.IsInteractive {
+		elog.Info(1, fmt.Sprintf("Service Settings: %+v", settings.Redacted()))
 	}
 
 	// Step 2: Create and start configuration monitor
+	//
+	// If the domain cannot be reached and a snapshot is available the service
+	// starts from the snapshot and keeps trying to reach the domain. Once the
+	// configuration monitor is ready the service switches over to it.
 	elog.Info(EventInitProgress, "Creating configuration monitor.")
-	cfg := dfsrconfig.NewDomainMonitor(settings.Domain, settings.ConfigPollingInterval, settings.ConfigPollingTimeout)
-	if err := cfg.Start(); err != nil {
-		elog.Error(EventInitFailure, fmt.Sprintf("Configuration initialization failure: %v", err))
-		return true, ErrConfigInitFailure
-	}
-	defer cfg.Close()
+	closeConfig := func() {}
+	defer func() { closeConfig() }() // Runs after the backlog monitor is closed
 
-	cfg.Update()
-	if err := cfg.WaitReady(); err != nil { // TODO: Support some sort of timeout
-		elog.Error(EventInitFailure, fmt.Sprintf("Configuration initialization failure: %v", err))
-		return true, ErrConfigInitFailure
+	var (
+		source   monitor.Source
+		fallback *switchSource
+		cfgChan  <-chan dfsrconfig.DomainUpdate
+		cfgReady <-chan liveConfig
+	)
+	if live, err := openConfig(settings); err == nil {
+		closeConfig = live.close
+		source = live.cfg
+		cfgChan = live.cfg.ListenChanges()
+		if settings.SnapshotPath != "" {
+			go saveSnapshots(settings.SnapshotPath, live.updates)
+		}
+	} else {
+		if settings.SnapshotPath == "" {
+			elog.Error(EventInitFailure, fmt.Sprintf("Configuration initialization failure: %v", err))
+			return true, ErrConfigInitFailure
+		}
+		elog.Warning(EventInitProgress, fmt.Sprintf("Configuration initialization failure: %v. Using configuration snapshot %s until the domain can be reached.", err, settings.SnapshotPath))
+		snap, err := snapshot.NewSource(settings.SnapshotPath)
+		if err != nil {
+			elog.Error(EventInitFailure, fmt.Sprintf("Configuration snapshot failure: %v", err))
+			return true, ErrConfigInitFailure
+		}
+		fallback = newSwitchSource(snap)
+		source = fallback
+
+		done := make(chan struct{})
+		defer close(done)
+		cfgReady = retryConfig(settings, done)
 	}
 
 	// Step 3: Create backlog monitor
+	//
+	// The history store is opened first so that the monitor, which closes the
+	// store's listener, is closed before the store.
+	var store *history.Store
+	if settings.HistoryPath != "" {
+		var err error
+		if store, err = history.Open(settings.HistoryPath, nil); err != nil {
+			elog.Error(EventInitFailure, fmt.Sprintf("History initialization failure: %v", err))
+			return true, ErrConsumerInitFailure
+		}
+		defer store.Close()
+	}
+
 	elog.Info(EventInitProgress, "Creating backlog monitor.")
-	mon := monitor.New(cfg, settings.BacklogPollingInterval, settings.BacklogPollingTimeout, settings.VectorCacheDuration, settings.Limit)
+	mon := monitor.New(source, settings.BacklogPollingInterval, settings.BacklogPollingTimeout, settings.VectorCacheDuration, settings.Limit)
+	defer mon.Close()
+	mon.SetHealthInterval(settings.HealthPollingInterval)
 	monChan := mon.Listen(updateChanSize)
 
 	// Step 4: Create backlog consumers
 	if settings.StatHatKey != "" {
 		stathatconsumer.New(settings.StatHatKey, settings.StatHatFormat, mon.Listen(updateChanSize))
 	}
+	servers := make(map[string]*http.ServeMux) // Maps listen addresses to handlers
+	serveMux := func(addr string) *http.ServeMux {
+		if mux, ok := servers[addr]; ok {
+			return mux
+		}
+		mux := http.NewServeMux()
+		servers[addr] = mux
+		return mux
+	}
+	if settings.PrometheusAddr != "" {
+		consumer := promconsumer.New(mon.Listen(updateChanSize))
+		if settings.HealthPollingInterval > 0 {
+			consumer.ConsumeHealth(mon.ListenHealth(updateChanSize))
+		}
+		serveMux(settings.PrometheusAddr).Handle("/metrics", consumer)
+	}
+	if settings.APIAddr != "" {
+		serveMux(settings.APIAddr).Handle("/api/", http.StripPrefix("/api", httpapi.New(source, mon)))
+	}
+	for addr, mux := range servers {
+		go func(addr string, mux *http.ServeMux) {
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				elog.Error(1, fmt.Sprintf("HTTP endpoint failure on %s: %v", addr, err))
+			}
+		}(addr, mux)
+	}
+	if settings.InfluxURL != "" {
+		config := linesender.DefaultConfig
+		config.URL = settings.InfluxURL
+		if _, err := influxconsumer.New(config, "", nil, mon.Listen(updateChanSize)); err != nil {
+			elog.Error(EventInitFailure, fmt.Sprintf("InfluxDB consumer initialization failure: %v", err))
+			return true, ErrConsumerInitFailure
+		}
+	}
+	if settings.GraphiteURL != "" {
+		config := linesender.DefaultConfig
+		config.URL = settings.GraphiteURL
+		if _, err := graphiteconsumer.New(config, settings.GraphiteFormat, "", mon.Listen(updateChanSize)); err != nil {
+			elog.Error(EventInitFailure, fmt.Sprintf("Graphite consumer initialization failure: %v", err))
+			return true, ErrConsumerInitFailure
+		}
+	}
+
+	if store != nil {
+		store.Consume(mon.Listen(updateChanSize), func(err error) {
+			elog.Warning(1, fmt.Sprintf("Failed to record backlog history: %v", err))
+		})
+	}
+
+	// Step 5: Create alerting engine and notifiers
+	if err := startAlerting(settings, mon); err != nil {
+		elog.Error(EventInitFailure, fmt.Sprintf("Alerting initialization failure: %v", err))
+		return true, ErrConsumerInitFailure
+	}
 
-	// Step 5: Start backlog monitor
+	// Step 6: Start backlog monitor
 	if err := mon.Start(); err != nil {
 		elog.Error(EventInitFailure, fmt.Sprintf("Monitor initialization failure: %v", err))
 		return true, 1
 	}
-	defer mon.Close()
 
 	elog.Info(EventInitComplete, "Initialization complete.")
 
@@ -90,6 +202,21 @@ func (m *dfsrmonitor) Execute(args []string, r <-chan svc.ChangeRequest, changes
 				return
 			}
 			go watchUpdate(update)
+		case live := <-cfgReady:
+			cfgReady = nil
+			closeConfig = live.close
+			cfgChan = live.cfg.ListenChanges()
+			fallback.Set(live.cfg)
+			go saveSnapshots(settings.SnapshotPath, live.updates)
+			elog.Info(EventConfigChange, "Configuration retrieved from the domain. No longer using the configuration snapshot.")
+			mon.Update() // Pick up the current topology right away
+		case update, running := <-cfgChan:
+			if !running {
+				cfgChan = nil
+				continue
+			}
+			logChanges(update)
+			mon.Update() // Pick up the new topology right away
 		case c := <-r:
 			switch c.Cmd {
 			case svc.Interrogate:
@@ -173,6 +300,56 @@ func watchUpdate(update *monitor.Update) {
 	elog.Info(1, fmt.Sprintf("Polling finished at %v. Total wall time: %v", update.End(), update.Duration()))
 }
 
+// startConfig starts the configuration monitor and waits for it to retrieve
+// the domain configuration. If timeout is greater than zero and the
+// configuration has not been retrieved within it, errConfigTimeout is
+// returned and the monitor continues its retrieval in the background.
+func startConfig(cfg *dfsrconfig.DomainMonitor, timeout time.Duration) error {
+	if err := cfg.Start(); err != nil {
+		return err
+	}
+	cfg.Update()
+	if timeout <= 0 {
+		return cfg.WaitReady()
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		ready <- cfg.WaitReady() // Returns when the monitor is closed
+	}()
+
+	select {
+	case err := <-ready:
+		return err
+	case <-time.After(timeout):
+		return errConfigTimeout
+	}
+}
+
+// saveSnapshots writes each complete configuration received from updates to a
+// snapshot file at path until updates is closed. Configurations that carry an
+// error, including partial retrievals, are not saved so that they never
+// replace the last complete snapshot.
+func saveSnapshots(path string, updates <-chan dfsrconfig.DomainUpdate) {
+	for update := range updates {
+		if update.Err != nil {
+			continue
+		}
+		if err := snapshot.New(update.Domain, update.Timestamp).Save(path); err != nil {
+			elog.Warning(1, fmt.Sprintf("Failed to save configuration snapshot: %v", err))
+		}
+	}
+}
+
+func logChanges(update dfsrconfig.DomainUpdate) {
+	lines := make([]string, 0, len(update.Changes)+1)
+	lines = append(lines, fmt.Sprintf("Configuration changes detected at %v:", update.Timestamp))
+	for _, change := range update.Changes {
+		lines = append(lines, change.String())
+	}
+	elog.Info(EventConfigChange, strings.Join(lines, "\n"))
+}
+
 func isCancellationErr(err error) bool {
 	switch err {
 	case context.Canceled, context.DeadlineExceeded:
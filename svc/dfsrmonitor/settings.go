@@ -13,37 +13,85 @@ import (
 // Settings represents a set of DFSR monitor service configuration settings
 type Settings struct {
 	Domain                 string
+	Directory              string
 	ConfigPollingInterval  time.Duration
 	ConfigPollingTimeout   time.Duration
 	BacklogPollingInterval time.Duration
 	BacklogPollingTimeout  time.Duration
+	HealthPollingInterval  time.Duration
 	VectorCacheDuration    time.Duration
 	Limit                  uint
 	StatHatKey             string
 	StatHatFormat          string
+	PrometheusAddr         string
+	APIAddr                string
+	InfluxURL              string
+	GraphiteURL            string
+	GraphiteFormat         string
+	AlertBacklog           uint
+	AlertBacklogDuration   time.Duration
+	AlertFailures          int
+	AlertGrowth            int
+	WebhookURL             string
+	WebhookFormat          string
+	PagerDutyKey           string
+	SMTPServer             string
+	SMTPFrom               string
+	SMTPTo                 string
+	SMTPUsername           string
+	SMTPPassword           string
+	NotifyLimit            int
+	HistoryPath            string
+	SnapshotPath           string
 }
 
 // DefaultSettings is the default set of DFSR monitor settings.
 var DefaultSettings = Settings{
+	Directory:              directoryLDAP,
 	ConfigPollingInterval:  15 * time.Minute,
 	ConfigPollingTimeout:   2 * time.Minute,
 	BacklogPollingInterval: 5 * time.Minute,
 	BacklogPollingTimeout:  5 * time.Minute,
 	VectorCacheDuration:    30 * time.Second,
 	Limit:                  1,
+	AlertBacklogDuration:   30 * time.Minute,
+	AlertFailures:          3,
+	NotifyLimit:            30,
 }
 
 // Bind will link the settings to the provided flag set.
 func (s *Settings) Bind(fs *flag.FlagSet) {
 	fs.Var(bindflag.String(&s.Domain), "domain", "AD domain to monitor (will autodetect if not provided)")
+	fs.Var(bindflag.String(&s.Directory), "dir", "directory to poll for configuration: ldap (locates a domain controller), adsi, or an ldap:// or ldaps:// URL (ADSI does not support incremental refresh)")
 	fs.Var(bindflag.Duration(&s.ConfigPollingInterval), "cpi", "configuration polling interval")
-	fs.Var(bindflag.Duration(&s.ConfigPollingTimeout), "cpt", "configuration polling timeout")
+	fs.Var(bindflag.Duration(&s.ConfigPollingTimeout), "cpt", "configuration polling timeout, which also limits the wait for the domain at startup")
 	fs.Var(bindflag.Duration(&s.BacklogPollingInterval), "bpi", "backlog polling interval")
 	fs.Var(bindflag.Duration(&s.BacklogPollingTimeout), "bpt", "backlog polling timeout")
+	fs.Var(bindflag.Duration(&s.HealthPollingInterval), "hpi", "member health report polling interval (disabled if zero)")
 	fs.Var(bindflag.Duration(&s.VectorCacheDuration), "cache", "vector cache duration")
 	fs.Var(bindflag.Uint(&s.Limit), "limit", "maximum number of queries per server")
 	fs.Var(bindflag.String(&s.StatHatKey), "shk", "StatHat ezkey for StatHat reporting")
 	fs.Var(bindflag.String(&s.StatHatFormat), "shf", "StatHat name format in fmt style")
+	fs.Var(bindflag.String(&s.PrometheusAddr), "prom", "listen address for the Prometheus /metrics endpoint")
+	fs.Var(bindflag.String(&s.APIAddr), "api", "listen address for the /api JSON endpoints")
+	fs.Var(bindflag.String(&s.InfluxURL), "influx", "InfluxDB line protocol destination URL (tcp, udp, http or https)")
+	fs.Var(bindflag.String(&s.GraphiteURL), "graphite", "Graphite plaintext destination URL (tcp or udp)")
+	fs.Var(bindflag.String(&s.GraphiteFormat), "gf", "Graphite metric path format in fmt style")
+	fs.Var(bindflag.Uint(&s.AlertBacklog), "ab", "alert when a connection backlog stays above this value")
+	fs.Var(bindflag.Duration(&s.AlertBacklogDuration), "abd", "time a backlog must stay above the alert value")
+	fs.Var(bindflag.Int(&s.AlertFailures), "af", "alert after this many consecutive backlog query failures")
+	fs.Var(bindflag.Int(&s.AlertGrowth), "ag", "alert after a backlog grows across this many consecutive polls")
+	fs.Var(bindflag.String(&s.WebhookURL), "wh", "webhook URL for alert notifications")
+	fs.Var(bindflag.String(&s.WebhookFormat), "whf", "webhook payload format (generic, slack or pagerduty)")
+	fs.Var(bindflag.String(&s.PagerDutyKey), "pdk", "PagerDuty Events API v2 routing key (stored in a protected file on installation)")
+	fs.Var(bindflag.String(&s.SMTPServer), "smtp", "SMTP server address for alert notifications in host:port form")
+	fs.Var(bindflag.String(&s.SMTPFrom), "smtpfrom", "sender address for alert notification email")
+	fs.Var(bindflag.String(&s.SMTPTo), "smtpto", "comma-separated recipient addresses for alert notification email")
+	fs.Var(bindflag.String(&s.SMTPUsername), "smtpuser", "SMTP username")
+	fs.Var(bindflag.String(&s.SMTPPassword), "smtppass", "SMTP password (stored in a protected file on installation)")
+	fs.Var(bindflag.Int(&s.NotifyLimit), "nl", "maximum number of alert notifications per hour for each destination")
+	fs.Var(bindflag.String(&s.HistoryPath), "history", "path of the backlog history database")
+	fs.Var(bindflag.String(&s.SnapshotPath), "snapshot", "path of a configuration snapshot that is kept current and used until the domain can be reached")
 }
 
 // Parse parses the given argument list and applies the specified values.
@@ -53,12 +101,47 @@ func (s *Settings) Parse(args []string, errorHandling flag.ErrorHandling) (err e
 	return fs.Parse(args)
 }
 
+// Secrets returns the secret values of the settings.
+func (s *Settings) Secrets() Secrets {
+	return Secrets{
+		SMTPPassword: s.SMTPPassword,
+		PagerDutyKey: s.PagerDutyKey,
+	}
+}
+
+// ApplySecrets applies the given secret values to settings that have not
+// already been provided.
+func (s *Settings) ApplySecrets(secrets Secrets) {
+	if s.SMTPPassword == "" {
+		s.SMTPPassword = secrets.SMTPPassword
+	}
+	if s.PagerDutyKey == "" {
+		s.PagerDutyKey = secrets.PagerDutyKey
+	}
+}
+
+// Redacted returns a copy of the settings with secret values masked, which is
+// suitable for logging.
+func (s Settings) Redacted() Settings {
+	if s.SMTPPassword != "" {
+		s.SMTPPassword = redacted
+	}
+	if s.PagerDutyKey != "" {
+		s.PagerDutyKey = redacted
+	}
+	return s
+}
+
 // Args returns the current settings as a set of command line arguments that can
-// be passed back into the service.
+// be passed back into the service. Secret values are not included. They are
+// saved to a protected file by the installer instead.
 func (s *Settings) Args() (args []string) {
 	if s.Domain != "" {
 		args = append(args, makeArg("domain", s.Domain))
 	}
+	if s.Directory != "" {
+		args = append(args, makeArg("dir", s.Directory))
+	}
 	if s.ConfigPollingInterval != time.Duration(0) {
 		args = append(args, makeArg("cpi", s.ConfigPollingInterval.String()))
 	}
@@ -71,6 +154,9 @@ func (s *Settings) Args() (args []string) {
 	if s.BacklogPollingTimeout != time.Duration(0) {
 		args = append(args, makeArg("bpt", s.BacklogPollingTimeout.String()))
 	}
+	if s.HealthPollingInterval != time.Duration(0) {
+		args = append(args, makeArg("hpi", s.HealthPollingInterval.String()))
+	}
 	if s.VectorCacheDuration != time.Duration(0) {
 		args = append(args, makeArg("cache", s.VectorCacheDuration.String()))
 	}
@@ -83,5 +169,59 @@ func (s *Settings) Args() (args []string) {
 	if s.StatHatFormat != "" {
 		args = append(args, makeArg("shf", s.StatHatFormat))
 	}
+	if s.PrometheusAddr != "" {
+		args = append(args, makeArg("prom", s.PrometheusAddr))
+	}
+	if s.APIAddr != "" {
+		args = append(args, makeArg("api", s.APIAddr))
+	}
+	if s.InfluxURL != "" {
+		args = append(args, makeArg("influx", s.InfluxURL))
+	}
+	if s.GraphiteURL != "" {
+		args = append(args, makeArg("graphite", s.GraphiteURL))
+	}
+	if s.GraphiteFormat != "" {
+		args = append(args, makeArg("gf", s.GraphiteFormat))
+	}
+	if s.AlertBacklog != 0 {
+		args = append(args, makeArg("ab", fmt.Sprintf("%v", s.AlertBacklog)))
+	}
+	if s.AlertBacklogDuration != time.Duration(0) {
+		args = append(args, makeArg("abd", s.AlertBacklogDuration.String()))
+	}
+	if s.AlertFailures != 0 {
+		args = append(args, makeArg("af", fmt.Sprintf("%v", s.AlertFailures)))
+	}
+	if s.AlertGrowth != 0 {
+		args = append(args, makeArg("ag", fmt.Sprintf("%v", s.AlertGrowth)))
+	}
+	if s.WebhookURL != "" {
+		args = append(args, makeArg("wh", s.WebhookURL))
+	}
+	if s.WebhookFormat != "" {
+		args = append(args, makeArg("whf", s.WebhookFormat))
+	}
+	if s.SMTPServer != "" {
+		args = append(args, makeArg("smtp", s.SMTPServer))
+	}
+	if s.SMTPFrom != "" {
+		args = append(args, makeArg("smtpfrom", s.SMTPFrom))
+	}
+	if s.SMTPTo != "" {
+		args = append(args, makeArg("smtpto", s.SMTPTo))
+	}
+	if s.SMTPUsername != "" {
+		args = append(args, makeArg("smtpuser", s.SMTPUsername))
+	}
+	if s.NotifyLimit != 0 {
+		args = append(args, makeArg("nl", fmt.Sprintf("%v", s.NotifyLimit)))
+	}
+	if s.HistoryPath != "" {
+		args = append(args, makeArg("history", s.HistoryPath))
+	}
+	if s.SnapshotPath != "" {
+		args = append(args, makeArg("snapshot", s.SnapshotPath))
+	}
 	return
 }
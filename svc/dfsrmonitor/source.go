@@ -0,0 +1,122 @@
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gopkg.in/dfsr.v0/dfsr"
+	"gopkg.in/dfsr.v0/dfsrconfig"
+	"gopkg.in/dfsr.v0/monitor"
+)
+
+var _ = (monitor.Source)((*switchSource)(nil)) // Compile-time interface compliance check
+
+// switchSource is a configuration source that delegates to another source,
+// which can be replaced while the backlog monitor is running. It allows the
+// service to start from a configuration snapshot and to switch to the domain
+// configuration monitor once the domain can be reached.
+type switchSource struct {
+	mutex   sync.RWMutex
+	current monitor.Source
+}
+
+func newSwitchSource(initial monitor.Source) *switchSource {
+	return &switchSource{current: initial}
+}
+
+// Value returns the configuration of the current source.
+func (s *switchSource) Value() (*dfsr.Domain, time.Time, error) {
+	s.mutex.RLock()
+	current := s.current
+	s.mutex.RUnlock()
+	return current.Value()
+}
+
+// Set replaces the current source.
+func (s *switchSource) Set(source monitor.Source) {
+	s.mutex.Lock()
+	s.current = source
+	s.mutex.Unlock()
+}
+
+// liveConfig is a configuration monitor that has retrieved the domain
+// configuration, along with the function that closes it and releases its
+// directory.
+//
+// When a snapshot path is configured, updates receives every update of the
+// monitor, starting with its first retrieval. Otherwise it is nil.
+type liveConfig struct {
+	cfg     *dfsrconfig.DomainMonitor
+	updates <-chan dfsrconfig.DomainUpdate
+	close   func()
+}
+
+// openConfig creates and starts a configuration monitor and waits up to the
+// configuration polling timeout for it to retrieve the domain configuration.
+// If it fails the monitor is closed before the error is returned.
+func openConfig(settings Settings) (live liveConfig, err error) {
+	cfg, releaseDir, err := newConfigMonitor(settings)
+	if err != nil {
+		return
+	}
+
+	var updates <-chan dfsrconfig.DomainUpdate
+	if settings.SnapshotPath != "" {
+		updates = cfg.Listen() // Subscribe before the first retrieval
+	}
+
+	if err = startConfig(cfg, settings.ConfigPollingTimeout); err != nil {
+		closeConfig := func() {
+			cfg.Close()
+			releaseDir()
+		}
+		if err == errConfigTimeout {
+			go closeConfig() // Close waits for the slow retrieval to wind down
+		} else {
+			closeConfig()
+		}
+		return
+	}
+	return liveConfig{
+		cfg:     cfg,
+		updates: updates,
+		close: func() {
+			cfg.Close()
+			releaseDir() // Runs after the monitor is closed
+		},
+	}, nil
+}
+
+// retryConfig attempts to open a configuration monitor every
+// configRetryInterval until it succeeds or done is closed. The monitor is
+// delivered on the returned channel. If done is closed before the monitor is
+// received the monitor is closed instead.
+func retryConfig(settings Settings, done <-chan struct{}) <-chan liveConfig {
+	ch := make(chan liveConfig)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(configRetryInterval):
+			}
+
+			live, err := openConfig(settings)
+			if err != nil {
+				elog.Warning(EventInitProgress, fmt.Sprintf("Configuration initialization failure: %v. Retrying in %v.", err, configRetryInterval))
+				continue
+			}
+
+			select {
+			case ch <- live:
+			case <-done:
+				live.close()
+			}
+			return
+		}
+	}()
+	return ch
+}
@@ -78,12 +78,7 @@ func (cache *Cache) Value(guid uuid.UUID) (vector *Vector, call callstat.Call, o
 	v, ok := cache.c.Value(guid)
 	if ok {
 		e := v.(entry)
-		var err error
-		vector, err = e.vector.Duplicate()
-		if err != nil {
-			ok = false
-			return
-		}
+		vector = e.vector.Duplicate()
 		call = e.call
 	}
 	return
@@ -113,6 +108,6 @@ func (cache *Cache) Lookup(ctx context.Context, guid uuid.UUID, tracker dfsr.Tra
 	}
 	e := v.(entry)
 	call.Add(&e.call)
-	vector, err = e.vector.Duplicate()
+	vector = e.vector.Duplicate()
 	return
 }
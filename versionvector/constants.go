@@ -0,0 +1,13 @@
+package versionvector
+
+import "errors"
+
+var (
+	// ErrInvalidLength is returned when serialized version vector data is not
+	// a multiple of EntrySize.
+	ErrInvalidLength = errors.New("version vector data has an invalid length")
+
+	// ErrFolderMismatch is returned when comparing version vectors that
+	// contain a different number of replicated folders.
+	ErrFolderMismatch = errors.New("version vectors contain a different number of replicated folders")
+)
@@ -0,0 +1,204 @@
+package versionvector
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Delta describes the version ranges that are present in one version vector
+// but absent from another. It holds a folder vector for each replicated folder
+// in the compared vectors, in the same order.
+//
+// The entries of each folder are sorted by database and then by version, and
+// do not overlap.
+type Delta struct {
+	Folders []Folder
+}
+
+// Comparison holds the result of comparing the version vectors of two members
+// of a replication group.
+type Comparison struct {
+	Ahead  *Delta // Versions held by the first member that the second lacks
+	Behind *Delta // Versions held by the second member that the first lacks
+}
+
+// InSync returns true if neither member holds versions that the other lacks.
+func (c Comparison) InSync() bool {
+	return c.Ahead.Empty() && c.Behind.Empty()
+}
+
+// Compare returns the differences between the version vectors of two members
+// of the same replication group. Folders are paired as described for Diff.
+//
+// The vectors must contain the same number of replicated folders, otherwise
+// ErrFolderMismatch will be returned.
+func Compare(vector, other *Vector) (c Comparison, err error) {
+	if c.Ahead, err = Diff(vector, other); err != nil {
+		return
+	}
+	c.Behind, err = Diff(other, vector)
+	return
+}
+
+// Diff returns the version ranges that are present in have but absent from
+// lack.
+//
+// Version vectors do not identify their replicated folders, so folders are
+// paired by position and the vectors must list their folders in the same
+// order. Vectors retrieved from members of the same replication group are
+// relied upon to do so, as the backlog queries of the DFSR service also pair
+// a reference vector with the sending member's folders by position. Diff
+// verifies that the vectors contain the same number of replicated folders and
+// returns ErrFolderMismatch otherwise, which catches members that have not yet
+// picked up an added or removed folder.
+func Diff(have, lack *Vector) (delta *Delta, err error) {
+	if len(have.Folders) != len(lack.Folders) {
+		return nil, ErrFolderMismatch
+	}
+
+	delta = &Delta{Folders: make([]Folder, len(have.Folders))}
+	for i := range have.Folders {
+		delta.Folders[i] = DiffFolder(have.Folders[i], lack.Folders[i])
+	}
+	return
+}
+
+// DiffFolder returns the version ranges that are present in have but absent
+// from lack. The entries of the returned folder are sorted by database and then
+// by version, and do not overlap.
+func DiffFolder(have, lack Folder) (delta Folder) {
+	h := normalize(have.Entries)
+	l := normalize(lack.Entries)
+
+	j := 0
+	for _, entry := range h {
+		// Skip ranges in lack that precede the entry
+		for j < len(l) && (compareDatabase(l[j].Database, entry.Database) < 0 || (l[j].Database == entry.Database && l[j].High < entry.Low)) {
+			j++
+		}
+
+		// Subtract each overlapping range in lack from the entry
+		low := entry.Low
+		remaining := true
+		for k := j; remaining && k < len(l) && l[k].Database == entry.Database && l[k].Low <= entry.High; k++ {
+			if l[k].Low > low {
+				delta.Entries = append(delta.Entries, Entry{Database: entry.Database, Low: low, High: l[k].Low - 1})
+			}
+			if l[k].High >= entry.High {
+				remaining = false
+			} else {
+				low = l[k].High + 1
+			}
+		}
+		if remaining {
+			delta.Entries = append(delta.Entries, Entry{Database: entry.Database, Low: low, High: entry.High})
+		}
+	}
+	return
+}
+
+// Empty returns true if the delta does not contain any versions. A nil delta
+// is considered empty.
+func (delta *Delta) Empty() bool {
+	if delta == nil {
+		return true
+	}
+	for _, folder := range delta.Folders {
+		if len(folder.Entries) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Versions returns the total number of versions contained in the delta.
+//
+// Version numbers are allocated for each change committed to a DFSR database,
+// so this serves as an estimate of divergence rather than a count of files.
+// The backlog reported by the DFSR service will usually be lower.
+func (delta *Delta) Versions() (total uint64) {
+	if delta == nil {
+		return 0
+	}
+	for _, folder := range delta.Folders {
+		total += folder.Versions()
+	}
+	return
+}
+
+// Databases returns the originating databases that are present in the delta,
+// in sorted order.
+func (delta *Delta) Databases() (databases []uuid.UUID) {
+	if delta == nil {
+		return nil
+	}
+	seen := make(map[uuid.UUID]bool)
+	for _, folder := range delta.Folders {
+		for _, entry := range folder.Entries {
+			if !seen[entry.Database] {
+				seen[entry.Database] = true
+				databases = append(databases, entry.Database)
+			}
+		}
+	}
+	sort.Slice(databases, func(i, j int) bool {
+		return compareDatabase(databases[i], databases[j]) < 0
+	})
+	return
+}
+
+// Versions returns the total number of versions contained in the folder
+// vector. Overlapping entries are only counted once.
+func (folder Folder) Versions() (total uint64) {
+	for _, entry := range normalize(folder.Entries) {
+		total += entry.Versions()
+	}
+	return
+}
+
+// Versions returns the number of versions in the entry's range. Ranges are
+// inclusive of both the low and high version numbers. An entry with a high
+// version number lower than its low version number is empty.
+func (entry Entry) Versions() uint64 {
+	if entry.High < entry.Low {
+		return 0
+	}
+	return entry.High - entry.Low + 1
+}
+
+// normalize returns a sorted copy of the given entries with empty ranges
+// removed and overlapping or adjacent ranges for the same database merged.
+func normalize(entries []Entry) (normalized []Entry) {
+	sorted := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.High >= entry.Low {
+			sorted = append(sorted, entry)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := compareDatabase(sorted[i].Database, sorted[j].Database); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Low < sorted[j].Low
+	})
+
+	for _, entry := range sorted {
+		if n := len(normalized); n > 0 {
+			last := &normalized[n-1]
+			if last.Database == entry.Database && (entry.Low <= last.High || entry.Low-1 == last.High) {
+				if entry.High > last.High {
+					last.High = entry.High
+				}
+				continue
+			}
+		}
+		normalized = append(normalized, entry)
+	}
+	return
+}
+
+func compareDatabase(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,194 @@
+package versionvector_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"gopkg.in/dfsr.v0/versionvector"
+)
+
+var (
+	db1 = uuid.MustParse("11111111-1111-4111-8111-111111111111")
+	db2 = uuid.MustParse("22222222-2222-4222-8222-222222222222")
+	db3 = uuid.MustParse("33333333-3333-4333-8333-333333333333")
+)
+
+func folder(entries ...versionvector.Entry) versionvector.Folder {
+	return versionvector.Folder{Entries: entries}
+}
+
+func TestDiffFolder(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		have, lack versionvector.Folder
+		want       []versionvector.Entry
+	}{
+		{
+			name: "identical",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+		},
+		{
+			name: "empty lack",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+			want: []versionvector.Entry{{Database: db1, Low: 1, High: 100}},
+		},
+		{
+			name: "ahead",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: 150}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+			want: []versionvector.Entry{{Database: db1, Low: 101, High: 150}},
+		},
+		{
+			name: "adjacent ranges in have are merged",
+			have: folder(
+				versionvector.Entry{Database: db1, Low: 51, High: 100},
+				versionvector.Entry{Database: db1, Low: 1, High: 50},
+			),
+			lack: folder(versionvector.Entry{Database: db1, Low: 1, High: 40}),
+			want: []versionvector.Entry{{Database: db1, Low: 41, High: 100}},
+		},
+		{
+			name: "adjacent ranges in lack cover have",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+			lack: folder(
+				versionvector.Entry{Database: db1, Low: 1, High: 50},
+				versionvector.Entry{Database: db1, Low: 51, High: 100},
+			),
+		},
+		{
+			name: "overlapping ranges",
+			have: folder(
+				versionvector.Entry{Database: db1, Low: 1, High: 60},
+				versionvector.Entry{Database: db1, Low: 40, High: 100},
+			),
+			lack: folder(
+				versionvector.Entry{Database: db1, Low: 10, High: 20},
+				versionvector.Entry{Database: db1, Low: 15, High: 30},
+				versionvector.Entry{Database: db1, Low: 90, High: 200},
+			),
+			want: []versionvector.Entry{
+				{Database: db1, Low: 1, High: 9},
+				{Database: db1, Low: 31, High: 89},
+			},
+		},
+		{
+			name: "low of zero",
+			have: folder(versionvector.Entry{Database: db1, Low: 0, High: 10}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 5, High: 10}),
+			want: []versionvector.Entry{{Database: db1, Low: 0, High: 4}},
+		},
+		{
+			name: "lack starts at zero",
+			have: folder(versionvector.Entry{Database: db1, Low: 0, High: 10}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 0, High: 3}),
+			want: []versionvector.Entry{{Database: db1, Low: 4, High: 10}},
+		},
+		{
+			name: "high of max",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: math.MaxUint64}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+			want: []versionvector.Entry{{Database: db1, Low: 101, High: math.MaxUint64}},
+		},
+		{
+			name: "lack reaches max",
+			have: folder(versionvector.Entry{Database: db1, Low: 1, High: math.MaxUint64}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 100, High: math.MaxUint64}),
+			want: []versionvector.Entry{{Database: db1, Low: 1, High: 99}},
+		},
+		{
+			name: "full range",
+			have: folder(versionvector.Entry{Database: db1, Low: 0, High: math.MaxUint64}),
+			lack: folder(
+				versionvector.Entry{Database: db1, Low: 0, High: 9},
+				versionvector.Entry{Database: db1, Low: 20, High: math.MaxUint64},
+			),
+			want: []versionvector.Entry{{Database: db1, Low: 10, High: 19}},
+		},
+		{
+			name: "empty ranges are ignored",
+			have: folder(versionvector.Entry{Database: db1, Low: 10, High: 5}),
+			lack: folder(versionvector.Entry{Database: db1, Low: 1, High: 1}),
+		},
+		{
+			name: "multiple databases",
+			have: folder(
+				versionvector.Entry{Database: db3, Low: 1, High: 30},
+				versionvector.Entry{Database: db1, Low: 1, High: 10},
+				versionvector.Entry{Database: db2, Low: 1, High: 20},
+			),
+			lack: folder(
+				versionvector.Entry{Database: db2, Low: 1, High: 20},
+				versionvector.Entry{Database: db1, Low: 1, High: 5},
+				versionvector.Entry{Database: db3, Low: 11, High: 40},
+			),
+			want: []versionvector.Entry{
+				{Database: db1, Low: 6, High: 10},
+				{Database: db3, Low: 1, High: 10},
+			},
+		},
+		{
+			name: "ranges of other databases do not subtract",
+			have: folder(versionvector.Entry{Database: db2, Low: 1, High: 10}),
+			lack: folder(
+				versionvector.Entry{Database: db1, Low: 1, High: 100},
+				versionvector.Entry{Database: db3, Low: 1, High: 100},
+			),
+			want: []versionvector.Entry{{Database: db2, Low: 1, High: 10}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := versionvector.DiffFolder(tt.have, tt.lack)
+			if !reflect.DeepEqual(delta.Entries, tt.want) {
+				t.Errorf("DiffFolder returned %+v, want %+v", delta.Entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	have := versionvector.New(
+		folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+		folder(versionvector.Entry{Database: db2, Low: 1, High: 50}),
+	)
+	lack := versionvector.New(
+		folder(versionvector.Entry{Database: db1, Low: 1, High: 100}),
+		folder(versionvector.Entry{Database: db2, Low: 1, High: 20}),
+	)
+
+	// Folders are paired by position
+	delta, err := versionvector.Diff(have, lack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delta.Folders) != 2 || len(delta.Folders[0].Entries) != 0 {
+		t.Fatalf("Diff returned %+v", delta)
+	}
+	if want := []versionvector.Entry{{Database: db2, Low: 21, High: 50}}; !reflect.DeepEqual(delta.Folders[1].Entries, want) {
+		t.Errorf("Diff returned %+v for the second folder, want %+v", delta.Folders[1].Entries, want)
+	}
+	if delta.Versions() != 30 || delta.Empty() {
+		t.Errorf("delta holds %d versions", delta.Versions())
+	}
+	if dbs := delta.Databases(); !reflect.DeepEqual(dbs, []uuid.UUID{db2}) {
+		t.Errorf("delta databases are %v", dbs)
+	}
+
+	c, err := versionvector.Compare(have, lack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.InSync() || !c.Behind.Empty() || c.Ahead.Versions() != 30 {
+		t.Errorf("unexpected comparison %+v", c)
+	}
+
+	if c, err = versionvector.Compare(have, have.Duplicate()); err != nil || !c.InSync() {
+		t.Errorf("comparison of identical vectors returned %+v, %v", c, err)
+	}
+
+	if _, err = versionvector.Diff(have, versionvector.New(folder())); err != versionvector.ErrFolderMismatch {
+		t.Errorf("Diff of mismatched vectors returned %v", err)
+	}
+}
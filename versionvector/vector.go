@@ -1,43 +1,164 @@
 package versionvector
 
 import (
-	"github.com/go-ole/go-ole"
-	"github.com/scjalliance/comutil"
+	"encoding/binary"
+
+	"github.com/google/uuid"
 )
 
-// Vector represents version vector data from a replication group member.
+// EntrySize is the number of bytes occupied by a single serialized version
+// vector entry.
+const EntrySize = 32
+
+// Vector represents version vector data from a replication group member. It
+// holds a folder vector for each replicated folder in the group, in the order
+// in which they were provided by the member.
+//
+// Vectors do not hold any system resources and are safe to copy, store and
+// compare on any platform.
 type Vector struct {
-	sa *ole.SafeArrayConversion
+	Folders []Folder
+}
+
+// Folder represents the version vector of a replicated folder.
+type Folder struct {
+	Entries []Entry
+}
+
+// Entry is a range of versions that originated from a particular DFSR
+// database.
+//
+// [MS-FRS2]: FRS_VERSION_VECTOR
+type Entry struct {
+	Database uuid.UUID // Originating database GUID
+	Low      uint64    // Lowest version number in the range
+	High     uint64    // Highest version number in the range
+}
+
+// New returns a new version vector containing the given folder vectors.
+func New(folders ...Folder) *Vector {
+	return &Vector{Folders: folders}
 }
 
-// New returns a new version vector for the given safe array of data.
-func New(sa *ole.SafeArrayConversion) (vector *Vector, err error) {
-	return &Vector{
-		sa: sa,
-	}, nil
+// Decode returns a new version vector for the given serialized folder vectors.
+// Each element of data must contain the serialized entries of one replicated
+// folder, as produced by Folder.Encode.
+func Decode(data [][]byte) (vector *Vector, err error) {
+	vector = &Vector{Folders: make([]Folder, len(data))}
+	for i := range data {
+		vector.Folders[i], err = DecodeFolder(data[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
 }
 
-// Data returns the version vector data as a safe array.
-func (vector *Vector) Data() (sa *ole.SafeArrayConversion) {
-	return vector.sa
+// Encode returns the serialized form of each folder vector contained in the
+// vector.
+func (vector *Vector) Encode() (data [][]byte) {
+	data = make([][]byte, len(vector.Folders))
+	for i := range vector.Folders {
+		data[i] = vector.Folders[i].Encode()
+	}
+	return
 }
 
 // Duplicate will return a duplicate of the vector that does not share any
 // memory with the original.
-func (vector *Vector) Duplicate() (duplicate *Vector, err error) {
-	sa, err := comutil.SafeArrayCopy(vector.Data().Array)
-	if err != nil {
+func (vector *Vector) Duplicate() (duplicate *Vector) {
+	duplicate = &Vector{Folders: make([]Folder, len(vector.Folders))}
+	for i := range vector.Folders {
+		duplicate.Folders[i] = vector.Folders[i].Duplicate()
+	}
+	return
+}
+
+// Equal returns true if vector and other contain identical folder vectors.
+func (vector *Vector) Equal(other *Vector) bool {
+	if vector == nil || other == nil {
+		return vector == other
+	}
+	if len(vector.Folders) != len(other.Folders) {
+		return false
+	}
+	for i := range vector.Folders {
+		if !vector.Folders[i].Equal(other.Folders[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeFolder returns the folder vector for the given serialized entries.
+//
+// Each entry is serialized as a database GUID in its little-endian Windows
+// representation, followed by the low and high version numbers as
+// little-endian 64-bit unsigned integers.
+func DecodeFolder(data []byte) (folder Folder, err error) {
+	if len(data)%EntrySize != 0 {
+		err = ErrInvalidLength
+		return
+	}
+
+	n := len(data) / EntrySize
+	if n == 0 {
 		return
 	}
 
-	return New(&ole.SafeArrayConversion{Array: sa})
+	folder.Entries = make([]Entry, n)
+	for i := 0; i < n; i++ {
+		b := data[i*EntrySize : (i+1)*EntrySize]
+		entry := &folder.Entries[i]
+		copy(entry.Database[:], b[0:16])
+		littleEndianToBigEndian(&entry.Database)
+		entry.Low = binary.LittleEndian.Uint64(b[16:24])
+		entry.High = binary.LittleEndian.Uint64(b[24:32])
+	}
+	return
 }
 
-// Close will release any resources consumed by the version vector. It should be
-// called when finished with the version vector.
-func (vector *Vector) Close() {
-	if vector.sa != nil {
-		vector.sa.Release()
-		vector.sa = nil
+// Encode returns the serialized form of the folder vector.
+func (folder Folder) Encode() (data []byte) {
+	data = make([]byte, len(folder.Entries)*EntrySize)
+	for i, entry := range folder.Entries {
+		b := data[i*EntrySize : (i+1)*EntrySize]
+		db := entry.Database
+		littleEndianToBigEndian(&db)
+		copy(b[0:16], db[:])
+		binary.LittleEndian.PutUint64(b[16:24], entry.Low)
+		binary.LittleEndian.PutUint64(b[24:32], entry.High)
 	}
+	return
+}
+
+// Duplicate will return a duplicate of the folder vector that does not share
+// any memory with the original.
+func (folder Folder) Duplicate() Folder {
+	if folder.Entries == nil {
+		return Folder{}
+	}
+	return Folder{Entries: append([]Entry(nil), folder.Entries...)}
+}
+
+// Equal returns true if folder and other contain identical entries in the same
+// order.
+func (folder Folder) Equal(other Folder) bool {
+	if len(folder.Entries) != len(other.Entries) {
+		return false
+	}
+	for i := range folder.Entries {
+		if folder.Entries[i] != other.Entries[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// littleEndianToBigEndian swaps the byte order of the first three components
+// of a GUID. The conversion is its own inverse.
+func littleEndianToBigEndian(id *uuid.UUID) {
+	id[0], id[1], id[2], id[3] = id[3], id[2], id[1], id[0]
+	id[4], id[5] = id[5], id[4]
+	id[6], id[7] = id[7], id[6]
 }